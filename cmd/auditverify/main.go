@@ -0,0 +1,83 @@
+// Команда auditverify проверяет целостность файла аудита сервиса: последовательность номеров записей,
+// цепочку хешей и, если задан ключ, подписи HMAC. Печатает первую нарушенную запись и завершается
+// с ненулевым кодом, если цепочка нарушена.
+//
+// Использование:
+//
+//	auditverify [-key ключ] [-partial] файл...
+//
+// Ротированные сегменты (в том числе сжатые .gz) передаются от старых к новым, текущий файл — последним:
+//
+//	auditverify audit.log.* audit.log
+//
+// Ключ также можно передать в переменной окружения AUDIT_HMAC_KEY. Флаг -partial нужен,
+// если старые сегменты удалены по сроку хранения: проверка начинается с первой доступной записи.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/audit"
+)
+
+func main() {
+	log.SetFlags(0)
+
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("auditverify", flag.ContinueOnError)
+	key := fs.String("key", os.Getenv("AUDIT_HMAC_KEY"), "ключ HMAC, которым сервис подписывает записи (-audit-hmac-key)")
+	partial := fs.Bool("partial", false, "начать проверку с первой записи, если начало цепочки удалено по сроку хранения")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		return errors.New("использование: auditverify [-key ключ] [-partial] файл...")
+	}
+
+	verifier := audit.NewVerifier([]byte(*key))
+	verifier.Partial = *partial
+
+	total := 0
+
+	for _, path := range fs.Args() {
+		count, err := verifyFile(verifier, path)
+		total += count
+
+		if err != nil {
+			return fmt.Errorf("%s: цепочка нарушена после %d верных записей: %w", path, total, err)
+		}
+	}
+
+	if *key == "" {
+		fmt.Fprintf(stdout, "OK: %d записей, подписи не проверялись (ключ не задан)\n", total)
+		return nil
+	}
+
+	fmt.Fprintf(stdout, "OK: %d записей, подписи верны\n", total)
+
+	return nil
+}
+
+func verifyFile(verifier *audit.Verifier, path string) (int, error) {
+	file, err := audit.OpenSegment(path)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer file.Close()
+
+	return verifier.Verify(file)
+}
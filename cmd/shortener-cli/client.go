@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/authenticator"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/facade"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/handler"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/middlewares"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/storage"
+)
+
+// Client — операции сервиса, доступные из командной строки независимо от транспорта.
+type Client interface {
+	Shorten(ctx context.Context, originalURL string) (string, error)
+	Batch(ctx context.Context, items []handler.BatchShortenRequest) ([]handler.BatchShortenResponse, error)
+	List(ctx context.Context) ([]facade.BatchUserShortenResponse, error)
+	Delete(ctx context.Context, ids []string) error
+	Expand(ctx context.Context, id string) (string, error)
+	Stats(ctx context.Context) (*storage.Stats, error)
+	// Session возвращает актуальную сессию пользователя, выданную сервисом.
+	Session() string
+	Close() error
+}
+
+type httpClient struct {
+	baseURL string
+	session string
+	// csrf — токен double-submit: сервис с включенной защитой CSRF сверяет cookie с заголовком,
+	// поэтому токен клиента годится любой, лишь бы совпадал в обоих местах.
+	csrf   string
+	client *http.Client
+}
+
+func newHTTPClient(baseURL string, session string) *httpClient {
+	return &httpClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		session: session,
+		csrf:    rand.Text(),
+		client: &http.Client{
+			// Редиректы не выполняем: для expand нужен сам заголовок Location.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+func (c *httpClient) do(ctx context.Context, method, path string, body any) (*http.Response, error) {
+	var reader io.Reader
+
+	if body != nil {
+		data, err := json.Marshal(body)
+
+		if err != nil {
+			return nil, err
+		}
+
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if c.session != "" {
+		req.AddCookie(&http.Cookie{Name: authenticator.GetCookieName(), Value: c.session})
+	}
+
+	req.AddCookie(&http.Cookie{Name: middlewares.CSRFCookieName, Value: c.csrf})
+	req.Header.Set(middlewares.CSRFHeader, c.csrf)
+
+	resp, err := c.client.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == authenticator.GetCookieName() {
+			c.session = cookie.Value
+		}
+	}
+
+	return resp, nil
+}
+
+func (c *httpClient) Shorten(ctx context.Context, originalURL string) (string, error) {
+	resp, err := c.do(ctx, http.MethodPost, "/api/shorten", handler.ShortenRequest{URL: originalURL})
+
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusConflict {
+		return "", statusError(resp)
+	}
+
+	var result handler.ShortenResponse
+
+	err = json.NewDecoder(resp.Body).Decode(&result)
+
+	return result.Result, err
+}
+
+func (c *httpClient) Batch(ctx context.Context, items []handler.BatchShortenRequest) ([]handler.BatchShortenResponse, error) {
+	resp, err := c.do(ctx, http.MethodPost, "/api/shorten/batch", items)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusConflict {
+		return nil, statusError(resp)
+	}
+
+	var result []handler.BatchShortenResponse
+
+	err = json.NewDecoder(resp.Body).Decode(&result)
+
+	return result, err
+}
+
+func (c *httpClient) List(ctx context.Context) ([]facade.BatchUserShortenResponse, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/api/user/urls", nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+		return nil, nil
+	case http.StatusOK:
+	default:
+		return nil, statusError(resp)
+	}
+
+	var result []facade.BatchUserShortenResponse
+
+	err = json.NewDecoder(resp.Body).Decode(&result)
+
+	return result, err
+}
+
+func (c *httpClient) Delete(ctx context.Context, ids []string) error {
+	resp, err := c.do(ctx, http.MethodDelete, "/api/user/urls", ids)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		return statusError(resp)
+	}
+
+	return nil
+}
+
+func (c *httpClient) Expand(ctx context.Context, id string) (string, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/"+url.PathEscape(id), nil)
+
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusTemporaryRedirect:
+		return resp.Header.Get("Location"), nil
+	case http.StatusGone:
+		return "", fmt.Errorf("ссылка %s удалена", id)
+	default:
+		return "", statusError(resp)
+	}
+}
+
+func (c *httpClient) Stats(ctx context.Context) (*storage.Stats, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/api/internal/stats", nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp)
+	}
+
+	var result storage.Stats
+
+	err = json.NewDecoder(resp.Body).Decode(&result)
+
+	return &result, err
+}
+
+func (c *httpClient) Session() string {
+	return c.session
+}
+
+func (c *httpClient) Close() error {
+	return nil
+}
+
+func statusError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	text := strings.TrimSpace(string(body))
+
+	if text == "" {
+		return errors.New(resp.Status)
+	}
+
+	return fmt.Errorf("%s: %s", resp.Status, text)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// cliConfig — локальный файл конфигурации клиента.
+// Хранит сессию, выданную сервисом, чтобы последующие команды выполнялись от того же пользователя.
+type cliConfig struct {
+	Session string `json:"session"`
+}
+
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+
+	if err != nil {
+		return ".shortener-cli.json"
+	}
+
+	return filepath.Join(dir, "shortener-cli", "config.json")
+}
+
+func loadConfig(path string) (cliConfig, error) {
+	var c cliConfig
+
+	data, err := os.ReadFile(path)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+
+	if err != nil {
+		return c, err
+	}
+
+	err = json.Unmarshal(data, &c)
+
+	return c, err
+}
+
+func saveConfig(path string, c cliConfig) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0600)
+}
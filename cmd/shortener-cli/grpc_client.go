@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/authenticator"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/facade"
+	pb "github.com/flash1nho/go-musthave-shortener-tpl/internal/grpc"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/handler"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/storage"
+)
+
+type grpcClient struct {
+	conn    *grpc.ClientConn
+	client  pb.ShortenerServiceClient
+	session string
+}
+
+func newGRPCClient(addr string, session string) (*grpcClient, error) {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &grpcClient{
+		conn:    conn,
+		client:  pb.NewShortenerServiceClient(conn),
+		session: session,
+	}, nil
+}
+
+// call подготавливает контекст с сессией и опцию, через которую сервис вернет обновленную сессию.
+func (c *grpcClient) call(ctx context.Context) (context.Context, grpc.CallOption, func()) {
+	if c.session != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", c.session)
+	}
+
+	var header metadata.MD
+
+	return ctx, grpc.Header(&header), func() {
+		for _, value := range header.Get("set-cookie") {
+			cookie, err := http.ParseSetCookie(value)
+
+			if err == nil && cookie.Name == authenticator.GetCookieName() {
+				c.session = cookie.Value
+			}
+		}
+	}
+}
+
+func (c *grpcClient) Shorten(ctx context.Context, originalURL string) (string, error) {
+	ctx, opt, done := c.call(ctx)
+	resp, err := c.client.ShortenURL(ctx, &pb.URLShortenRequest{URL: originalURL}, opt)
+	done()
+
+	if err != nil {
+		return "", err
+	}
+
+	return resp.GetResult(), nil
+}
+
+func (c *grpcClient) Batch(ctx context.Context, items []handler.BatchShortenRequest) ([]handler.BatchShortenResponse, error) {
+	reqItems := make([]*pb.BatchShortenItem, 0, len(items))
+
+	for _, item := range items {
+		reqItems = append(reqItems, &pb.BatchShortenItem{
+			CorrelationID: item.CorrelationID,
+			OriginalURL:   item.OriginalURL,
+		})
+	}
+
+	ctx, opt, done := c.call(ctx)
+	resp, err := c.client.ShortenBatch(ctx, &pb.BatchShortenRequest{Items: &reqItems}, opt)
+	done()
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]handler.BatchShortenResponse, 0, len(resp.GetItems()))
+
+	for _, item := range resp.GetItems() {
+		result = append(result, handler.BatchShortenResponse{
+			CorrelationID: item.GetCorrelationId(),
+			ShortURL:      item.GetShortUrl(),
+		})
+	}
+
+	return result, nil
+}
+
+func (c *grpcClient) List(ctx context.Context) ([]facade.BatchUserShortenResponse, error) {
+	ctx, opt, done := c.call(ctx)
+	resp, err := c.client.ListUserURLs(ctx, &pb.UserURLsRequest{}, opt)
+	done()
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]facade.BatchUserShortenResponse, 0, len(resp.GetUrls()))
+
+	for _, item := range resp.GetUrls() {
+		result = append(result, facade.BatchUserShortenResponse{
+			ShortURL:    item.GetShortUrl(),
+			OriginalURL: item.GetOriginalUrl(),
+		})
+	}
+
+	return result, nil
+}
+
+func (c *grpcClient) Delete(ctx context.Context, ids []string) error {
+	ctx, opt, done := c.call(ctx)
+	_, err := c.client.DeleteUserURLs(ctx, &pb.DeleteUserURLsRequest{IDs: ids}, opt)
+	done()
+
+	return err
+}
+
+func (c *grpcClient) Expand(ctx context.Context, id string) (string, error) {
+	ctx, opt, done := c.call(ctx)
+	resp, err := c.client.ExpandURL(ctx, &pb.URLExpandRequest{ID: id}, opt)
+	done()
+
+	if err != nil {
+		return "", err
+	}
+
+	return resp.GetResult(), nil
+}
+
+func (c *grpcClient) Stats(ctx context.Context) (*storage.Stats, error) {
+	ctx, opt, done := c.call(ctx)
+	resp, err := c.client.GetStats(ctx, &pb.StatsRequest{}, opt)
+	done()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &storage.Stats{URLs: int(resp.GetUrls()), Users: int(resp.GetUsers())}, nil
+}
+
+func (c *grpcClient) Session() string {
+	return c.session
+}
+
+func (c *grpcClient) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,324 @@
+// Shortener-cli — клиент командной строки для сервиса сокращения URL.
+//
+// Использование:
+//
+//	shortener-cli [флаги] <команда> [аргументы]
+//
+// Команды:
+//
+//	shorten <url>...     сократить одну или несколько ссылок
+//	batch [-f файл]      сократить ссылки пакетом из файла или stdin
+//	list                 вывести ссылки текущего пользователя
+//	delete <id>...       удалить ссылки текущего пользователя
+//	expand <id>          получить исходный URL по короткому идентификатору
+//	stats                статистика сервиса (требует доверенной подсети)
+//
+// Сессия, выданная сервисом, сохраняется в файле конфигурации (-config)
+// и используется в последующих вызовах.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/config"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/facade"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/handler"
+)
+
+const (
+	transportHTTP = "http"
+	transportGRPC = "grpc"
+
+	defaultGRPCAddr = "localhost:3200"
+)
+
+type options struct {
+	transport  string
+	addr       string
+	grpcAddr   string
+	output     string
+	configPath string
+	session    string
+	timeout    time.Duration
+}
+
+func main() {
+	log.SetFlags(0)
+
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	var opts options
+
+	fs := flag.NewFlagSet("shortener-cli", flag.ContinueOnError)
+	fs.StringVar(&opts.transport, "transport", transportHTTP, "транспорт: http|grpc")
+	fs.StringVar(&opts.addr, "addr", config.DefaultURL, "адрес HTTP API сервиса")
+	fs.StringVar(&opts.grpcAddr, "grpc-addr", defaultGRPCAddr, "адрес gRPC сервиса")
+	fs.StringVar(&opts.output, "o", formatTable, "формат вывода: table|json|csv")
+	fs.StringVar(&opts.configPath, "config", defaultConfigPath(), "файл конфигурации клиента")
+	fs.StringVar(&opts.session, "session", "", "сессия (значение cookie сессии), переопределяет сохраненную")
+	fs.DurationVar(&opts.timeout, "timeout", 10*time.Second, "таймаут выполнения команды")
+
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Использование: shortener-cli [флаги] shorten|batch|list|delete|expand|stats [аргументы]")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("не указана команда")
+	}
+
+	cfg, err := loadConfig(opts.configPath)
+
+	if err != nil {
+		return fmt.Errorf("ошибка чтения конфигурации: %w", err)
+	}
+
+	if opts.session != "" {
+		cfg.Session = opts.session
+	}
+
+	client, err := newClient(opts, cfg.Session)
+
+	if err != nil {
+		return err
+	}
+
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.timeout)
+	defer cancel()
+
+	res, err := execute(ctx, client, fs.Arg(0), fs.Args()[1:], stdin)
+
+	if session := client.Session(); session != "" && session != cfg.Session {
+		cfg.Session = session
+
+		if saveErr := saveConfig(opts.configPath, cfg); saveErr != nil {
+			log.Printf("не удалось сохранить сессию: %v", saveErr)
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if res == nil {
+		return nil
+	}
+
+	return render(stdout, opts.output, *res)
+}
+
+func newClient(opts options, session string) (Client, error) {
+	switch opts.transport {
+	case transportHTTP:
+		return newHTTPClient(opts.addr, session), nil
+	case transportGRPC:
+		return newGRPCClient(opts.grpcAddr, session)
+	default:
+		return nil, fmt.Errorf("неизвестный транспорт: %s", opts.transport)
+	}
+}
+
+func execute(ctx context.Context, client Client, command string, args []string, stdin io.Reader) (*result, error) {
+	switch command {
+	case "shorten":
+		return shorten(ctx, client, args)
+	case "batch":
+		return batch(ctx, client, args, stdin)
+	case "list":
+		return list(ctx, client)
+	case "delete":
+		return nil, remove(ctx, client, args)
+	case "expand":
+		return expand(ctx, client, args)
+	case "stats":
+		return stats(ctx, client)
+	default:
+		return nil, fmt.Errorf("неизвестная команда: %s", command)
+	}
+}
+
+func shorten(ctx context.Context, client Client, args []string) (*result, error) {
+	if len(args) == 0 {
+		return nil, errors.New("shorten: не указан URL")
+	}
+
+	res := &result{header: []string{"original_url", "short_url"}}
+	value := make([]facade.BatchUserShortenResponse, 0, len(args))
+
+	for _, originalURL := range args {
+		shortURL, err := client.Shorten(ctx, originalURL)
+
+		if err != nil {
+			return nil, fmt.Errorf("shorten %s: %w", originalURL, err)
+		}
+
+		value = append(value, facade.BatchUserShortenResponse{ShortURL: shortURL, OriginalURL: originalURL})
+		res.rows = append(res.rows, []string{originalURL, shortURL})
+	}
+
+	res.value = value
+
+	return res, nil
+}
+
+func batch(ctx context.Context, client Client, args []string, stdin io.Reader) (*result, error) {
+	fs := flag.NewFlagSet("batch", flag.ContinueOnError)
+	file := fs.String("f", "-", "файл со ссылками: JSON-массив или по одному URL в строке; - читает stdin")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	input := stdin
+
+	if *file != "-" {
+		f, err := os.Open(*file)
+
+		if err != nil {
+			return nil, err
+		}
+
+		defer f.Close()
+
+		input = f
+	}
+
+	items, err := readBatch(input)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(items) == 0 {
+		return nil, errors.New("batch: нет ссылок для сокращения")
+	}
+
+	resp, err := client.Batch(ctx, items)
+
+	if err != nil {
+		return nil, err
+	}
+
+	res := &result{value: resp, header: []string{"correlation_id", "short_url"}}
+
+	for _, item := range resp {
+		res.rows = append(res.rows, []string{item.CorrelationID, item.ShortURL})
+	}
+
+	return res, nil
+}
+
+// readBatch читает пакет ссылок в формате POST /api/shorten/batch
+// либо список URL по одному в строке, где correlation_id — номер строки.
+func readBatch(r io.Reader) ([]handler.BatchShortenRequest, error) {
+	data, err := io.ReadAll(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var items []handler.BatchShortenRequest
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return nil, fmt.Errorf("batch: некорректный JSON: %w", err)
+		}
+
+		return items, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	for line := 1; scanner.Scan(); line++ {
+		originalURL := strings.TrimSpace(scanner.Text())
+
+		if originalURL == "" {
+			continue
+		}
+
+		items = append(items, handler.BatchShortenRequest{CorrelationID: strconv.Itoa(line), OriginalURL: originalURL})
+	}
+
+	return items, scanner.Err()
+}
+
+func list(ctx context.Context, client Client) (*result, error) {
+	resp, err := client.List(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	res := &result{value: resp, header: []string{"short_url", "original_url"}}
+
+	for _, item := range resp {
+		res.rows = append(res.rows, []string{item.ShortURL, item.OriginalURL})
+	}
+
+	return res, nil
+}
+
+func remove(ctx context.Context, client Client, args []string) error {
+	if len(args) == 0 {
+		return errors.New("delete: не указаны идентификаторы ссылок")
+	}
+
+	return client.Delete(ctx, args)
+}
+
+func expand(ctx context.Context, client Client, args []string) (*result, error) {
+	if len(args) != 1 {
+		return nil, errors.New("expand: укажите один идентификатор ссылки")
+	}
+
+	originalURL, err := client.Expand(ctx, args[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	value := facade.BatchUserShortenResponse{ShortURL: args[0], OriginalURL: originalURL}
+
+	return &result{
+		value:  value,
+		header: []string{"id", "original_url"},
+		rows:   [][]string{{args[0], originalURL}},
+	}, nil
+}
+
+func stats(ctx context.Context, client Client) (*result, error) {
+	resp, err := client.Stats(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &result{
+		value:  resp,
+		header: []string{"urls", "users"},
+		rows:   [][]string{{strconv.Itoa(resp.URLs), strconv.Itoa(resp.Users)}},
+	}, nil
+}
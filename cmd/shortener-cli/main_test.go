@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/config"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/facade"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/handler"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/middlewares"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/storage"
+)
+
+func testServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	store, err := storage.NewStorage("", "")
+	require.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(nil)
+	baseURL := "http://" + server.Listener.Addr().String()
+	f := facade.NewFacade(store, baseURL)
+	h := handler.NewHandler(f, config.SettingsObject{})
+
+	r := chi.NewRouter()
+	r.Use(middlewares.CSRF(false))
+	r.Use(middlewares.Auth)
+	r.Post("/api/shorten", h.APIShortenPostURLHandler)
+	r.Post("/api/shorten/batch", h.APIShortenBatchPostURLHandler)
+	r.Get("/api/user/urls", h.APIUserURLHandler)
+	r.Delete("/api/user/urls", h.APIUserDeleteURLHandler)
+	r.Get("/{id}", h.GetURLHandler)
+
+	server.Config.Handler = r
+	server.Start()
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestRun(t *testing.T) {
+	server := testServer(t)
+	configPath := filepath.Join(t.TempDir(), "config.json")
+
+	exec := func(stdin string, args ...string) string {
+		t.Helper()
+
+		var out bytes.Buffer
+
+		args = append([]string{"-addr", server.URL, "-config", configPath}, args...)
+		require.NoError(t, run(args, strings.NewReader(stdin), &out))
+
+		return out.String()
+	}
+
+	out := exec("", "-o", "csv", "shorten", "https://practicum.yandex.ru")
+	assert.Contains(t, out, "https://practicum.yandex.ru,"+server.URL+"/")
+
+	cfg, err := loadConfig(configPath)
+	require.NoError(t, err)
+	assert.NotEmpty(t, cfg.Session, "сессия должна сохраниться в конфигурации")
+
+	out = exec("https://ya.ru\nhttps://go.dev\n", "-o", "csv", "batch")
+	assert.Equal(t, 3, strings.Count(out, "\n"), "заголовок и две ссылки")
+
+	out = exec("", "-o", "json", "list")
+	assert.Contains(t, out, "https://practicum.yandex.ru")
+	assert.Contains(t, out, "https://go.dev")
+
+	lines := strings.Split(strings.TrimSpace(exec("", "-o", "csv", "shorten", "https://go.dev")), "\n")
+	id := lines[1][strings.LastIndex(lines[1], "/")+1:]
+
+	out = exec("", "-o", "csv", "expand", id)
+	assert.Contains(t, out, id+",https://go.dev")
+
+	exec("", "delete", id)
+
+	out = exec("", "-o", "csv", "list")
+	assert.NotContains(t, out, "https://go.dev")
+}
+
+func TestReadBatch(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected []handler.BatchShortenRequest
+	}{
+		{
+			name:  "json",
+			input: `[{"correlation_id":"a","original_url":"https://ya.ru"}]`,
+			expected: []handler.BatchShortenRequest{
+				{CorrelationID: "a", OriginalURL: "https://ya.ru"},
+			},
+		},
+		{
+			name:  "lines",
+			input: "https://ya.ru\n\nhttps://go.dev\n",
+			expected: []handler.BatchShortenRequest{
+				{CorrelationID: "1", OriginalURL: "https://ya.ru"},
+				{CorrelationID: "3", OriginalURL: "https://go.dev"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			items, err := readBatch(strings.NewReader(tc.input))
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, items)
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatCSV   = "csv"
+)
+
+// result — данные для вывода: value сериализуется в JSON как есть,
+// header и rows используются для табличного и CSV-представлений.
+type result struct {
+	value  any
+	header []string
+	rows   [][]string
+}
+
+func render(w io.Writer, format string, r result) error {
+	switch format {
+	case formatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(r.value)
+	case formatCSV:
+		writer := csv.NewWriter(w)
+
+		if err := writer.Write(r.header); err != nil {
+			return err
+		}
+
+		if err := writer.WriteAll(r.rows); err != nil {
+			return err
+		}
+
+		return writer.Error()
+	case formatTable:
+		writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+		fmt.Fprintln(writer, strings.ToUpper(strings.Join(r.header, "\t")))
+
+		for _, row := range r.rows {
+			fmt.Fprintln(writer, strings.Join(row, "\t"))
+		}
+
+		return writer.Flush()
+	default:
+		return fmt.Errorf("неизвестный формат вывода: %s", format)
+	}
+}
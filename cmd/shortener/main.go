@@ -1,16 +1,28 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"net/http"
 	_ "net/http/pprof"
 
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/authenticator"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/blocklist"
 	"github.com/flash1nho/go-musthave-shortener-tpl/internal/config"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/destpolicy"
 	"github.com/flash1nho/go-musthave-shortener-tpl/internal/facade"
 	"github.com/flash1nho/go-musthave-shortener-tpl/internal/grpc"
 	"github.com/flash1nho/go-musthave-shortener-tpl/internal/handler"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/metrics"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/quota"
 	"github.com/flash1nho/go-musthave-shortener-tpl/internal/service"
 	"github.com/flash1nho/go-musthave-shortener-tpl/internal/storage"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/tracing"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/urlnorm"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/webhook"
+
+	"go.uber.org/zap"
 )
 
 var (
@@ -24,19 +36,100 @@ func main() {
 	fmt.Printf("Build date: %s\n", buildDate)
 	fmt.Printf("Build commit: %s\n", buildCommit)
 
+	metrics.SetBuildInfo(buildVersion, buildCommit, buildDate)
+
 	go func() {
 		http.ListenAndServe("localhost:6060", nil)
 	}()
 
 	settings := config.Settings()
+
+	if settings.SessionKey != "" {
+		authenticator.SetKey(settings.SessionKey)
+	} else {
+		settings.Log.Warn("Ключ подписи сессий не задан, сессии пользователей не переживут перезапуск")
+	}
+
+	shutdownTracing, err := tracing.Init(context.Background(), settings.Tracing.Exporter, settings.Tracing.Endpoint, buildVersion)
+
+	if err != nil {
+		settings.Log.Error("Ошибка инициализации трассировки", zap.Error(err))
+		shutdownTracing = func(context.Context) error { return nil }
+	}
+
 	store, err := storage.NewStorage(settings.FilePath, settings.DatabaseDSN)
 
 	if err != nil {
 		settings.Log.Error(fmt.Sprint(err))
+	} else {
+		metrics.RegisterPool(store.Pool)
 	}
 
 	f := facade.NewFacade(store, settings.Server2.BaseURL)
+	f.Quota = newQuotaManager(store, settings.Quota)
+	f.Normalizer = urlnorm.New(urlnorm.Options{
+		AllowedSchemes: settings.URLNorm.AllowedSchemes,
+		MaxLength:      settings.URLNorm.MaxLength,
+		StripTracking:  settings.URLNorm.StripTracking,
+	})
+	f.Blocklist = loadBlocklist(settings)
+	f.Destinations = newDestinationPolicy(settings)
+	f.Webhooks = newWebhookManager(store, f.Destinations, settings.Log)
 	h := handler.NewHandler(f, settings)
 	gh := grpc.NewHandler(f)
 	service.NewService(h, gh, settings).Run()
+
+	if err := shutdownTracing(context.Background()); err != nil {
+		settings.Log.Error("Ошибка выгрузки трассировок", zap.Error(err))
+	}
+}
+
+// newQuotaManager хранит счетчики квот в базе, если она подключена, иначе в памяти.
+func newQuotaManager(store *storage.Storage, cfg config.Quota) *quota.Manager {
+	limits := quota.Limits{Daily: cfg.Daily, Total: cfg.Total}
+
+	if store != nil && store.Pool != nil {
+		return quota.NewManager(quota.NewPostgresStore(store.Pool), limits)
+	}
+
+	return quota.NewManager(quota.NewMemoryStore(), limits)
+}
+
+// newWebhookManager хранит вебхуки и очередь доставок в базе, если она подключена, иначе в памяти.
+// Адреса вебхуков во внутренней сети запрещены всегда, а включенная политика адресов назначения
+// проверяется дополнительно, как для адресов ссылок.
+func newWebhookManager(store *storage.Storage, policy *destpolicy.Policy, log *zap.Logger) *webhook.Manager {
+	opts := webhook.Options{Check: policy.Check}
+
+	if store != nil && store.Pool != nil {
+		return webhook.NewManager(webhook.NewPostgresStore(store.Pool), opts, log)
+	}
+
+	return webhook.NewManager(webhook.NewMemoryStore(), opts, log)
+}
+
+// loadBlocklist читает список блокировок; при ошибке в файле сервис стартует с пустым списком.
+func loadBlocklist(settings config.SettingsObject) *blocklist.List {
+	list, err := blocklist.Load(settings.BlocklistFile, settings.Log)
+
+	if err != nil {
+		settings.Log.Error("Ошибка загрузки списка блокировок", zap.String("path", settings.BlocklistFile), zap.Error(err))
+		list, _ = blocklist.Load("", settings.Log)
+	}
+
+	return list
+}
+
+// newDestinationPolicy собирает политику адресов назначения. При ошибке в настройке
+// запрещаются все категории: отключать защиту из-за опечатки небезопасно.
+func newDestinationPolicy(settings config.SettingsObject) *destpolicy.Policy {
+	ownHosts := []string{settings.Server1.BaseURL, settings.Server2.BaseURL, settings.Server1.Addr, settings.Server2.Addr}
+	policy, err := destpolicy.New(settings.DestinationDeny, ownHosts, net.DefaultResolver)
+
+	if err != nil {
+		settings.Log.Error("Ошибка настройки политики адресов назначения, запрещены все категории", zap.Error(err))
+		policy, _ = destpolicy.New([]string{destpolicy.All}, ownHosts, net.DefaultResolver)
+	}
+
+	return policy
 }
@@ -0,0 +1,93 @@
+// Package audit описывает события аудита и формат их хранения в виде цепочки хешей,
+// в которой изменение, удаление или перестановка записи обнаруживается проверкой Verify.
+package audit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/clientip"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/logger"
+)
+
+// Действия над ссылками, попадающие в аудит.
+const (
+	ActionShorten = "shorten"
+	ActionFollow  = "follow"
+	ActionDelete  = "delete"
+	ActionUpdate  = "update"
+
+	// Действия оператора через /api/internal. В UserID события — владелец ссылки
+	// или отключаемый пользователь.
+	ActionForceDelete = "force_delete"
+	ActionBlock       = "block"
+	ActionUnblock     = "unblock"
+	ActionDisableUser = "disable_user"
+	ActionEnableUser  = "enable_user"
+
+	// Чтения оператора через /api/internal: доступ к чужим ссылкам, пользователям и журналу
+	// тоже оставляет след. В UserID и ShortCode — запрошенные пользователь и ссылка.
+	ActionViewLink   = "admin_view_link"
+	ActionViewUser   = "admin_view_user"
+	ActionViewHealth = "admin_view_health"
+	ActionAuditQuery = "audit_query"
+)
+
+// Итог действия.
+const (
+	StatusSuccess = "success"
+	// StatusConflict — ссылка на этот адрес уже существовала, клиенту возвращена она.
+	StatusConflict = "conflict"
+	StatusFailure  = "failure"
+)
+
+// Event — событие аудита: действие пользователя над ссылкой и его итог.
+// Одинаково формируется для HTTP API, REST-моста и gRPC.
+type Event struct {
+	Timestamp int64  `json:"ts"`
+	Action    string `json:"action"`
+	Status    string `json:"status"`
+	UserID    string `json:"user_id"`
+	// URL — исходный адрес ссылки.
+	URL       string `json:"url"`
+	ShortCode string `json:"short_code,omitempty"`
+	ClientIP  string `json:"client_ip,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	// Error — причина неудачи; для успешных действий пусто.
+	Error string `json:"error,omitempty"`
+}
+
+// Notifier доставляет события аудита в приемники.
+type Notifier interface {
+	NotifyAll(ctx context.Context, e Event)
+}
+
+// NewEvent заполняет событие по итогу действия err. Адрес клиента и идентификатор запроса
+// берутся из контекста; conflict — ошибка, означающая уже существующую ссылку.
+func NewEvent(ctx context.Context, action, userID, shortCode, originalURL string, err, conflict error) Event {
+	e := Event{
+		Timestamp: time.Now().Unix(),
+		Action:    action,
+		Status:    StatusSuccess,
+		UserID:    userID,
+		URL:       originalURL,
+		ShortCode: shortCode,
+		RequestID: logger.RequestIDFromContext(ctx),
+	}
+
+	if ip := clientip.IP(ctx); ip != nil {
+		e.ClientIP = ip.String()
+	}
+
+	switch {
+	case err == nil:
+	case conflict != nil && errors.Is(err, conflict):
+		e.Status = StatusConflict
+	default:
+		e.Status = StatusFailure
+		e.Error = err.Error()
+	}
+
+	return e
+}
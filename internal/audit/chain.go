@@ -0,0 +1,214 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// GenesisHash — значение prev_hash первой записи цепочки.
+var GenesisHash = strings.Repeat("0", sha256.Size*2)
+
+// hashField начинает поля, которые дописываются к телу записи и не входят в хешируемые данные.
+const hashField = `,"hash":"`
+
+// maxLine ограничивает длину одной записи при чтении файла аудита.
+const maxLine = 1 << 20
+
+// Record — запись файла аудита: событие со звеньями цепочки.
+//
+// Hash — SHA-256 тела записи, то есть JSON без полей hash и sig; тело включает Seq и PrevHash,
+// поэтому хеш каждой записи зависит от всех предыдущих. Sig — HMAC-SHA256 тела на ключе сервиса;
+// без ключа подделать его нельзя, даже переписав хвост файла целиком.
+type Record struct {
+	Seq      uint64 `json:"seq"`
+	PrevHash string `json:"prev_hash"`
+	Event
+	Hash string `json:"hash,omitempty"`
+	Sig  string `json:"sig,omitempty"`
+}
+
+// Chain выдает записи цепочки по порядку. Не потокобезопасен: запись в файл должна идти
+// под той же блокировкой, что и Seal, иначе порядок строк разойдется с порядком номеров.
+type Chain struct {
+	seq  uint64
+	prev string
+	key  []byte
+}
+
+// NewChain создает пустую цепочку. Непустой key включает подпись записей HMAC.
+func NewChain(key []byte) *Chain {
+	return &Chain{prev: GenesisHash, key: key}
+}
+
+// Resume продолжает цепочку после последней записи r. Строки без полей цепочки пропускаются,
+// поэтому дописанный после сбоя обрывок строки не мешает продолжить запись.
+func (c *Chain) Resume(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxLine)
+
+	for scanner.Scan() {
+		var record Record
+
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil || record.Hash == "" {
+			continue
+		}
+
+		c.seq = record.Seq
+		c.prev = record.Hash
+	}
+
+	return scanner.Err()
+}
+
+// Seal превращает событие в следующую запись цепочки и возвращает строку для файла вместе с переводом строки.
+func (c *Chain) Seal(e Event) ([]byte, error) {
+	body, err := json.Marshal(Record{Seq: c.seq + 1, PrevHash: c.prev, Event: e})
+
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sum(body)
+	line := append(body[:len(body)-1:len(body)-1], hashField+hash+`"`...)
+
+	if len(c.key) > 0 {
+		line = append(line, `,"sig":"`+sign(c.key, body)+`"`...)
+	}
+
+	c.seq++
+	c.prev = hash
+
+	return append(line, "}\n"...), nil
+}
+
+// BreakError описывает первое нарушение цепочки.
+type BreakError struct {
+	// Line — номер строки файла, начиная с 1.
+	Line int
+	// Seq — номер записи из строки, если ее удалось разобрать.
+	Seq    uint64
+	Reason string
+}
+
+func (e *BreakError) Error() string {
+	return fmt.Sprintf("строка %d (seq %d): %s", e.Line, e.Seq, e.Reason)
+}
+
+// Verifier проверяет цепочку, разбитую на сегменты: Verify вызывается для каждого сегмента
+// по порядку, и проверка продолжается с последней записи предыдущего.
+type Verifier struct {
+	key []byte
+	// Partial принимает первую запись за начало цепочки. Нужен, когда старые сегменты
+	// удалены по сроку хранения; удаление начала такой цепочки не обнаруживается.
+	Partial bool
+	seq     uint64
+	prev    string
+	started bool
+}
+
+// NewVerifier создает проверку цепочки с начала. Если key пуст, подписи не проверяются;
+// если задан, каждая запись обязана быть подписана этим ключом.
+func NewVerifier(key []byte) *Verifier {
+	return &Verifier{key: key, prev: GenesisHash}
+}
+
+// Verify проверяет записи из r и возвращает число проверенных записей.
+// При нарушении возвращается *BreakError для первой неверной строки r.
+func (v *Verifier) Verify(r io.Reader) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxLine)
+
+	var (
+		line  int
+		count int
+	)
+
+	for scanner.Scan() {
+		line++
+
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		record, err := verifyLine(scanner.Bytes(), v.key)
+
+		if err != nil {
+			return count, &BreakError{Line: line, Seq: record.Seq, Reason: err.Error()}
+		}
+
+		if v.Partial && !v.started {
+			v.seq, v.prev = record.Seq-1, record.PrevHash
+		}
+
+		switch {
+		case record.Seq != v.seq+1:
+			return count, &BreakError{Line: line, Seq: record.Seq, Reason: fmt.Sprintf("ожидалась запись %d: записи удалены или переставлены", v.seq+1)}
+		case record.PrevHash != v.prev:
+			return count, &BreakError{Line: line, Seq: record.Seq, Reason: "prev_hash не совпадает с хешем предыдущей записи"}
+		}
+
+		v.seq = record.Seq
+		v.prev = record.Hash
+		v.started = true
+		count++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return count, err
+	}
+
+	return count, nil
+}
+
+// Verify проверяет цепочку записей из r с самого начала. См. Verifier.
+func Verify(r io.Reader, key []byte) (int, error) {
+	return NewVerifier(key).Verify(r)
+}
+
+// verifyLine проверяет хеш и подпись одной записи.
+func verifyLine(line []byte, key []byte) (Record, error) {
+	var record Record
+
+	if err := json.Unmarshal(line, &record); err != nil {
+		return record, fmt.Errorf("запись не разбирается: %w", err)
+	}
+
+	cut := bytes.LastIndex(line, []byte(hashField))
+
+	if record.Hash == "" || cut < 0 {
+		return record, errors.New("запись без хеша")
+	}
+
+	body := append(line[:cut:cut], '}')
+
+	if sum(body) != record.Hash {
+		return record, errors.New("хеш не совпадает с содержимым записи")
+	}
+
+	if len(key) > 0 && !hmac.Equal([]byte(sign(key, body)), []byte(record.Sig)) {
+		return record, errors.New("подпись HMAC неверна или отсутствует")
+	}
+
+	return record, nil
+}
+
+func sum(body []byte) string {
+	h := sha256.Sum256(body)
+
+	return hex.EncodeToString(h[:])
+}
+
+func sign(key, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,151 @@
+package audit
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sealed возвращает строки цепочки из n событий.
+func sealed(t *testing.T, key []byte, n int) []string {
+	t.Helper()
+
+	chain := NewChain(key)
+	lines := make([]string, 0, n)
+
+	for i := range n {
+		line, err := chain.Seal(Event{Timestamp: int64(i), Action: "POST", UserID: "user", URL: "/api/shorten"})
+		require.NoError(t, err)
+
+		lines = append(lines, string(line))
+	}
+
+	return lines
+}
+
+func TestVerify(t *testing.T) {
+	key := []byte("secret")
+
+	tests := []struct {
+		name     string
+		key      []byte
+		verify   []byte
+		tamper   func(lines []string) []string
+		wantLine int
+		count    int
+	}{
+		{
+			name:   "цепочка без ключа",
+			tamper: func(lines []string) []string { return lines },
+			count:  3,
+		},
+		{
+			name:   "цепочка с подписью",
+			key:    key,
+			verify: key,
+			tamper: func(lines []string) []string { return lines },
+			count:  3,
+		},
+		{
+			name: "измененное событие",
+			tamper: func(lines []string) []string {
+				lines[1] = strings.Replace(lines[1], `"user_id":"user"`, `"user_id":"admin"`, 1)
+				return lines
+			},
+			wantLine: 2,
+			count:    1,
+		},
+		{
+			name:     "удаленная запись",
+			tamper:   func(lines []string) []string { return append(lines[:1], lines[2:]...) },
+			wantLine: 2,
+			count:    1,
+		},
+		{
+			name:     "переставленные записи",
+			tamper:   func(lines []string) []string { return []string{lines[0], lines[2], lines[1]} },
+			wantLine: 2,
+			count:    1,
+		},
+		{
+			name: "удаленное начало",
+			tamper: func(lines []string) []string {
+				return lines[1:]
+			},
+			wantLine: 1,
+		},
+		{
+			name:     "подпись другим ключом",
+			key:      []byte("other"),
+			verify:   key,
+			tamper:   func(lines []string) []string { return lines },
+			wantLine: 1,
+		},
+		{
+			name:     "запись без подписи при заданном ключе",
+			verify:   key,
+			tamper:   func(lines []string) []string { return lines },
+			wantLine: 1,
+		},
+		{
+			name: "запись старого формата",
+			tamper: func(lines []string) []string {
+				return append([]string{`{"ts":1,"action":"POST","user_id":"user","url":"/"}` + "\n"}, lines...)
+			},
+			wantLine: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lines := test.tamper(sealed(t, test.key, 3))
+
+			count, err := Verify(strings.NewReader(strings.Join(lines, "")), test.verify)
+
+			assert.Equal(t, test.count, count, "Число верных записей не совпадает с ожидаемым")
+
+			if test.wantLine == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			var broken *BreakError
+
+			require.ErrorAs(t, err, &broken)
+			assert.Equal(t, test.wantLine, broken.Line, "Строка нарушения не совпадает с ожидаемой")
+		})
+	}
+}
+
+func TestResume(t *testing.T) {
+	var file bytes.Buffer
+
+	for _, line := range sealed(t, nil, 2) {
+		file.WriteString(line)
+	}
+
+	// Обрывок строки после сбоя не мешает продолжить цепочку.
+	file.WriteString(`{"seq":3,"prev_ha`)
+
+	chain := NewChain(nil)
+	require.NoError(t, chain.Resume(bytes.NewReader(file.Bytes())))
+
+	line, err := chain.Seal(Event{Action: "GET", URL: "/abc"})
+	require.NoError(t, err)
+
+	var log bytes.Buffer
+
+	for _, line := range sealed(t, nil, 2) {
+		log.WriteString(line)
+	}
+
+	log.Write(line)
+
+	count, err := Verify(&log, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count, "Число верных записей не совпадает с ожидаемым")
+}
@@ -0,0 +1,348 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/metrics"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/tracing"
+)
+
+// Значения Options по умолчанию.
+const (
+	DefaultQueueSize     = 1024
+	DefaultBatchSize     = 100
+	DefaultFlushInterval = time.Second
+)
+
+// Поведение при переполнении очереди приемника.
+const (
+	// OverflowDrop отбрасывает событие и учитывает его в счетчике: запросы пользователей не ждут аудит.
+	OverflowDrop = "drop"
+	// OverflowBlock задерживает действие пользователя, пока в очереди не освободится место:
+	// медленный приемник замедляет сервис, зато события теряются только при остановке —
+	// ожидающие места в момент Close отбрасываются и учитываются в счетчике.
+	OverflowBlock = "block"
+)
+
+// Options — параметры очередей диспетчера. Нулевые значения заменяются значениями по умолчанию.
+type Options struct {
+	// QueueSize — емкость очереди каждого приемника.
+	QueueSize int
+	// BatchSize — сколько событий передается приемнику за раз.
+	BatchSize int
+	// FlushInterval — как долго неполная пачка ждет добора перед отправкой.
+	FlushInterval time.Duration
+	// Overflow — OverflowDrop или OverflowBlock.
+	Overflow string
+}
+
+func (o Options) withDefaults() Options {
+	if o.QueueSize <= 0 {
+		o.QueueSize = DefaultQueueSize
+	}
+
+	if o.BatchSize <= 0 {
+		o.BatchSize = DefaultBatchSize
+	}
+
+	if o.FlushInterval <= 0 {
+		o.FlushInterval = DefaultFlushInterval
+	}
+
+	if o.Overflow != OverflowBlock {
+		o.Overflow = OverflowDrop
+	}
+
+	return o
+}
+
+// Filter отбирает события для приемника. Пустой список не ограничивает соответствующее поле.
+type Filter struct {
+	Actions  []string `json:"actions,omitempty"`
+	Statuses []string `json:"statuses,omitempty"`
+}
+
+// IsZero сообщает, что фильтр пропускает все события.
+func (f Filter) IsZero() bool {
+	return len(f.Actions) == 0 && len(f.Statuses) == 0
+}
+
+// String возвращает фильтр в виде "actions=delete,update; statuses=failure" для заголовка X-Audit-Filter.
+func (f Filter) String() string {
+	var parts []string
+
+	if len(f.Actions) > 0 {
+		parts = append(parts, "actions="+strings.Join(f.Actions, ","))
+	}
+
+	if len(f.Statuses) > 0 {
+		parts = append(parts, "statuses="+strings.Join(f.Statuses, ","))
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+// Match сообщает, нужно ли передавать событие приемнику.
+func (f Filter) Match(e Event) bool {
+	return (len(f.Actions) == 0 || slices.Contains(f.Actions, e.Action)) &&
+		(len(f.Statuses) == 0 || slices.Contains(f.Statuses, e.Status))
+}
+
+// queued — событие в очереди вместе со спаном действия, которое его породило.
+type queued struct {
+	event Event
+	span  trace.SpanContext
+}
+
+// queue — ограниченная очередь одного приемника со своей горутиной доставки.
+type queue struct {
+	name    string
+	sink    Sink
+	filter  Filter
+	events  chan queued
+	dropped atomic.Uint64
+}
+
+// Dispatcher раздает события аудита приемникам через ограниченные очереди и доставляет их пачками.
+// Медленный или недоступный приемник не задерживает остальные.
+type Dispatcher struct {
+	opts Options
+	log  *zap.Logger
+
+	mu     sync.RWMutex
+	queues []*queue
+	closed bool
+	wg     sync.WaitGroup
+
+	// done закрывается в начале Close, чтобы освободить вызовы, ждущие места в очереди.
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func NewDispatcher(opts Options, log *zap.Logger) *Dispatcher {
+	return &Dispatcher{opts: opts.withDefaults(), log: log, done: make(chan struct{})}
+}
+
+// Register добавляет приемник под именем name и запускает его доставку.
+// В очередь приемника попадают только события, подходящие под filter.
+func (d *Dispatcher) Register(name string, sink Sink, filter Filter) {
+	q := &queue{name: name, sink: sink, filter: filter, events: make(chan queued, d.opts.QueueSize)}
+
+	d.mu.Lock()
+	d.queues = append(d.queues, q)
+	d.mu.Unlock()
+
+	d.wg.Add(1)
+
+	go d.run(q)
+}
+
+// NotifyAll ставит событие в очередь каждого приемника. После Close события не принимаются.
+// С OverflowBlock вызов ждет места в очереди до Close; ctx задает только родительский спан.
+func (d *Dispatcher) NotifyAll(ctx context.Context, e Event) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		return
+	}
+
+	item := queued{event: e, span: trace.SpanContextFromContext(ctx)}
+
+	for _, q := range d.queues {
+		if !q.filter.Match(e) {
+			continue
+		}
+
+		if d.opts.Overflow == OverflowBlock {
+			select {
+			case q.events <- item:
+				metrics.AuditEnqueued(q.name)
+			case <-d.done:
+				q.dropped.Add(1)
+				metrics.AuditDropped(q.name)
+			}
+
+			continue
+		}
+
+		select {
+		case q.events <- item:
+			metrics.AuditEnqueued(q.name)
+		default:
+			q.dropped.Add(1)
+			metrics.AuditDropped(q.name)
+		}
+	}
+}
+
+// QueueStats — состояние очереди одного приемника.
+type QueueStats struct {
+	Sink     string `json:"sink"`
+	Queued   int    `json:"queued"`
+	Capacity int    `json:"capacity"`
+	Dropped  uint64 `json:"dropped"`
+}
+
+// Stats возвращает состояние очередей приемников в порядке регистрации.
+func (d *Dispatcher) Stats() []QueueStats {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	stats := make([]QueueStats, 0, len(d.queues))
+
+	for _, q := range d.queues {
+		stats = append(stats, QueueStats{Sink: q.name, Queued: len(q.events), Capacity: cap(q.events), Dropped: q.dropped.Load()})
+	}
+
+	return stats
+}
+
+// Dropped возвращает число отброшенных событий по приемникам.
+func (d *Dispatcher) Dropped() map[string]uint64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	dropped := make(map[string]uint64, len(d.queues))
+
+	for _, q := range d.queues {
+		dropped[q.name] = q.dropped.Load()
+	}
+
+	return dropped
+}
+
+// run собирает события очереди в пачки по BatchSize и отправляет неполную пачку раз в FlushInterval.
+// После закрытия очереди оставшиеся события отправляются последней пачкой.
+func (d *Dispatcher) run(q *queue) {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(d.opts.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Event, 0, d.opts.BatchSize)
+	spans := make([]trace.SpanContext, 0, d.opts.BatchSize)
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+
+		ctx, span := flushSpan(q.name, spans)
+		err := q.sink.Write(ctx, batch)
+
+		tracing.End(span, err)
+
+		if err != nil {
+			metrics.AuditFailed(q.name, len(batch))
+			d.log.Error("Ошибка доставки событий аудита", zap.String("sink", q.name), zap.Int("events", len(batch)), zap.Error(err))
+		} else {
+			metrics.AuditDone(q.name, len(batch))
+		}
+
+		batch, spans = batch[:0], spans[:0]
+	}
+
+	for {
+		select {
+		case item, ok := <-q.events:
+			if !ok {
+				flush()
+				return
+			}
+
+			batch = append(batch, item.event)
+			spans = append(spans, item.span)
+
+			if len(batch) >= d.opts.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// flushSpan открывает спан отправки пачки. Он продолжает трассу первого события пачки,
+// чтобы приемник получил ее в traceparent, и ссылается на спаны остальных событий.
+func flushSpan(sink string, spans []trace.SpanContext) (context.Context, trace.Span) {
+	ctx := context.Background()
+
+	var links []trace.Link
+
+	for _, sc := range spans {
+		if !sc.IsValid() {
+			continue
+		}
+
+		if !trace.SpanContextFromContext(ctx).IsValid() {
+			ctx = trace.ContextWithRemoteSpanContext(ctx, sc)
+			continue
+		}
+
+		links = append(links, trace.Link{SpanContext: sc})
+	}
+
+	return tracing.Start(ctx, "audit.flush",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithLinks(links...),
+		trace.WithAttributes(attribute.String("audit.sink", sink), attribute.Int("audit.events", len(spans))),
+	)
+}
+
+// Close перестает принимать события, доставляет накопленные и закрывает приемники.
+// Если ctx истекает раньше, недоставленные события теряются, а приемники остаются открытыми.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	// Вызовы NotifyAll, ждущие места, держат блокировку чтения: сначала отпускаем их.
+	d.stopOnce.Do(func() { close(d.done) })
+
+	d.mu.Lock()
+
+	if d.closed {
+		d.mu.Unlock()
+		return nil
+	}
+
+	d.closed = true
+
+	for _, q := range d.queues {
+		close(q.events)
+	}
+
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return fmt.Errorf("события аудита доставлены не полностью: %w", ctx.Err())
+	}
+
+	for _, q := range d.queues {
+		if err := q.sink.Close(); err != nil {
+			d.log.Error("Ошибка закрытия приемника аудита", zap.String("sink", q.name), zap.Error(err))
+		}
+
+		if dropped := q.dropped.Load(); dropped > 0 {
+			d.log.Warn("События аудита отброшены из-за переполнения очереди", zap.String("sink", q.name), zap.Uint64("dropped", dropped))
+		}
+	}
+
+	return nil
+}
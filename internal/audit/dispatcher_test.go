@@ -0,0 +1,273 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+)
+
+// memorySink запоминает пачки; release, если задан, задерживает каждую запись.
+type memorySink struct {
+	mu      sync.Mutex
+	batches [][]Event
+	release chan struct{}
+	closed  bool
+}
+
+func (s *memorySink) Write(_ context.Context, events []Event) error {
+	if s.release != nil {
+		<-s.release
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.batches = append(s.batches, append([]Event(nil), events...))
+
+	return nil
+}
+
+func (s *memorySink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+
+	return nil
+}
+
+func (s *memorySink) sizes() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var sizes []int
+
+	for _, batch := range s.batches {
+		sizes = append(sizes, len(batch))
+	}
+
+	return sizes
+}
+
+func TestDispatcherBatching(t *testing.T) {
+	sink := &memorySink{}
+	d := NewDispatcher(Options{BatchSize: 3, FlushInterval: time.Hour}, zap.NewNop())
+	d.Register("memory", sink, Filter{})
+
+	for i := range 7 {
+		d.NotifyAll(context.Background(), Event{Timestamp: int64(i)})
+	}
+
+	assert.Eventually(t, func() bool { return len(sink.sizes()) == 2 }, time.Second, 10*time.Millisecond, "Полные пачки не отправлены")
+
+	require.NoError(t, d.Close(context.Background()))
+
+	assert.Equal(t, []int{3, 3, 1}, sink.sizes(), "При остановке остаток очереди должен отправиться последней пачкой")
+	assert.True(t, sink.closed, "Приемник не закрыт")
+
+	d.NotifyAll(context.Background(), Event{})
+	assert.Equal(t, []int{3, 3, 1}, sink.sizes(), "После остановки события не принимаются")
+}
+
+func TestDispatcherFlushInterval(t *testing.T) {
+	sink := &memorySink{}
+	d := NewDispatcher(Options{BatchSize: 100, FlushInterval: 20 * time.Millisecond}, zap.NewNop())
+	d.Register("memory", sink, Filter{})
+
+	d.NotifyAll(context.Background(), Event{})
+
+	assert.Eventually(t, func() bool { return len(sink.sizes()) == 1 }, time.Second, 10*time.Millisecond, "Неполная пачка не отправлена по таймеру")
+
+	require.NoError(t, d.Close(context.Background()))
+}
+
+func TestDispatcherOverflow(t *testing.T) {
+	t.Run("drop", func(t *testing.T) {
+		sink := &memorySink{release: make(chan struct{})}
+		d := NewDispatcher(Options{QueueSize: 2, BatchSize: 1, Overflow: OverflowDrop}, zap.NewNop())
+		d.Register("slow", sink, Filter{})
+
+		// Первое событие забирает занятая горутина доставки, два следующих заполняют очередь.
+		for range 6 {
+			d.NotifyAll(context.Background(), Event{})
+			time.Sleep(5 * time.Millisecond)
+		}
+
+		assert.Equal(t, uint64(3), d.Dropped()["slow"], "Число отброшенных событий не совпадает с ожидаемым")
+
+		close(sink.release)
+		require.NoError(t, d.Close(context.Background()))
+		assert.Len(t, sink.sizes(), 3, "Доставлены не все события из очереди")
+	})
+
+	t.Run("block", func(t *testing.T) {
+		sink := &memorySink{release: make(chan struct{})}
+		d := NewDispatcher(Options{QueueSize: 1, BatchSize: 1, Overflow: OverflowBlock}, zap.NewNop())
+		d.Register("slow", sink, Filter{})
+
+		d.NotifyAll(context.Background(), Event{})
+		time.Sleep(5 * time.Millisecond)
+		d.NotifyAll(context.Background(), Event{})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// Очередь полна: вызов ждет места, и отмена контекста действия его не прерывает.
+		notified := make(chan struct{})
+
+		go func() {
+			d.NotifyAll(ctx, Event{})
+			close(notified)
+		}()
+
+		select {
+		case <-notified:
+			t.Fatal("Вызов должен ждать места в очереди")
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		close(sink.release)
+		<-notified
+
+		require.NoError(t, d.Close(context.Background()))
+		assert.Len(t, sink.sizes(), 3, "Доставлены не все события")
+		assert.Zero(t, d.Dropped()["slow"], "Ни одно событие не должно быть отброшено")
+	})
+
+	t.Run("block close", func(t *testing.T) {
+		sink := &memorySink{release: make(chan struct{})}
+		d := NewDispatcher(Options{QueueSize: 1, BatchSize: 1, Overflow: OverflowBlock}, zap.NewNop())
+		d.Register("slow", sink, Filter{})
+
+		d.NotifyAll(context.Background(), Event{})
+		time.Sleep(5 * time.Millisecond)
+		d.NotifyAll(context.Background(), Event{})
+
+		notified := make(chan struct{})
+
+		go func() {
+			d.NotifyAll(context.Background(), Event{})
+			close(notified)
+		}()
+
+		time.Sleep(5 * time.Millisecond)
+
+		// Close не ждет освобождения места: ожидающее событие отбрасывается.
+		closed := make(chan error)
+
+		go func() { closed <- d.Close(context.Background()) }()
+
+		<-notified
+		assert.Equal(t, uint64(1), d.Dropped()["slow"], "Ожидавшее при остановке событие должно считаться отброшенным")
+
+		close(sink.release)
+		require.NoError(t, <-closed)
+		assert.Len(t, sink.sizes(), 2, "Доставлены не все события из очереди")
+	})
+}
+
+func TestDispatcherFilter(t *testing.T) {
+	all, failures := &memorySink{}, &memorySink{}
+	d := NewDispatcher(Options{FlushInterval: time.Hour}, zap.NewNop())
+	d.Register("all", all, Filter{})
+	d.Register("failures", failures, Filter{Actions: []string{ActionShorten, ActionDelete}, Statuses: []string{StatusFailure}})
+
+	for _, e := range []Event{
+		{Action: ActionShorten, Status: StatusSuccess},
+		{Action: ActionShorten, Status: StatusFailure},
+		{Action: ActionFollow, Status: StatusFailure},
+		{Action: ActionDelete, Status: StatusFailure},
+	} {
+		d.NotifyAll(context.Background(), e)
+	}
+
+	require.NoError(t, d.Close(context.Background()))
+
+	assert.Equal(t, []int{4}, all.sizes(), "Приемник без фильтра должен получить все события")
+	assert.Equal(t, []int{2}, failures.sizes(), "Фильтр пропустил лишние события")
+}
+
+func TestHTTPSink(t *testing.T) {
+	var received []Event
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			return
+		}
+
+		assert.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+
+		scanner := bufio.NewScanner(r.Body)
+
+		for scanner.Scan() {
+			var e Event
+
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &e))
+
+			received = append(received, e)
+		}
+	}))
+	defer server.Close()
+
+	sink := NewHTTPSink(server.URL)
+
+	require.NoError(t, sink.Write(context.Background(), []Event{{Action: ActionShorten}, {Action: ActionDelete}}))
+	assert.Equal(t, []Event{{Action: ActionShorten}, {Action: ActionDelete}}, received, "События не совпадают с отправленными")
+	assert.NoError(t, sink.Check(context.Background()))
+}
+
+func TestDispatcherTracePropagation(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	traceparent := make(chan string, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent <- r.Header.Get("traceparent")
+	}))
+	defer server.Close()
+
+	d := NewDispatcher(Options{BatchSize: 2, FlushInterval: time.Hour}, zap.NewNop())
+	d.Register("url", NewHTTPSink(server.URL), Filter{})
+
+	// события двух разных запросов попадают в одну пачку
+	first, firstSpan := otel.Tracer("test").Start(context.Background(), "GET /{id}")
+	second, secondSpan := otel.Tracer("test").Start(context.Background(), "POST /")
+
+	d.NotifyAll(first, Event{Action: ActionFollow})
+	d.NotifyAll(second, Event{Action: ActionShorten})
+
+	firstSpan.End()
+	secondSpan.End()
+
+	require.NoError(t, d.Close(context.Background()))
+
+	assert.Contains(t, <-traceparent, firstSpan.SpanContext().TraceID().String(),
+		"Отправка аудита должна продолжать трассу действия пользователя")
+
+	var flush sdktrace.ReadOnlySpan
+
+	for _, span := range recorder.Ended() {
+		if span.Name() == "audit.flush" {
+			flush = span
+		}
+	}
+
+	require.NotNil(t, flush, "Отправка пачки должна открывать спан")
+	require.Len(t, flush.Links(), 1, "Спан отправки должен ссылаться на остальные события пачки")
+	assert.Equal(t, secondSpan.SpanContext().TraceID(), flush.Links()[0].SpanContext.TraceID())
+}
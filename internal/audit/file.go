@@ -0,0 +1,432 @@
+package audit
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// segmentTimeFormat — метка времени в имени ротированного сегмента; сортируется как строка.
+const segmentTimeFormat = "20060102T150405.000000000Z"
+
+// Rotation — правила ротации файла аудита. Нулевые значения отключают соответствующее правило.
+type Rotation struct {
+	// MaxSize — размер файла в байтах, после которого начинается новый сегмент.
+	MaxSize int64
+	// Interval — как долго пишется один сегмент.
+	Interval time.Duration
+	// Compress сжимает ротированные сегменты gzip.
+	Compress bool
+	// MaxFiles — сколько ротированных сегментов хранить.
+	MaxFiles int
+	// MaxAge — сколько хранить ротированный сегмент.
+	MaxAge time.Duration
+}
+
+// FileSink дописывает события в файл цепочкой хешей Chain. Файл остается открытым,
+// а пачка событий записывается одной операцией через буфер.
+//
+// При ротации текущий файл переименовывается в path.<время UTC>[.gz], а цепочка продолжается
+// в новом файле, поэтому сегменты проверяются подряд: auditverify path.* path.
+type FileSink struct {
+	mu       sync.Mutex
+	path     string
+	file     *os.File
+	buf      *bufio.Writer
+	chain    *Chain
+	rotation Rotation
+	log      *zap.Logger
+	size     int64
+	openedAt time.Time
+}
+
+// NewFileSink открывает файл аудита path. Цепочка продолжается после последней записи
+// существующего файла или, если он пуст, последнего сегмента; непустой key подписывает записи HMAC.
+func NewFileSink(path string, key []byte, rotation Rotation, log *zap.Logger) (*FileSink, error) {
+	s := &FileSink{path: path, chain: NewChain(key), rotation: rotation, log: log}
+
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+
+	if err := s.resume(); err != nil {
+		s.file.Close()
+		return nil, fmt.Errorf("ошибка чтения файла аудита %s: %w", path, err)
+	}
+
+	return s, nil
+}
+
+// resume восстанавливает состояние цепочки из текущего файла или последнего сегмента.
+func (s *FileSink) resume() error {
+	if s.size > 0 {
+		return s.chain.Resume(io.NewSectionReader(s.file, 0, s.size))
+	}
+
+	segments, err := s.segments()
+
+	if err != nil || len(segments) == 0 {
+		return err
+	}
+
+	r, err := OpenSegment(segments[len(segments)-1])
+
+	if err != nil {
+		return err
+	}
+
+	defer r.Close()
+
+	return s.chain.Resume(r)
+}
+
+func (s *FileSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
+
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	s.file = file
+	s.buf = bufio.NewWriter(file)
+	s.size = info.Size()
+	s.openedAt = time.Now()
+
+	return nil
+}
+
+func (s *FileSink) Write(_ context.Context, events []Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.shouldRotate() {
+		if err := s.rotate(); err != nil {
+			s.log.Error("Ошибка ротации файла аудита, запись продолжается в текущий файл", zap.Error(err))
+		}
+	}
+
+	for _, e := range events {
+		line, err := s.chain.Seal(e)
+
+		if err != nil {
+			return err
+		}
+
+		n, _ := s.buf.Write(line)
+		s.size += int64(n)
+	}
+
+	return s.buf.Flush()
+}
+
+func (s *FileSink) shouldRotate() bool {
+	if s.size == 0 {
+		return false
+	}
+
+	return (s.rotation.MaxSize > 0 && s.size >= s.rotation.MaxSize) ||
+		(s.rotation.Interval > 0 && time.Since(s.openedAt) >= s.rotation.Interval)
+}
+
+// rotate закрывает текущий файл, переименовывает его в сегмент и открывает новый.
+// Сжатие и удаление старых сегментов идут в горутине диспетчера, а не в запросах пользователей.
+func (s *FileSink) rotate() error {
+	if err := errors.Join(s.buf.Flush(), s.file.Close()); err != nil {
+		return err
+	}
+
+	segment := s.path + "." + time.Now().UTC().Format(segmentTimeFormat)
+	renameErr := os.Rename(s.path, segment)
+
+	if err := s.open(); err != nil {
+		return err
+	}
+
+	if renameErr != nil {
+		return renameErr
+	}
+
+	if s.rotation.Compress {
+		if err := compress(segment); err != nil {
+			s.log.Error("Ошибка сжатия сегмента аудита", zap.String("segment", segment), zap.Error(err))
+		}
+	}
+
+	s.prune()
+
+	return nil
+}
+
+// Reopen закрывает и заново открывает файл по тому же пути. Нужен внешней ротации (logrotate):
+// после переименования файла сервис по SIGHUP начинает новый файл, продолжая цепочку.
+func (s *FileSink) Reopen() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := errors.Join(s.buf.Flush(), s.file.Close()); err != nil {
+		s.log.Error("Ошибка закрытия файла аудита", zap.Error(err))
+	}
+
+	return s.open()
+}
+
+// segments возвращает ротированные сегменты от старых к новым.
+func (s *FileSink) segments() ([]string, error) {
+	matches, err := filepath.Glob(s.path + ".*")
+
+	if err != nil {
+		return nil, err
+	}
+
+	segments := slices.DeleteFunc(matches, func(name string) bool {
+		_, ok := s.segmentTime(name)
+		return !ok
+	})
+
+	slices.Sort(segments)
+
+	return segments, nil
+}
+
+// segmentTime возвращает время ротации сегмента из его имени.
+func (s *FileSink) segmentTime(segment string) (time.Time, bool) {
+	stamp := strings.TrimSuffix(strings.TrimPrefix(segment, s.path+"."), ".gz")
+	rotatedAt, err := time.Parse(segmentTimeFormat, stamp)
+
+	return rotatedAt, err == nil
+}
+
+// prune удаляет сегменты сверх MaxFiles и старше MaxAge.
+func (s *FileSink) prune() {
+	if s.rotation.MaxFiles <= 0 && s.rotation.MaxAge <= 0 {
+		return
+	}
+
+	segments, err := s.segments()
+
+	if err != nil {
+		s.log.Error("Ошибка поиска сегментов аудита", zap.Error(err))
+		return
+	}
+
+	for i, segment := range segments {
+		expired := s.rotation.MaxFiles > 0 && len(segments)-i > s.rotation.MaxFiles
+
+		if !expired && s.rotation.MaxAge > 0 {
+			if info, err := os.Stat(segment); err == nil && time.Since(info.ModTime()) > s.rotation.MaxAge {
+				expired = true
+			}
+		}
+
+		if !expired {
+			continue
+		}
+
+		if err := os.Remove(segment); err != nil {
+			s.log.Error("Ошибка удаления сегмента аудита", zap.String("segment", segment), zap.Error(err))
+		}
+	}
+}
+
+// compress заменяет сегмент его gzip-копией.
+func compress(segment string) error {
+	src, err := os.Open(segment)
+
+	if err != nil {
+		return err
+	}
+
+	defer src.Close()
+
+	dst, err := os.OpenFile(segment+".gz", os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(dst)
+
+	if _, err := io.Copy(zw, src); err != nil {
+		zw.Close()
+		dst.Close()
+		os.Remove(dst.Name())
+
+		return err
+	}
+
+	if err := errors.Join(zw.Close(), dst.Close()); err != nil {
+		os.Remove(dst.Name())
+		return err
+	}
+
+	return os.Remove(segment)
+}
+
+// OpenSegment открывает файл или сегмент аудита, распаковывая сжатые gzip.
+func OpenSegment(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasSuffix(path, ".gz") {
+		return file, nil
+	}
+
+	zr, err := gzip.NewReader(file)
+
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{zr, file}, nil
+}
+
+// Query перебирает записи сегментов и текущего файла от старых к новым. Файлы открываются
+// под блокировкой, а читаются без нее: запись событий не останавливается, а выборка видит
+// состояние на момент вызова. Сегменты, ротированные раньше q.From, не читаются.
+func (s *FileSink) Query(ctx context.Context, q Query, fn func(Event) error) error {
+	readers, err := s.snapshot(q.From)
+
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		for _, r := range readers {
+			r.Close()
+		}
+	}()
+
+	skip, left := q.Offset, q.Limit
+
+	for _, r := range readers {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(nil, maxLine)
+
+		for scanner.Scan() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			var record Record
+
+			if err := json.Unmarshal(scanner.Bytes(), &record); err != nil || !q.Match(record.Event) {
+				continue
+			}
+
+			if skip > 0 {
+				skip--
+				continue
+			}
+
+			if err := fn(record.Event); err != nil {
+				return err
+			}
+
+			// При Limit == 0 счетчик уходит в минус и выборка не ограничивается.
+			if left--; left == 0 {
+				return nil
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// snapshot открывает сегменты, которые могут содержать события не раньше from, и текущий файл
+// в пределах уже записанных строк.
+func (s *FileSink) snapshot(from time.Time) ([]io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	segments, err := s.segments()
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Время события хранится в секундах, поэтому сегмент пропускается, только если он
+	// ротирован раньше начала секунды from.
+	from = from.Truncate(time.Second)
+	readers := make([]io.ReadCloser, 0, len(segments)+1)
+
+	closeAll := func() {
+		for _, r := range readers {
+			r.Close()
+		}
+	}
+
+	for _, segment := range segments {
+		if rotatedAt, _ := s.segmentTime(segment); rotatedAt.Before(from) {
+			continue
+		}
+
+		r, err := OpenSegment(segment)
+
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+
+		readers = append(readers, r)
+	}
+
+	file, err := os.Open(s.path)
+
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+
+	return append(readers, struct {
+		io.Reader
+		io.Closer
+	}{io.NewSectionReader(file, 0, s.size), file}), nil
+}
+
+// Check проверяет, что файл аудита доступен для записи.
+func (s *FileSink) Check(_ context.Context) error {
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_WRONLY, 0)
+
+	if err != nil {
+		return err
+	}
+
+	return file.Close()
+}
+
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return errors.Join(s.buf.Flush(), s.file.Close())
+}
@@ -0,0 +1,209 @@
+package audit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// verifyFiles проверяет цепочку по сегментам от старых к новым и текущему файлу.
+func verifyFiles(t *testing.T, key []byte, partial bool, paths ...string) (int, error) {
+	t.Helper()
+
+	verifier := NewVerifier(key)
+	verifier.Partial = partial
+
+	total := 0
+
+	for _, path := range paths {
+		r, err := OpenSegment(path)
+		require.NoError(t, err)
+
+		count, err := verifier.Verify(r)
+		r.Close()
+
+		total += count
+
+		if err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
+
+func segmentsOf(t *testing.T, path string) []string {
+	t.Helper()
+
+	segments, err := (&FileSink{path: path}).segments()
+	require.NoError(t, err)
+
+	return segments
+}
+
+func TestFileSinkResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	key := []byte("secret")
+
+	for range 2 {
+		sink, err := NewFileSink(path, key, Rotation{}, zap.NewNop())
+		require.NoError(t, err)
+
+		require.NoError(t, sink.Write(context.Background(), []Event{{Action: ActionShorten}, {Action: ActionFollow}}))
+		require.NoError(t, sink.Close())
+	}
+
+	count, err := verifyFiles(t, key, false, path)
+
+	assert.NoError(t, err, "Цепочка должна продолжаться после повторного открытия файла")
+	assert.Equal(t, 4, count, "Число записей не совпадает с ожидаемым")
+}
+
+func TestFileSinkRotation(t *testing.T) {
+	tests := []struct {
+		name     string
+		rotation Rotation
+		segments int
+		partial  bool
+	}{
+		{name: "по размеру", rotation: Rotation{MaxSize: 1}, segments: 4},
+		{name: "со сжатием", rotation: Rotation{MaxSize: 1, Compress: true}, segments: 4},
+		{name: "с ограничением числа сегментов", rotation: Rotation{MaxSize: 1, MaxFiles: 2}, segments: 2, partial: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audit.log")
+
+			sink, err := NewFileSink(path, nil, test.rotation, zap.NewNop())
+			require.NoError(t, err)
+
+			// Каждая пачка превышает MaxSize, поэтому перед следующей файл ротируется.
+			for range 5 {
+				require.NoError(t, sink.Write(context.Background(), []Event{{Action: ActionShorten}}))
+			}
+
+			require.NoError(t, sink.Close())
+
+			segments := segmentsOf(t, path)
+			require.Len(t, segments, test.segments, "Число сегментов не совпадает с ожидаемым")
+
+			for _, segment := range segments {
+				assert.Equal(t, test.rotation.Compress, strings.HasSuffix(segment, ".gz"), "Сжатие сегмента не совпадает с ожидаемым")
+			}
+
+			count, err := verifyFiles(t, nil, test.partial, append(segments, path)...)
+
+			assert.NoError(t, err, "Цепочка должна продолжаться через сегменты")
+			assert.Equal(t, test.segments+1, count, "Число записей не совпадает с ожидаемым")
+
+			if test.partial {
+				_, err := verifyFiles(t, nil, false, append(segments, path)...)
+				assert.Error(t, err, "Без -partial удаленное начало цепочки должно обнаруживаться")
+			}
+		})
+	}
+}
+
+func TestFileSinkReopen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.log")
+	rotated := filepath.Join(dir, "audit.log.1")
+
+	sink, err := NewFileSink(path, nil, Rotation{}, zap.NewNop())
+	require.NoError(t, err)
+
+	require.NoError(t, sink.Write(context.Background(), []Event{{Action: ActionShorten}}))
+
+	// Внешняя ротация: файл переименован, сервис получает SIGHUP.
+	require.NoError(t, os.Rename(path, rotated))
+	require.NoError(t, sink.Reopen())
+	require.NoError(t, sink.Write(context.Background(), []Event{{Action: ActionFollow}}))
+	require.NoError(t, sink.Close())
+
+	count, err := verifyFiles(t, nil, false, rotated, path)
+
+	assert.NoError(t, err, "Цепочка должна продолжаться в новом файле")
+	assert.Equal(t, 2, count, "Число записей не совпадает с ожидаемым")
+}
+
+func TestFileSinkResumeFromSegment(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	sink, err := NewFileSink(path, nil, Rotation{MaxSize: 1, Compress: true}, zap.NewNop())
+	require.NoError(t, err)
+
+	require.NoError(t, sink.Write(context.Background(), []Event{{Action: ActionShorten}}))
+
+	// Остановка сразу после ротации: текущий файл пуст, цепочка берется из последнего сегмента.
+	require.NoError(t, sink.rotate())
+	require.NoError(t, sink.Close())
+
+	sink, err = NewFileSink(path, nil, Rotation{}, zap.NewNop())
+	require.NoError(t, err)
+
+	require.NoError(t, sink.Write(context.Background(), []Event{{Action: ActionFollow}}))
+	require.NoError(t, sink.Close())
+
+	count, err := verifyFiles(t, nil, false, append(segmentsOf(t, path), path)...)
+
+	assert.NoError(t, err, "Цепочка должна продолжаться после последнего сегмента")
+	assert.Equal(t, 2, count, "Число записей не совпадает с ожидаемым")
+}
+
+func TestFileSinkQuery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	sink, err := NewFileSink(path, nil, Rotation{MaxSize: 1, Compress: true}, zap.NewNop())
+	require.NoError(t, err)
+
+	defer sink.Close()
+
+	// Каждое событие попадает в свой сегмент, последнее остается в текущем файле.
+	for i, e := range []Event{
+		{Action: ActionShorten, UserID: "u1", ShortCode: "a"},
+		{Action: ActionFollow, UserID: "u2", ShortCode: "a"},
+		{Action: ActionShorten, UserID: "u1", ShortCode: "b"},
+		{Action: ActionDelete, UserID: "u1", ShortCode: "a"},
+		{Action: ActionFollow, UserID: "u2", ShortCode: "b"},
+	} {
+		e.Timestamp = int64(1000 + i)
+		require.NoError(t, sink.Write(context.Background(), []Event{e}))
+	}
+
+	require.Len(t, segmentsOf(t, path), 4, "Число сегментов не совпадает с ожидаемым")
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []int64
+	}{
+		{name: "все события", want: []int64{1000, 1001, 1002, 1003, 1004}},
+		{name: "по пользователю", query: Query{UserID: "u1"}, want: []int64{1000, 1002, 1003}},
+		{name: "по действию и ссылке", query: Query{Action: ActionFollow, ShortCode: "b"}, want: []int64{1004}},
+		{name: "по интервалу", query: Query{From: time.Unix(1001, 0), To: time.Unix(1003, 0)}, want: []int64{1001, 1002}},
+		{name: "страница", query: Query{Offset: 1, Limit: 2}, want: []int64{1001, 1002}},
+		{name: "страница выборки", query: Query{UserID: "u1", Offset: 2, Limit: 2}, want: []int64{1003}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []int64
+
+			err := sink.Query(context.Background(), test.query, func(e Event) error {
+				got = append(got, e.Timestamp)
+				return nil
+			})
+
+			require.NoError(t, err)
+			assert.Equal(t, test.want, got, "Выборка не совпадает с ожидаемой")
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// auditColumns — колонки таблицы audit_events, заполняемые приемником.
+var auditColumns = []string{"ts", "action", "status", "user_id", "url", "short_code", "client_ip", "request_id", "error"}
+
+// PostgresSink сохраняет события в таблицу audit_events основной базы. Пачка записывается
+// одной командой COPY.
+type PostgresSink struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresSink(pool *pgxpool.Pool) *PostgresSink {
+	return &PostgresSink{pool: pool}
+}
+
+func (s *PostgresSink) Write(ctx context.Context, events []Event) error {
+	_, err := s.pool.CopyFrom(ctx, pgx.Identifier{"audit_events"}, auditColumns, pgx.CopyFromSlice(len(events), func(i int) ([]any, error) {
+		e := events[i]
+
+		return []any{time.Unix(e.Timestamp, 0), e.Action, e.Status, e.UserID, e.URL, e.ShortCode, e.ClientIP, e.RequestID, e.Error}, nil
+	}))
+
+	return err
+}
+
+// Query выбирает события из audit_events в порядке записи. Время события хранится с точностью
+// до секунды, поэтому границы интервала округляются так же, как в Query.Match.
+func (s *PostgresSink) Query(ctx context.Context, q Query, fn func(Event) error) error {
+	var (
+		where []string
+		args  []any
+	)
+
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+
+	if q.UserID != "" {
+		add("user_id = $%d", q.UserID)
+	}
+
+	if q.Action != "" {
+		add("action = $%d", q.Action)
+	}
+
+	if q.ShortCode != "" {
+		add("short_code = $%d", q.ShortCode)
+	}
+
+	if !q.From.IsZero() {
+		add("ts >= $%d", q.From.Truncate(time.Second))
+	}
+
+	if !q.To.IsZero() {
+		add("ts < $%d", q.To.Truncate(time.Second))
+	}
+
+	query := "SELECT " + strings.Join(auditColumns, ", ") + " FROM audit_events"
+
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+
+	query += " ORDER BY id"
+
+	if q.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", q.Limit)
+	}
+
+	if q.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET %d", q.Offset)
+	}
+
+	rows, err := s.pool.Query(ctx, query, args...)
+
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			e  Event
+			ts time.Time
+		)
+
+		if err := rows.Scan(&ts, &e.Action, &e.Status, &e.UserID, &e.URL, &e.ShortCode, &e.ClientIP, &e.RequestID, &e.Error); err != nil {
+			return err
+		}
+
+		e.Timestamp = ts.Unix()
+
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// Check проверяет доступность базы.
+func (s *PostgresSink) Check(ctx context.Context) error {
+	return s.pool.Ping(ctx)
+}
+
+// Close ничего не делает: пулом соединений владеет хранилище.
+func (s *PostgresSink) Close() error {
+	return nil
+}
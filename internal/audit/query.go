@@ -0,0 +1,37 @@
+package audit
+
+import (
+	"context"
+	"time"
+)
+
+// Query — условия выборки событий аудита. Пустые поля не ограничивают выборку.
+// События возвращаются в порядке записи.
+type Query struct {
+	UserID    string
+	Action    string
+	ShortCode string
+	// From и To ограничивают время события: From включительно, To не включительно.
+	From time.Time
+	To   time.Time
+	// Offset — сколько подходящих событий пропустить.
+	Offset int
+	// Limit — сколько событий вернуть; 0 — все подходящие.
+	Limit int
+}
+
+// Match сообщает, подходит ли событие под условия выборки без учета Offset и Limit.
+func (q Query) Match(e Event) bool {
+	return (q.UserID == "" || e.UserID == q.UserID) &&
+		(q.Action == "" || e.Action == q.Action) &&
+		(q.ShortCode == "" || e.ShortCode == q.ShortCode) &&
+		(q.From.IsZero() || e.Timestamp >= q.From.Unix()) &&
+		(q.To.IsZero() || e.Timestamp < q.To.Unix())
+}
+
+// Querier — приемник, из которого можно прочитать сохраненные события.
+type Querier interface {
+	// Query передает fn подходящие события по одному, не загружая выборку в память целиком.
+	// Ошибка fn прекращает чтение и возвращается вызывающему.
+	Query(ctx context.Context, q Query, fn func(Event) error) error
+}
@@ -0,0 +1,97 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/tracing"
+)
+
+// Sink — приемник событий аудита. Write получает пачку событий в порядке их поступления
+// и вызывается из одной горутины диспетчера.
+type Sink interface {
+	Write(ctx context.Context, events []Event) error
+	Close() error
+}
+
+// HTTPSink отправляет пачку событий одним POST-запросом в формате NDJSON: событие на строку.
+type HTTPSink struct {
+	url    string
+	client *retryablehttp.Client
+}
+
+// NewHTTPSink создает приемник с собственным клиентом: повторы настраиваются один раз
+// и не влияют на другие клиенты сервиса.
+func NewHTTPSink(url string) *HTTPSink {
+	client := retryablehttp.NewClient()
+	client.RetryMax = 3
+	client.RetryWaitMin = time.Second
+	client.RetryWaitMax = 5 * time.Second
+	client.Logger = nil
+
+	return &HTTPSink{url: url, client: client}
+}
+
+func (s *HTTPSink) Write(ctx context.Context, events []Event) error {
+	var body bytes.Buffer
+
+	encoder := json.NewEncoder(&body)
+
+	for _, e := range events {
+		if err := encoder.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	req, err := retryablehttp.NewRequestWithContext(ctx, http.MethodPost, s.url, body.Bytes())
+
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	tracing.Inject(ctx, req.Header)
+
+	resp, err := s.client.Do(req)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("приемник аудита ответил %s", resp.Status)
+	}
+
+	return nil
+}
+
+// Check проверяет, что приемник аудита отвечает по сети. Любой HTTP-ответ считается признаком доступности.
+func (s *HTTPSink) Check(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, s.url, nil)
+
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.client.HTTPClient.Do(req)
+
+	if err != nil {
+		return err
+	}
+
+	return resp.Body.Close()
+}
+
+func (s *HTTPSink) Close() error {
+	s.client.HTTPClient.CloseIdleConnections()
+
+	return nil
+}
@@ -0,0 +1,189 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// facilityAudit — facility «log audit» (13) из RFC 5424.
+const facilityAudit = 13
+
+// Уровни важности RFC 5424.
+const (
+	severityWarning = 4
+	severityInfo    = 6
+)
+
+// sdID — идентификатор структурированных данных события; 32473 — номер предприятия для примеров из RFC 5612.
+const sdID = "audit@32473"
+
+// dialTimeout ограничивает подключение к серверу syslog.
+const dialTimeout = 5 * time.Second
+
+// SyslogSink отправляет события в syslog в формате RFC 5424: поля события передаются
+// структурированными данными, а само событие в JSON — текстом сообщения.
+// По TCP и потоковому Unix-сокету сообщения разделяются подсчетом октетов (RFC 6587).
+type SyslogSink struct {
+	mu       sync.Mutex
+	network  string
+	address  string
+	hostname string
+	appName  string
+	conn     net.Conn
+}
+
+// NewSyslogSink разбирает адрес сервера syslog: udp://host:514, tcp://host:601, unix:///run/syslog.sock
+// или unixgram:///dev/log. Подключение устанавливается при первой отправке.
+func NewSyslogSink(address, appName string) (*SyslogSink, error) {
+	u, err := url.Parse(address)
+
+	if err != nil {
+		return nil, fmt.Errorf("некорректный адрес syslog %q: %w", address, err)
+	}
+
+	s := &SyslogSink{network: u.Scheme, appName: appName}
+
+	switch u.Scheme {
+	case "udp", "tcp":
+		if u.Port() == "" {
+			return nil, fmt.Errorf("в адресе syslog %q не указан порт", address)
+		}
+
+		s.address = u.Host
+	case "unix", "unixgram":
+		s.address = u.Path
+	default:
+		return nil, fmt.Errorf("неподдерживаемый протокол syslog %q: ожидается udp, tcp, unix или unixgram", u.Scheme)
+	}
+
+	if s.hostname, err = os.Hostname(); err != nil {
+		s.hostname = "-"
+	}
+
+	return s, nil
+}
+
+func (s *SyslogSink) Write(ctx context.Context, events []Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		dialer := net.Dialer{Timeout: dialTimeout}
+		conn, err := dialer.DialContext(ctx, s.network, s.address)
+
+		if err != nil {
+			return err
+		}
+
+		s.conn = conn
+	}
+
+	for _, e := range events {
+		msg, err := s.format(e)
+
+		if err != nil {
+			return err
+		}
+
+		if s.stream() {
+			msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+		}
+
+		// После ошибки соединение пересоздается при следующей пачке.
+		if _, err := s.conn.Write(msg); err != nil {
+			s.conn.Close()
+			s.conn = nil
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *SyslogSink) stream() bool {
+	return s.network == "tcp" || s.network == "unix"
+}
+
+// format собирает сообщение RFC 5424:
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD-ELEMENT] MSG.
+func (s *SyslogSink) format(e Event) ([]byte, error) {
+	payload, err := json.Marshal(e)
+
+	if err != nil {
+		return nil, err
+	}
+
+	severity := severityInfo
+
+	if e.Status == StatusFailure {
+		severity = severityWarning
+	}
+
+	var msg bytes.Buffer
+
+	fmt.Fprintf(&msg, "<%d>1 %s %s %s %d %s [%s",
+		facilityAudit*8+severity,
+		time.Unix(e.Timestamp, 0).UTC().Format(time.RFC3339),
+		header(s.hostname), header(s.appName), os.Getpid(), header(e.Action), sdID)
+
+	for _, param := range [][2]string{
+		{"status", e.Status},
+		{"user_id", e.UserID},
+		{"short_code", e.ShortCode},
+		{"client_ip", e.ClientIP},
+		{"request_id", e.RequestID},
+	} {
+		if param[1] != "" {
+			fmt.Fprintf(&msg, ` %s="%s"`, param[0], sdEscaper.Replace(param[1]))
+		}
+	}
+
+	msg.WriteString("] ")
+	msg.Write(payload)
+
+	return msg.Bytes(), nil
+}
+
+// sdEscaper экранирует значения параметров структурированных данных (RFC 5424, раздел 6.3.3).
+var sdEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// header приводит значение поля заголовка к печатным ASCII без пробелов; пустое значение — "-".
+func header(value string) string {
+	value = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return -1
+		}
+
+		return r
+	}, value)
+
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
+
+func (s *SyslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+
+	err := s.conn.Close()
+	s.conn = nil
+
+	return err
+}
@@ -0,0 +1,134 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syslogLine — заголовок RFC 5424 со структурированными данными и JSON события.
+var syslogLine = regexp.MustCompile(`^<(\d+)>1 \S+ \S+ shortener \d+ (\S+) \[audit@32473 ((?:[^\]\\]|\\.)*)\] (\{.*\})$`)
+
+func TestSyslogSinkUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	sink, err := NewSyslogSink("udp://"+conn.LocalAddr().String(), "shortener")
+	require.NoError(t, err)
+	defer sink.Close()
+
+	events := []Event{
+		{Timestamp: 1, Action: ActionShorten, Status: StatusSuccess, UserID: "u1", URL: "https://example.com", ShortCode: "abc"},
+		{Timestamp: 2, Action: ActionFollow, Status: StatusFailure, UserID: `"quoted]`, Error: "not found"},
+	}
+
+	require.NoError(t, sink.Write(context.Background(), events))
+
+	tests := []struct {
+		priority string
+		msgID    string
+		params   string
+	}{
+		{priority: "110", msgID: ActionShorten, params: `status="success" user_id="u1" short_code="abc"`},
+		{priority: "108", msgID: ActionFollow, params: `status="failure" user_id="\"quoted\]"`},
+	}
+
+	buf := make([]byte, 4096)
+
+	for _, test := range tests {
+		n, _, err := conn.ReadFrom(buf)
+		require.NoError(t, err)
+
+		match := syslogLine.FindStringSubmatch(string(buf[:n]))
+		require.NotNil(t, match, "Сообщение не соответствует формату RFC 5424: %s", buf[:n])
+
+		assert.Equal(t, test.priority, match[1], "Приоритет не совпадает с ожидаемым")
+		assert.Equal(t, test.msgID, match[2], "MSGID не совпадает с ожидаемым")
+		assert.Equal(t, test.params, strings.TrimSpace(match[3]), "Структурированные данные не совпадают с ожидаемыми")
+	}
+}
+
+func TestSyslogSinkTCPFraming(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	received := make(chan []string, 1)
+
+	go func() {
+		conn, err := ln.Accept()
+
+		if err != nil {
+			return
+		}
+
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+
+		var messages []string
+
+		for range 2 {
+			size, err := r.ReadString(' ')
+
+			if err != nil {
+				break
+			}
+
+			n, _ := strconv.Atoi(strings.TrimSpace(size))
+			msg := make([]byte, n)
+
+			if _, err := io.ReadFull(r, msg); err != nil {
+				break
+			}
+
+			messages = append(messages, string(msg))
+		}
+
+		received <- messages
+	}()
+
+	sink, err := NewSyslogSink("tcp://"+ln.Addr().String(), "shortener")
+	require.NoError(t, err)
+	defer sink.Close()
+
+	require.NoError(t, sink.Write(context.Background(), []Event{
+		{Timestamp: 1, Action: ActionShorten, Status: StatusSuccess},
+		{Timestamp: 2, Action: ActionDelete, Status: StatusSuccess},
+	}))
+
+	messages := <-received
+	require.Len(t, messages, 2, "Число сообщений не совпадает с ожидаемым")
+
+	for _, msg := range messages {
+		assert.Regexp(t, syslogLine, msg, "Границы сообщений определены неверно")
+	}
+}
+
+func TestNewSyslogSink(t *testing.T) {
+	tests := []struct {
+		address string
+		wantErr bool
+	}{
+		{address: "udp://localhost:514"},
+		{address: "unixgram:///dev/log"},
+		{address: "tcp://localhost", wantErr: true},
+		{address: "http://localhost:514", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.address, func(t *testing.T) {
+			_, err := NewSyslogSink(test.address, "shortener")
+			assert.Equal(t, test.wantErr, err != nil, "Результат разбора адреса не совпадает с ожидаемым")
+		})
+	}
+}
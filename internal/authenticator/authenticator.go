@@ -2,6 +2,7 @@ package authenticator
 
 import (
 	"context"
+	"crypto/sha256"
 	"fmt"
 
 	"github.com/google/uuid"
@@ -14,6 +15,22 @@ type UserID string
 
 const userKey = UserID("userID")
 
+// newSessionKey отмечает запросы, для которых сессия выдана только что, а не предъявлена клиентом.
+const newSessionKey = UserID("newSession")
+
+// hashKey — ключ подписи cookie, общий для всех аутентификаторов процесса,
+// чтобы выданная сессия проходила проверку в последующих запросах.
+// Без SetKey ключ случайный, и сессии не переживают перезапуск процесса.
+var hashKey = securecookie.GenerateRandomKey(32)
+
+// SetKey выводит ключ подписи cookie из секрета настроек, чтобы сессии оставались
+// действительными после перезапуска и на всех экземплярах сервиса.
+// Вызывается до запуска серверов.
+func SetKey(secret string) {
+	sum := sha256.Sum256([]byte(secret))
+	hashKey = sum[:]
+}
+
 type Authenticator struct {
 	cookieManager *securecookie.SecureCookie
 }
@@ -25,7 +42,7 @@ type CookieData struct {
 
 func NewAuthenticator() *Authenticator {
 	return &Authenticator{
-		cookieManager: securecookie.New(securecookie.GenerateRandomKey(32), nil),
+		cookieManager: securecookie.New(hashKey, nil),
 	}
 }
 
@@ -48,6 +65,10 @@ func GetUserKey() UserID {
 	return userKey
 }
 
+func GetCookieName() string {
+	return cookieName
+}
+
 func (a *Authenticator) Authenticate(ctx context.Context, p AuthProvider) (context.Context, error) {
 	var cookieValue string
 
@@ -55,7 +76,9 @@ func (a *Authenticator) Authenticate(ctx context.Context, p AuthProvider) (conte
 
 	var userID string
 
-	if err != nil {
+	isNew := err != nil
+
+	if isNew {
 		cookieData, err := a.createSignedCookie()
 
 		if err != nil {
@@ -76,9 +99,19 @@ func (a *Authenticator) Authenticate(ctx context.Context, p AuthProvider) (conte
 
 	p.SetCookie(ctx, cookieName, cookieValue)
 
+	ctx = context.WithValue(ctx, newSessionKey, isNew)
+
 	return context.WithValue(ctx, userKey, userID), nil
 }
 
+// IsNewSession сообщает, что сессия выдана в текущем запросе. Такой userID не подтверждает
+// личность клиента: получить новый можно на каждый запрос, просто не передавая cookie.
+func IsNewSession(ctx context.Context) bool {
+	isNew, _ := ctx.Value(newSessionKey).(bool)
+
+	return isNew
+}
+
 func (a *Authenticator) createSignedCookie() (*CookieData, error) {
 	userID, err := GenerateUniqueUserID()
 
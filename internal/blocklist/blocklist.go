@@ -0,0 +1,399 @@
+// Package blocklist запрещает сокращать адреса и переходить по ссылкам на нежелательные ресурсы.
+//
+// Правила хранятся в текстовом файле, по одному в строке:
+//
+//	# комментарий
+//	domain phishing.example          точное совпадение хоста
+//	suffix example.org               хост и все его поддомены
+//	regex  ^https?://[^/]+/login\.php полный адрес
+//	suffix suspicious.example warn   вместо блокировки показать предупреждение
+//
+// Поля разделяются пробелами, поэтому пробел в регулярном выражении записывается как \s.
+// Файл перечитывается при изменении, а правила, добавленные через API, сохраняются в него же
+// (комментарии при этом не сохраняются).
+package blocklist
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"go.uber.org/zap"
+	"golang.org/x/net/idna"
+)
+
+// Типы правил.
+const (
+	TypeDomain = "domain"
+	TypeSuffix = "suffix"
+	TypeRegex  = "regex"
+)
+
+// Действия при срабатывании правила.
+const (
+	ActionBlock = "block"
+	ActionWarn  = "warn"
+)
+
+// ReloadInterval — как часто проверяется изменение файла правил.
+const ReloadInterval = 5 * time.Second
+
+var ErrRuleNotFound = errors.New("правило не найдено")
+
+type Rule struct {
+	Type    string `json:"type"`
+	Pattern string `json:"pattern"`
+	Action  string `json:"action"`
+
+	re *regexp.Regexp
+}
+
+// BlockedError возвращается, если адрес попадает под правило списка.
+type BlockedError struct {
+	Rule Rule
+}
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("адрес запрещен правилом %s %s", e.Rule.Type, e.Rule.Pattern)
+}
+
+// IsBlocked сообщает, что ошибка вызвана правилом списка, и возвращает сработавшее правило.
+func IsBlocked(err error) (*BlockedError, bool) {
+	var blocked *BlockedError
+
+	ok := errors.As(err, &blocked)
+
+	return blocked, ok
+}
+
+// NewRule проверяет и подготавливает правило.
+func NewRule(ruleType, pattern, action string) (Rule, error) {
+	rule := Rule{Type: strings.ToLower(ruleType), Pattern: strings.TrimSpace(pattern), Action: strings.ToLower(action)}
+
+	if rule.Action == "" {
+		rule.Action = ActionBlock
+	}
+
+	if rule.Action != ActionBlock && rule.Action != ActionWarn {
+		return Rule{}, fmt.Errorf("неизвестное действие %q", action)
+	}
+
+	if rule.Pattern == "" {
+		return Rule{}, errors.New("пустой шаблон правила")
+	}
+
+	if strings.IndexFunc(rule.Pattern, unicode.IsSpace) >= 0 {
+		return Rule{}, errors.New("шаблон правила не должен содержать пробелов")
+	}
+
+	switch rule.Type {
+	case TypeDomain, TypeSuffix:
+		// Хосты проверяемых адресов уже приведены к punycode, шаблоны приводятся так же.
+		host, err := idna.Lookup.ToASCII(strings.TrimPrefix(strings.ToLower(rule.Pattern), "."))
+
+		if err != nil {
+			return Rule{}, fmt.Errorf("некорректный домен: %w", err)
+		}
+
+		rule.Pattern = host
+	case TypeRegex:
+		re, err := regexp.Compile(rule.Pattern)
+
+		if err != nil {
+			return Rule{}, fmt.Errorf("некорректное регулярное выражение: %w", err)
+		}
+
+		rule.re = re
+	default:
+		return Rule{}, fmt.Errorf("неизвестный тип правила %q", ruleType)
+	}
+
+	return rule, nil
+}
+
+func (r Rule) matches(host, rawURL string) bool {
+	switch r.Type {
+	case TypeDomain:
+		return host == r.Pattern
+	case TypeSuffix:
+		return host == r.Pattern || strings.HasSuffix(host, "."+r.Pattern)
+	case TypeRegex:
+		return r.re.MatchString(rawURL)
+	}
+
+	return false
+}
+
+func (r Rule) same(other Rule) bool {
+	return r.Type == other.Type && r.Pattern == other.Pattern
+}
+
+func (r Rule) String() string {
+	line := r.Type + " " + r.Pattern
+
+	if r.Action == ActionWarn {
+		line += " " + ActionWarn
+	}
+
+	return line
+}
+
+// List — список правил, синхронизированный с файлом.
+type List struct {
+	mu      sync.RWMutex
+	path    string
+	rules   []Rule
+	modTime time.Time
+	log     *zap.Logger
+}
+
+// Load читает правила из файла path. Отсутствующий файл означает пустой список.
+func Load(path string, log *zap.Logger) (*List, error) {
+	l := &List{path: path, log: log}
+
+	if err := l.reload(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Check проверяет адрес. Если он попадает под правило, возвращается *BlockedError;
+// правила блокировки имеют приоритет над предупреждениями.
+func (l *List) Check(rawURL string) error {
+	u, err := url.Parse(rawURL)
+
+	if err != nil {
+		return nil
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var warn *Rule
+
+	for i, rule := range l.rules {
+		if !rule.matches(host, rawURL) {
+			continue
+		}
+
+		if rule.Action == ActionBlock {
+			return &BlockedError{Rule: rule}
+		}
+
+		if warn == nil {
+			warn = &l.rules[i]
+		}
+	}
+
+	if warn != nil {
+		return &BlockedError{Rule: *warn}
+	}
+
+	return nil
+}
+
+// Rules возвращает копию текущих правил.
+func (l *List) Rules() []Rule {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return append([]Rule(nil), l.rules...)
+}
+
+// Add добавляет правило или меняет действие существующего и сохраняет список в файл.
+func (l *List) Add(rule Rule) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rules := append([]Rule(nil), l.rules...)
+	replaced := false
+
+	for i := range rules {
+		if rules[i].same(rule) {
+			rules[i] = rule
+			replaced = true
+		}
+	}
+
+	if !replaced {
+		rules = append(rules, rule)
+	}
+
+	return l.save(rules)
+}
+
+// Remove удаляет правило и сохраняет список в файл.
+func (l *List) Remove(ruleType, pattern string) error {
+	target, err := NewRule(ruleType, pattern, ActionBlock)
+
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rules := make([]Rule, 0, len(l.rules))
+
+	for _, rule := range l.rules {
+		if !rule.same(target) {
+			rules = append(rules, rule)
+		}
+	}
+
+	if len(rules) == len(l.rules) {
+		return ErrRuleNotFound
+	}
+
+	return l.save(rules)
+}
+
+// save атомарно перезаписывает файл и применяет правила. Вызывается под l.mu.
+func (l *List) save(rules []Rule) error {
+	if l.path != "" {
+		tmp, err := os.CreateTemp(filepath.Dir(l.path), ".blocklist-*")
+
+		if err != nil {
+			return err
+		}
+
+		w := bufio.NewWriter(tmp)
+
+		for _, rule := range rules {
+			fmt.Fprintln(w, rule.String())
+		}
+
+		err = errors.Join(w.Flush(), tmp.Close())
+
+		if err == nil {
+			err = os.Rename(tmp.Name(), l.path)
+		}
+
+		if err != nil {
+			os.Remove(tmp.Name())
+			return err
+		}
+
+		if info, err := os.Stat(l.path); err == nil {
+			l.modTime = info.ModTime()
+		}
+	}
+
+	l.rules = rules
+
+	return nil
+}
+
+// reload перечитывает файл, если он изменился с прошлого чтения.
+func (l *List) reload() error {
+	if l.path == "" {
+		return nil
+	}
+
+	info, err := os.Stat(l.path)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	l.mu.RLock()
+	unchanged := info.ModTime().Equal(l.modTime)
+	l.mu.RUnlock()
+
+	if unchanged {
+		return nil
+	}
+
+	rules, err := parseFile(l.path)
+
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.rules = rules
+	l.modTime = info.ModTime()
+	l.mu.Unlock()
+
+	return nil
+}
+
+func parseFile(path string) ([]Rule, error) {
+	file, err := os.Open(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	var rules []Rule
+
+	scanner := bufio.NewScanner(file)
+	line := 0
+
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("%s:%d: ожидается «тип шаблон [warn]»", path, line)
+		}
+
+		action := ActionBlock
+
+		if len(fields) == 3 {
+			action = fields[2]
+		}
+
+		rule, err := NewRule(fields[0], fields[1], action)
+
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, scanner.Err()
+}
+
+// Watch перечитывает файл правил при изменении, пока не отменен ctx.
+// Ошибка в файле не сбрасывает действующие правила.
+func (l *List) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := l.reload(); err != nil {
+				l.log.Error("Ошибка перечитывания списка блокировок", zap.String("path", l.path), zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package blocklist
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+
+	require.NoError(t, os.WriteFile(path, []byte(`# тестовый список
+domain phishing.example
+suffix bad.example
+suffix suspicious.example warn
+domain block.suspicious.example
+regex  ^https?://[^/]+/wp-login\.php
+domain пример.рф
+`), 0o644))
+
+	l, err := Load(path, zap.NewNop())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		url    string
+		action string
+	}{
+		{name: "точный домен", url: "https://phishing.example/a", action: ActionBlock},
+		{name: "поддомен точного домена", url: "https://www.phishing.example/a"},
+		{name: "суффикс", url: "http://a.b.bad.example/", action: ActionBlock},
+		{name: "суффикс без поддомена", url: "http://bad.example/", action: ActionBlock},
+		{name: "похожий домен", url: "http://notbad.example/"},
+		{name: "предупреждение", url: "https://www.suspicious.example/", action: ActionWarn},
+		{name: "блокировка важнее предупреждения", url: "https://block.suspicious.example/", action: ActionBlock},
+		{name: "регулярное выражение", url: "https://site.example/wp-login.php", action: ActionBlock},
+		{name: "IDN", url: "https://xn--e1afmkfd.xn--p1ai/", action: ActionBlock},
+		{name: "разрешенный адрес", url: "https://practicum.yandex.ru/"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := l.Check(test.url)
+
+			if test.action == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			blocked, ok := IsBlocked(err)
+			require.True(t, ok, "Ожидается срабатывание правила, получено %v", err)
+			assert.Equal(t, test.action, blocked.Rule.Action, "Действие правила не совпадает с ожидаемым")
+		})
+	}
+}
+
+func TestAddRemovePersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+
+	l, err := Load(path, zap.NewNop())
+	require.NoError(t, err)
+
+	rule, err := NewRule("suffix", "Bad.Example", "")
+	require.NoError(t, err)
+	require.NoError(t, l.Add(rule))
+
+	warn, err := NewRule("suffix", "bad.example", "warn")
+	require.NoError(t, err)
+	require.NoError(t, l.Add(warn))
+
+	assert.Len(t, l.Rules(), 1, "Повторное добавление должно заменять правило")
+
+	reloaded, err := Load(path, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, []Rule{warn}, reloaded.Rules(), "Правила не сохранились в файл")
+
+	require.NoError(t, l.Remove("suffix", "bad.example"))
+	assert.ErrorIs(t, l.Remove("suffix", "bad.example"), ErrRuleNotFound)
+	assert.NoError(t, l.Check("https://bad.example/"))
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	require.NoError(t, os.WriteFile(path, []byte("domain a.example\n"), 0o644))
+
+	l, err := Load(path, zap.NewNop())
+	require.NoError(t, err)
+	assert.Error(t, l.Check("https://a.example/"))
+
+	require.NoError(t, os.WriteFile(path, []byte("domain b.example\n"), 0o644))
+	later := time.Now().Add(time.Second)
+	require.NoError(t, os.Chtimes(path, later, later))
+	require.NoError(t, l.reload())
+
+	assert.NoError(t, l.Check("https://a.example/"), "Правило должно исчезнуть после изменения файла")
+	assert.Error(t, l.Check("https://b.example/"))
+
+	// ошибка в файле не сбрасывает действующие правила
+	require.NoError(t, os.WriteFile(path, []byte("unknown c.example\n"), 0o644))
+	later = later.Add(time.Second)
+	require.NoError(t, os.Chtimes(path, later, later))
+	assert.Error(t, l.reload())
+	assert.Error(t, l.Check("https://b.example/"))
+}
@@ -0,0 +1,187 @@
+package clientip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// Subnets — список подсетей IPv4 и IPv6, разобранный при старте сервиса.
+type Subnets []*net.IPNet
+
+// ParseSubnets разбирает подсети в нотации CIDR; отдельный адрес считается подсетью из одного адреса.
+// Для пустого списка возвращается nil.
+func ParseSubnets(items []string) (Subnets, error) {
+	var subnets Subnets
+
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+
+		if item == "" {
+			continue
+		}
+
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+
+			if ip == nil {
+				return nil, fmt.Errorf("некорректный адрес %q", item)
+			}
+
+			bits := 8 * net.IPv6len
+
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+
+			subnets = append(subnets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+
+			continue
+		}
+
+		_, subnet, err := net.ParseCIDR(item)
+
+		if err != nil {
+			return nil, fmt.Errorf("некорректная подсеть %q: %w", item, err)
+		}
+
+		subnets = append(subnets, subnet)
+	}
+
+	return subnets, nil
+}
+
+// Contains сообщает, входит ли адрес хотя бы в одну из подсетей. Адреса IPv4, записанные
+// как IPv6 (::ffff:a.b.c.d), сравниваются с подсетями IPv4.
+func (s Subnets) Contains(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+
+	for _, subnet := range s {
+		if subnet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Resolver определяет адрес клиента. Заголовкам X-Forwarded-For и X-Real-IP он доверяет,
+// только если запрос пришел от прокси из доверенного списка; иначе используется адрес соединения.
+type Resolver struct {
+	trusted Subnets
+}
+
+// NewResolver разбирает список доверенных прокси: подсети CIDR или отдельные адреса.
+func NewResolver(trustedProxies []string) (*Resolver, error) {
+	trusted, err := ParseSubnets(trustedProxies)
+
+	if err != nil {
+		return nil, fmt.Errorf("ошибка разбора доверенных прокси: %w", err)
+	}
+
+	return &Resolver{trusted: trusted}, nil
+}
+
+func (r *Resolver) isTrusted(ip net.IP) bool {
+	return r.trusted.Contains(ip)
+}
+
+// resolve выбирает адрес клиента по адресу соединения и заголовкам прокси.
+// В X-Forwarded-For берется самый правый адрес, не принадлежащий доверенным прокси.
+func (r *Resolver) resolve(remote net.IP, forwardedFor []string, realIP string) net.IP {
+	if remote == nil || !r.isTrusted(remote) {
+		return remote
+	}
+
+	var hops []string
+
+	for _, header := range forwardedFor {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+
+		if ip == nil {
+			break
+		}
+
+		if !r.isTrusted(ip) {
+			return ip
+		}
+	}
+
+	if ip := net.ParseIP(strings.TrimSpace(realIP)); ip != nil {
+		return ip
+	}
+
+	return remote
+}
+
+// FromRequest возвращает адрес клиента HTTP-запроса.
+func (r *Resolver) FromRequest(req *http.Request) net.IP {
+	return r.resolve(parseHostIP(req.RemoteAddr), req.Header.Values("X-Forwarded-For"), req.Header.Get("X-Real-IP"))
+}
+
+// FromContext возвращает адрес клиента gRPC-вызова по адресу соединения и метаданным.
+func (r *Resolver) FromContext(ctx context.Context) net.IP {
+	var remote net.IP
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		remote = parseHostIP(p.Addr.String())
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	var realIP string
+
+	if values := md.Get("x-real-ip"); len(values) > 0 {
+		realIP = values[0]
+	}
+
+	return r.resolve(remote, md.Get("x-forwarded-for"), realIP)
+}
+
+func parseHostIP(addr string) net.IP {
+	host, _, err := net.SplitHostPort(addr)
+
+	if err != nil {
+		host = addr
+	}
+
+	return net.ParseIP(host)
+}
+
+type ipKey struct{}
+
+// WithIP сохраняет адрес клиента в контексте.
+func WithIP(ctx context.Context, ip net.IP) context.Context {
+	return context.WithValue(ctx, ipKey{}, ip)
+}
+
+// IP возвращает адрес клиента, сохраненный Middleware или UnaryServerInterceptor; nil, если его нет.
+func IP(ctx context.Context) net.IP {
+	ip, _ := ctx.Value(ipKey{}).(net.IP)
+
+	return ip
+}
+
+// Middleware сохраняет адрес клиента HTTP-запроса в контексте.
+func (r *Resolver) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		next.ServeHTTP(w, req.WithContext(WithIP(req.Context(), r.FromRequest(req))))
+	})
+}
+
+// UnaryServerInterceptor сохраняет адрес клиента gRPC-вызова в контексте.
+func (r *Resolver) UnaryServerInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	return handler(WithIP(ctx, r.FromContext(ctx)), req)
+}
@@ -0,0 +1,82 @@
+package clientip
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSubnets(t *testing.T) {
+	subnets, err := ParseSubnets([]string{"10.0.0.0/8", " 2001:db8::/32 ", "192.0.2.7", "::1", ""})
+	require.NoError(t, err)
+
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "10.1.2.3", want: true},
+		{ip: "::ffff:10.1.2.3", want: true},
+		{ip: "11.0.0.1", want: false},
+		{ip: "2001:db8::42", want: true},
+		{ip: "2001:db9::42", want: false},
+		{ip: "192.0.2.7", want: true},
+		{ip: "192.0.2.8", want: false},
+		{ip: "::1", want: true},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, subnets.Contains(net.ParseIP(test.ip)), "Принадлежность адреса %s подсетям не совпадает с ожидаемой", test.ip)
+	}
+
+	assert.False(t, subnets.Contains(nil), "Неизвестный адрес не должен входить в подсети")
+
+	empty, err := ParseSubnets([]string{"", " "})
+	require.NoError(t, err)
+	assert.Nil(t, empty, "Для пустого списка ожидается nil")
+
+	for _, item := range []string{"10.0.0.0/33", "not-an-ip", "2001:db8::/129"} {
+		_, err := ParseSubnets([]string{item})
+		assert.Error(t, err, "Ожидается ошибка разбора %q", item)
+	}
+}
+
+func TestFromRequest(t *testing.T) {
+	resolver, err := NewResolver([]string{"10.0.0.0/8", "fd00::/8"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		realIP       string
+		want         string
+	}{
+		{name: "без прокси", remoteAddr: "198.51.100.1:1234", want: "198.51.100.1"},
+		{name: "подделка заголовков клиентом", remoteAddr: "198.51.100.1:1234", forwardedFor: "10.0.0.5", realIP: "10.0.0.5", want: "198.51.100.1"},
+		{name: "X-Real-IP от доверенного прокси", remoteAddr: "10.0.0.2:1234", realIP: "203.0.113.9", want: "203.0.113.9"},
+		{name: "цепочка доверенных прокси", remoteAddr: "10.0.0.2:1234", forwardedFor: "192.0.2.66, 203.0.113.9, 10.0.0.3", want: "203.0.113.9"},
+		{name: "прокси IPv6", remoteAddr: "[fd00::1]:1234", forwardedFor: "2001:db8::7", want: "2001:db8::7"},
+		{name: "доверенный прокси без заголовков", remoteAddr: "10.0.0.2:1234", want: "10.0.0.2"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = test.remoteAddr
+
+			if test.forwardedFor != "" {
+				r.Header.Set("X-Forwarded-For", test.forwardedFor)
+			}
+
+			if test.realIP != "" {
+				r.Header.Set("X-Real-IP", test.realIP)
+			}
+
+			assert.Equal(t, test.want, resolver.FromRequest(r).String(), "Адрес клиента не совпадает с ожидаемым")
+		})
+	}
+}
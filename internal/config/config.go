@@ -5,6 +5,9 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"dario.cat/mergo"
 	"github.com/flash1nho/go-musthave-shortener-tpl/internal/logger"
@@ -12,8 +15,17 @@ import (
 )
 
 const (
-	DefaultHost = "localhost:8080"
-	DefaultURL  = "http://localhost:8080"
+	DefaultHost        = "localhost:8080"
+	DefaultURL         = "http://localhost:8080"
+	DefaultMetricsHost = "localhost:9090"
+
+	DefaultRateLimitBackend  = "memory"
+	DefaultRateLimitCreate   = "60/m"
+	DefaultRateLimitRedirect = "600/m"
+	DefaultRateLimitAdmin    = "30/m"
+
+	// DefaultHSTSMaxAge — срок действия HSTS в секундах (один год).
+	DefaultHSTSMaxAge = 365 * 24 * 60 * 60
 )
 
 // Config — единая структура для всех источников
@@ -24,21 +36,160 @@ type Config struct {
 	DatabaseDSN     string `json:"database_dsn" env:"DATABASE_DSN"`
 	AuditFile       string `json:"-" env:"AUDIT_FILE"`
 	AuditURL        string `json:"-" env:"AUDIT_URL"`
+	AuditHMACKey    string `json:"audit_hmac_key" env:"AUDIT_HMAC_KEY"`
 	EnableHTTPS     bool   `json:"enable_https" env:"ENABLE_HTTPS"`
 	ConfigPath      string `json:"-" env:"CONFIG"`
 	TrustedSubnet   string `json:"trusted_subnet" env:"TRUSTED_SUBNET"`
+	MetricsAddress  string `json:"metrics_address" env:"METRICS_ADDRESS"`
+	TracingExporter string `json:"tracing_exporter" env:"TRACING_EXPORTER"`
+	TracingEndpoint string `json:"tracing_endpoint" env:"TRACING_ENDPOINT"`
+	LogLevel        string `json:"log_level" env:"LOG_LEVEL"`
+	LogEncoding     string `json:"log_encoding" env:"LOG_ENCODING"`
+	TrustedProxies  string `json:"trusted_proxies" env:"TRUSTED_PROXIES"`
+	SessionKey      string `json:"session_key" env:"SESSION_KEY"`
+
+	RateLimitBackend  string `json:"rate_limit_backend" env:"RATE_LIMIT_BACKEND"`
+	RateLimitCreate   string `json:"rate_limit_create" env:"RATE_LIMIT_CREATE"`
+	RateLimitRedirect string `json:"rate_limit_redirect" env:"RATE_LIMIT_REDIRECT"`
+	RateLimitAdmin    string `json:"rate_limit_admin" env:"RATE_LIMIT_ADMIN"`
+
+	QuotaDaily int `json:"quota_daily" env:"QUOTA_DAILY"`
+	QuotaTotal int `json:"quota_total" env:"QUOTA_TOTAL"`
+
+	URLAllowedSchemes string `json:"url_allowed_schemes" env:"URL_ALLOWED_SCHEMES"`
+	URLMaxLength      int    `json:"url_max_length" env:"URL_MAX_LENGTH"`
+	URLStripTracking  bool   `json:"url_strip_tracking" env:"URL_STRIP_TRACKING"`
+
+	BlocklistFile     string `json:"blocklist_file" env:"BLOCKLIST_FILE"`
+	DestinationPolicy string `json:"destination_policy" env:"DESTINATION_POLICY"`
+
+	CORSAllowedOrigins string `json:"cors_allowed_origins" env:"CORS_ALLOWED_ORIGINS"`
+	CSRFProtection     bool   `json:"csrf_protection" env:"CSRF_PROTECTION"`
+	HSTSMaxAge         int    `json:"hsts_max_age" env:"HSTS_MAX_AGE"`
+
+	AuditQueueSize     int    `json:"audit_queue_size" env:"AUDIT_QUEUE_SIZE"`
+	AuditBatchSize     int    `json:"audit_batch_size" env:"AUDIT_BATCH_SIZE"`
+	AuditFlushInterval string `json:"audit_flush_interval" env:"AUDIT_FLUSH_INTERVAL"`
+	AuditOverflow      string `json:"audit_overflow" env:"AUDIT_OVERFLOW"`
+
+	AuditMaxSize        int    `json:"audit_max_size" env:"AUDIT_MAX_SIZE"`
+	AuditRotateInterval string `json:"audit_rotate_interval" env:"AUDIT_ROTATE_INTERVAL"`
+	AuditCompress       bool   `json:"audit_compress" env:"AUDIT_COMPRESS"`
+	AuditMaxFiles       int    `json:"audit_max_files" env:"AUDIT_MAX_FILES"`
+	AuditMaxAge         string `json:"audit_max_age" env:"AUDIT_MAX_AGE"`
+
+	// AuditSinks в переменной окружения задается массивом JSON.
+	AuditSinks []AuditSink `json:"audit_sinks" env:"AUDIT_SINKS"`
+}
+
+// AuditSink — приемник аудита из списка audit_sinks. Приемники из -audit-file и -audit-url
+// добавляются к списку без фильтров.
+type AuditSink struct {
+	// Name — имя приемника в метриках и пробах готовности; по умолчанию совпадает с типом.
+	Name string `json:"name"`
+	// Type — file|url|syslog|postgres.
+	Type string `json:"type"`
+	// Address — путь к файлу (file), адрес приемника (url) или сервера syslog (syslog),
+	// например udp://host:514 или unixgram:///dev/log; для postgres используется основная база.
+	Address string `json:"address"`
+	// Actions и Statuses ограничивают события приемника; пустой список — все события.
+	Actions  []string `json:"actions"`
+	Statuses []string `json:"statuses"`
 }
 
 type SettingsObject struct {
-	Server1       Server
-	Server2       Server
-	Log           *zap.Logger
-	DatabaseDSN   string
-	FilePath      string
-	AuditFile     string
-	AuditURL      string
+	Server1     Server
+	Server2     Server
+	Log         *zap.Logger
+	DatabaseDSN string
+	FilePath    string
+	AuditFile   string
+	AuditURL    string
+	// AuditHMACKey подписывает записи файла аудита HMAC-SHA256; пустое значение — только цепочка хешей.
+	AuditHMACKey  string
+	AuditQueue    AuditQueue
+	AuditRotation AuditRotation
+	AuditSinks    []AuditSink
 	EnableHTTPS   bool
-	TrustedSubnet string
+	// TrustedSubnets — подсети IPv4 и IPv6, которым открыты служебные маршруты; пустой список — никому.
+	TrustedSubnets []string
+	MetricsAddr    string
+	Tracing        Tracing
+	// TrustedProxies — прокси, которым доверяются заголовки X-Forwarded-For и X-Real-IP.
+	TrustedProxies []string
+	// SessionKey — секрет подписи cookie сессии; пустое значение — случайный ключ на время работы процесса.
+	SessionKey string
+	RateLimit  RateLimit
+	Quota      Quota
+	URLNorm    URLNorm
+	// BlocklistFile — файл правил списка блокировок; пустое значение — правила только в памяти.
+	BlocklistFile string
+	// DestinationDeny — запрещенные категории адресов назначения (destpolicy): loopback,private,link-local,self или all.
+	DestinationDeny []string
+	Security        Security
+}
+
+// AuditQueue — очереди доставки событий аудита; нулевые значения заменяются значениями по умолчанию пакета audit.
+type AuditQueue struct {
+	// Size — емкость очереди каждого приемника.
+	Size int
+	// BatchSize — сколько событий передается приемнику за раз.
+	BatchSize int
+	// FlushInterval — как долго неполная пачка ждет добора перед отправкой.
+	FlushInterval time.Duration
+	// Overflow — поведение при переполнении очереди: drop|block.
+	Overflow string
+}
+
+// AuditRotation — ротация и срок хранения файла аудита; нулевые значения отключают соответствующее правило.
+type AuditRotation struct {
+	// MaxSize — размер файла в байтах, после которого начинается новый сегмент.
+	MaxSize int64
+	// Interval — как долго пишется один сегмент.
+	Interval time.Duration
+	// Compress сжимает ротированные сегменты gzip.
+	Compress bool
+	// MaxFiles — сколько ротированных сегментов хранить.
+	MaxFiles int
+	// MaxAge — сколько хранить ротированный сегмент.
+	MaxAge time.Duration
+}
+
+// Security — защита браузерных клиентов: CORS, CSRF и HSTS (только при HTTPS).
+type Security struct {
+	// AllowedOrigins — источники, которым разрешены кросс-доменные запросы с cookie; "*" — любые без cookie.
+	AllowedOrigins []string
+	// CSRF требует токен double-submit в изменяющих запросах с cookie сессии.
+	CSRF bool
+	// HSTSMaxAge — срок действия Strict-Transport-Security в секундах; отрицательное значение отключает HSTS.
+	HSTSMaxAge int
+}
+
+// URLNorm — правила проверки и нормализации сокращаемых адресов.
+type URLNorm struct {
+	AllowedSchemes []string
+	MaxLength      int
+	StripTracking  bool
+}
+
+// Quota — квоты пользователя на создание ссылок за сутки и всего; 0 — без ограничения.
+// Индивидуальные квоты задаются в таблице user_quotas.
+type Quota struct {
+	Daily int
+	Total int
+}
+
+// RateLimit — лимиты частоты запросов в формате ratelimit.ParseLimit и хранилище корзин: memory|postgres.
+type RateLimit struct {
+	Backend  string
+	Create   string
+	Redirect string
+	Admin    string
+}
+
+type Tracing struct {
+	Exporter string
+	Endpoint string
 }
 
 type Server struct {
@@ -47,7 +198,7 @@ type Server struct {
 }
 
 func Settings() SettingsObject {
-	logger.Initialize("info")
+	logger.Initialize(logger.DefaultLevel, logger.DefaultEncoding)
 
 	// 1. Конфигурация из Флагов
 	flagCfg := parseFlags()
@@ -85,17 +236,83 @@ func Settings() SettingsObject {
 	if finalCfg.BaseURL == "" {
 		finalCfg.BaseURL = "http://" + finalCfg.ServerAddress
 	}
+	if finalCfg.MetricsAddress == "" {
+		finalCfg.MetricsAddress = DefaultMetricsHost
+	}
+	if finalCfg.RateLimitBackend == "" {
+		finalCfg.RateLimitBackend = DefaultRateLimitBackend
+	}
+	if finalCfg.RateLimitCreate == "" {
+		finalCfg.RateLimitCreate = DefaultRateLimitCreate
+	}
+	if finalCfg.RateLimitRedirect == "" {
+		finalCfg.RateLimitRedirect = DefaultRateLimitRedirect
+	}
+	if finalCfg.RateLimitAdmin == "" {
+		finalCfg.RateLimitAdmin = DefaultRateLimitAdmin
+	}
+	if finalCfg.HSTSMaxAge == 0 {
+		finalCfg.HSTSMaxAge = DefaultHSTSMaxAge
+	}
+	if finalCfg.LogLevel == "" {
+		finalCfg.LogLevel = logger.DefaultLevel
+	}
+	if finalCfg.LogEncoding == "" {
+		finalCfg.LogEncoding = logger.DefaultEncoding
+	}
+
+	if err := logger.Initialize(finalCfg.LogLevel, finalCfg.LogEncoding); err != nil {
+		logger.Log.Error("Ошибка настройки логгера, используются значения по умолчанию", zap.Error(err))
+	}
 
 	return SettingsObject{
-		Server1:       Server{Addr: finalCfg.ServerAddress, BaseURL: finalCfg.BaseURL},
-		Server2:       Server{Addr: finalCfg.ServerAddress, BaseURL: finalCfg.BaseURL},
-		Log:           logger.Log,
-		DatabaseDSN:   finalCfg.DatabaseDSN,
-		FilePath:      finalCfg.FileStoragePath,
-		AuditFile:     finalCfg.AuditFile,
-		AuditURL:      finalCfg.AuditURL,
-		EnableHTTPS:   finalCfg.EnableHTTPS,
-		TrustedSubnet: finalCfg.TrustedSubnet,
+		Server1:      Server{Addr: finalCfg.ServerAddress, BaseURL: finalCfg.BaseURL},
+		Server2:      Server{Addr: finalCfg.ServerAddress, BaseURL: finalCfg.BaseURL},
+		Log:          logger.Log,
+		DatabaseDSN:  finalCfg.DatabaseDSN,
+		FilePath:     finalCfg.FileStoragePath,
+		AuditFile:    finalCfg.AuditFile,
+		AuditURL:     finalCfg.AuditURL,
+		AuditHMACKey: finalCfg.AuditHMACKey,
+		AuditQueue: AuditQueue{
+			Size:          finalCfg.AuditQueueSize,
+			BatchSize:     finalCfg.AuditBatchSize,
+			FlushInterval: parseDuration("audit_flush_interval", finalCfg.AuditFlushInterval),
+			Overflow:      strings.ToLower(finalCfg.AuditOverflow),
+		},
+		AuditSinks: finalCfg.AuditSinks,
+		AuditRotation: AuditRotation{
+			MaxSize:  int64(finalCfg.AuditMaxSize) << 20,
+			Interval: parseDuration("audit_rotate_interval", finalCfg.AuditRotateInterval),
+			Compress: finalCfg.AuditCompress,
+			MaxFiles: finalCfg.AuditMaxFiles,
+			MaxAge:   parseDuration("audit_max_age", finalCfg.AuditMaxAge),
+		},
+		EnableHTTPS:    finalCfg.EnableHTTPS,
+		TrustedSubnets: splitList(finalCfg.TrustedSubnet),
+		MetricsAddr:    finalCfg.MetricsAddress,
+		Tracing:        Tracing{Exporter: finalCfg.TracingExporter, Endpoint: finalCfg.TracingEndpoint},
+		TrustedProxies: splitList(finalCfg.TrustedProxies),
+		SessionKey:     finalCfg.SessionKey,
+		RateLimit: RateLimit{
+			Backend:  finalCfg.RateLimitBackend,
+			Create:   finalCfg.RateLimitCreate,
+			Redirect: finalCfg.RateLimitRedirect,
+			Admin:    finalCfg.RateLimitAdmin,
+		},
+		Quota: Quota{Daily: finalCfg.QuotaDaily, Total: finalCfg.QuotaTotal},
+		URLNorm: URLNorm{
+			AllowedSchemes: splitList(finalCfg.URLAllowedSchemes),
+			MaxLength:      finalCfg.URLMaxLength,
+			StripTracking:  finalCfg.URLStripTracking,
+		},
+		BlocklistFile:   finalCfg.BlocklistFile,
+		DestinationDeny: splitList(finalCfg.DestinationPolicy),
+		Security: Security{
+			AllowedOrigins: splitList(finalCfg.CORSAllowedOrigins),
+			CSRF:           finalCfg.CSRFProtection,
+			HSTSMaxAge:     finalCfg.HSTSMaxAge,
+		},
 	}
 }
 
@@ -108,7 +325,38 @@ func parseFlags() Config {
 	file := flag.String("f", "", "путь к файлу для хранения данных")
 	aFile := flag.String("audit-file", "", "путь к файлу-приёмнику, в который сохраняются логи аудита")
 	aURL := flag.String("audit-url", "", "полный URL удаленного сервера-приёмника, куда отправляются логи аудита")
-	trustedSubnet := flag.String("t", "", "доверенная подсеть")
+	aHMACKey := flag.String("audit-hmac-key", "", "ключ HMAC для подписи записей файла аудита")
+	aQueueSize := flag.Int("audit-queue-size", 0, "емкость очереди событий аудита каждого приемника, по умолчанию 1024")
+	aBatchSize := flag.Int("audit-batch-size", 0, "сколько событий аудита отправлять приемнику за раз, по умолчанию 100")
+	aFlushInterval := flag.String("audit-flush-interval", "", "как долго неполная пачка событий аудита ждет отправки, например 500ms; по умолчанию 1s")
+	aMaxSize := flag.Int("audit-max-size", 0, "размер файла аудита в МиБ, после которого он ротируется; 0 — без ограничения")
+	aRotateInterval := flag.String("audit-rotate-interval", "", "как часто ротировать файл аудита, например 24h; по умолчанию не ротируется по времени")
+	aCompress := flag.Bool("audit-compress", false, "сжимать ротированные сегменты файла аудита gzip")
+	aMaxFiles := flag.Int("audit-max-files", 0, "сколько ротированных сегментов файла аудита хранить; 0 — все")
+	aMaxAge := flag.String("audit-max-age", "", "сколько хранить ротированные сегменты файла аудита, например 720h; по умолчанию без ограничения")
+	aOverflow := flag.String("audit-overflow", "", "поведение при переполнении очереди аудита: drop (по умолчанию) — отбросить событие, block — ждать места")
+	trustedSubnet := flag.String("t", "", "доверенные подсети IPv4 и IPv6 через запятую (CIDR или адреса), которым открыты служебные маршруты; без них маршруты закрыты")
+	metricsAddress := flag.String("metrics-addr", "", "адрес служебного сервера метрик, по умолчанию "+DefaultMetricsHost)
+	tracingExporter := flag.String("tracing-exporter", "", "экспортер трассировок: none|stdout|file|otlp")
+	tracingEndpoint := flag.String("tracing-endpoint", "", "путь к файлу (file) или адрес коллектора (otlp) для трассировок")
+	logLevel := flag.String("log-level", "", "уровень логирования: debug|info|warn|error")
+	logEncoding := flag.String("log-encoding", "", "формат логов: json|console")
+	trustedProxies := flag.String("trusted-proxies", "", "доверенные прокси (CIDR или адреса через запятую), чьим заголовкам X-Forwarded-For/X-Real-IP можно верить")
+	sessionKey := flag.String("session-key", "", "секрет подписи cookie сессии; без него сессии не переживают перезапуск")
+	rateLimitBackend := flag.String("rate-limit-backend", "", "хранилище лимитов частоты: memory|postgres")
+	rateLimitCreate := flag.String("rate-limit-create", "", "лимит создания ссылок, например 60/m или 10/s:50; off — без лимита")
+	rateLimitRedirect := flag.String("rate-limit-redirect", "", "лимит переходов по ссылкам, например 600/m")
+	rateLimitAdmin := flag.String("rate-limit-admin", "", "лимит служебных маршрутов /api/internal, например 30/m")
+	quotaDaily := flag.Int("quota-daily", 0, "сколько ссылок пользователь может создать за сутки, 0 — без ограничения")
+	quotaTotal := flag.Int("quota-total", 0, "сколько ссылок пользователь может создать всего, 0 — без ограничения")
+	urlAllowedSchemes := flag.String("url-allowed-schemes", "", "допустимые схемы сокращаемых адресов через запятую, по умолчанию http,https")
+	urlMaxLength := flag.Int("url-max-length", 0, "максимальная длина сокращаемого адреса, по умолчанию 2048")
+	urlStripTracking := flag.Bool("url-strip-tracking", false, "удалять из адресов параметры отслеживания (utm_*, fbclid, gclid...)")
+	blocklistFile := flag.String("blocklist-file", "", "файл правил списка блокировок доменов и адресов")
+	destinationPolicy := flag.String("destination-policy", "", "запрещенные адреса назначения через запятую: loopback,private,link-local,self или all; по умолчанию проверка отключена")
+	corsAllowedOrigins := flag.String("cors-allowed-origins", "", "источники браузерных клиентов через запятую, которым разрешены кросс-доменные запросы, например https://dash.example")
+	csrfProtection := flag.Bool("csrf", false, "требовать CSRF-токен в изменяющих запросах с cookie сессии")
+	hstsMaxAge := flag.Int("hsts-max-age", 0, "срок действия HSTS в секундах при HTTPS, по умолчанию год; -1 отключает")
 	conf := flag.String("c", "", "Файл конфигурации")
 	flag.StringVar(conf, "config", "", "Файл конфигурации")
 	enableHTTPS := flag.Bool("s", false, "Enable HTTPS")
@@ -122,13 +370,50 @@ func parseFlags() Config {
 	c.ConfigPath = *conf
 	c.AuditFile = *aFile
 	c.AuditURL = *aURL
+	c.AuditHMACKey = *aHMACKey
+	c.AuditQueueSize = *aQueueSize
+	c.AuditBatchSize = *aBatchSize
+	c.AuditFlushInterval = *aFlushInterval
+	c.AuditOverflow = *aOverflow
+	c.AuditMaxSize = *aMaxSize
+	c.AuditRotateInterval = *aRotateInterval
+	c.AuditMaxFiles = *aMaxFiles
+	c.AuditMaxAge = *aMaxAge
 	c.TrustedSubnet = *trustedSubnet
+	c.MetricsAddress = *metricsAddress
+	c.TracingExporter = *tracingExporter
+	c.TracingEndpoint = *tracingEndpoint
+	c.LogLevel = *logLevel
+	c.LogEncoding = *logEncoding
+	c.TrustedProxies = *trustedProxies
+	c.SessionKey = *sessionKey
+	c.RateLimitBackend = *rateLimitBackend
+	c.RateLimitCreate = *rateLimitCreate
+	c.RateLimitRedirect = *rateLimitRedirect
+	c.RateLimitAdmin = *rateLimitAdmin
+	c.QuotaDaily = *quotaDaily
+	c.QuotaTotal = *quotaTotal
+	c.URLAllowedSchemes = *urlAllowedSchemes
+	c.URLMaxLength = *urlMaxLength
+	c.BlocklistFile = *blocklistFile
+	c.DestinationPolicy = *destinationPolicy
+	c.CORSAllowedOrigins = *corsAllowedOrigins
+	c.HSTSMaxAge = *hstsMaxAge
 
 	// С bool сложнее: флаг всегда false по умолчанию.
 	// Проверяем, был ли он явно передан в командной строке.
 	if isFlagPassed("s") {
 		c.EnableHTTPS = *enableHTTPS
 	}
+	if isFlagPassed("url-strip-tracking") {
+		c.URLStripTracking = *urlStripTracking
+	}
+	if isFlagPassed("csrf") {
+		c.CSRFProtection = *csrfProtection
+	}
+	if isFlagPassed("audit-compress") {
+		c.AuditCompress = *aCompress
+	}
 
 	return c
 }
@@ -142,9 +427,102 @@ func parseEnv() Config {
 		ConfigPath:      os.Getenv("CONFIG"),
 		AuditFile:       os.Getenv("AUDIT_FILE"),
 		AuditURL:        os.Getenv("AUDIT_URL"),
+		AuditHMACKey:    os.Getenv("AUDIT_HMAC_KEY"),
 		EnableHTTPS:     os.Getenv("ENABLE_HTTPS") == "true",
 		TrustedSubnet:   os.Getenv("TRUSTED_SUBNET"),
+		MetricsAddress:  os.Getenv("METRICS_ADDRESS"),
+		TracingExporter: os.Getenv("TRACING_EXPORTER"),
+		TracingEndpoint: os.Getenv("TRACING_ENDPOINT"),
+		LogLevel:        os.Getenv("LOG_LEVEL"),
+		LogEncoding:     os.Getenv("LOG_ENCODING"),
+		TrustedProxies:  os.Getenv("TRUSTED_PROXIES"),
+		SessionKey:      os.Getenv("SESSION_KEY"),
+
+		RateLimitBackend:  os.Getenv("RATE_LIMIT_BACKEND"),
+		RateLimitCreate:   os.Getenv("RATE_LIMIT_CREATE"),
+		RateLimitRedirect: os.Getenv("RATE_LIMIT_REDIRECT"),
+		RateLimitAdmin:    os.Getenv("RATE_LIMIT_ADMIN"),
+
+		QuotaDaily: envInt("QUOTA_DAILY"),
+		QuotaTotal: envInt("QUOTA_TOTAL"),
+
+		URLAllowedSchemes: os.Getenv("URL_ALLOWED_SCHEMES"),
+		URLMaxLength:      envInt("URL_MAX_LENGTH"),
+		URLStripTracking:  os.Getenv("URL_STRIP_TRACKING") == "true",
+
+		BlocklistFile:     os.Getenv("BLOCKLIST_FILE"),
+		DestinationPolicy: os.Getenv("DESTINATION_POLICY"),
+
+		CORSAllowedOrigins: os.Getenv("CORS_ALLOWED_ORIGINS"),
+		CSRFProtection:     os.Getenv("CSRF_PROTECTION") == "true",
+		HSTSMaxAge:         envInt("HSTS_MAX_AGE"),
+
+		AuditQueueSize:     envInt("AUDIT_QUEUE_SIZE"),
+		AuditBatchSize:     envInt("AUDIT_BATCH_SIZE"),
+		AuditFlushInterval: os.Getenv("AUDIT_FLUSH_INTERVAL"),
+		AuditOverflow:      os.Getenv("AUDIT_OVERFLOW"),
+
+		AuditMaxSize:        envInt("AUDIT_MAX_SIZE"),
+		AuditRotateInterval: os.Getenv("AUDIT_ROTATE_INTERVAL"),
+		AuditCompress:       os.Getenv("AUDIT_COMPRESS") == "true",
+		AuditMaxFiles:       envInt("AUDIT_MAX_FILES"),
+		AuditMaxAge:         os.Getenv("AUDIT_MAX_AGE"),
+
+		AuditSinks: envAuditSinks(),
+	}
+}
+
+// envInt читает целое из переменной окружения; отсутствующее или некорректное значение дает 0.
+func envInt(name string) int {
+	n, _ := strconv.Atoi(os.Getenv(name))
+
+	return n
+}
+
+// envAuditSinks читает список приемников аудита из AUDIT_SINKS в формате JSON.
+func envAuditSinks() []AuditSink {
+	value := os.Getenv("AUDIT_SINKS")
+
+	if value == "" {
+		return nil
 	}
+
+	var sinks []AuditSink
+
+	if err := json.Unmarshal([]byte(value), &sinks); err != nil {
+		logger.Log.Error("Некорректный список приемников аудита в AUDIT_SINKS", zap.Error(err))
+		return nil
+	}
+
+	return sinks
+}
+
+// parseDuration разбирает длительность параметра name; пустое или некорректное значение дает 0.
+func parseDuration(name, value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	d, err := time.ParseDuration(value)
+
+	if err != nil {
+		logger.Log.Error("Некорректная длительность, используется значение по умолчанию", zap.String("param", name), zap.Error(err))
+	}
+
+	return d
+}
+
+// splitList разбирает список через запятую, отбрасывая пустые элементы.
+func splitList(value string) []string {
+	var items []string
+
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
 }
 
 func parseJSON(path string) Config {
@@ -4,12 +4,23 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/tracing"
+
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 func Connect(databaseDSN string) (*pgxpool.Pool, error) {
 	ctx := context.Background()
-	pool, err := pgxpool.New(ctx, databaseDSN)
+	cfg, err := pgxpool.ParseConfig(databaseDSN)
+
+	if err != nil {
+		return nil, fmt.Errorf("ошибка разбора реквизитов базы данных: %w", err)
+	}
+
+	// Каждый запрос к базе становится дочерним спаном трассы запроса.
+	cfg.ConnConfig.Tracer = tracing.QueryTracer{}
+
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
 
 	if err != nil {
 		return nil, fmt.Errorf("ошибка подключения к базе данных: %w", err)
@@ -0,0 +1,262 @@
+// Package destpolicy запрещает сокращать адреса, ведущие во внутреннюю сеть сервиса
+// или обратно на сам сервис.
+//
+// Хост адреса разрешается в IP-адреса, и ссылка отклоняется, если хотя бы один из них
+// попадает в запрещенную категорию. Резолвер подменяется, поэтому политику можно проверять без сети.
+package destpolicy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/urlnorm"
+)
+
+// Категории запрещенных адресов назначения.
+const (
+	// Loopback — 127.0.0.0/8, ::1, а также 0.0.0.0 и ::, которые ведут на локальный хост.
+	Loopback = "loopback"
+	// Private — RFC 1918, RFC 6598 (CGNAT) и уникальные локальные адреса IPv6 fc00::/7.
+	Private = "private"
+	// LinkLocal — 169.254.0.0/16 (в том числе метаданные облаков) и fe80::/10.
+	LinkLocal = "link-local"
+	// Self — хосты самого сервиса, чтобы короткая ссылка не вела на другую короткую ссылку.
+	Self = "self"
+	// All — все категории сразу.
+	All = "all"
+)
+
+// DefaultTimeout — сколько ждать ответа резолвера на один хост.
+const DefaultTimeout = 2 * time.Second
+
+// ErrForbidden — адрес назначения запрещен политикой. Оборачивает urlnorm.ErrInvalidURL,
+// поэтому обрабатывается так же, как некорректный адрес.
+var ErrForbidden = fmt.Errorf("%w: адрес назначения запрещен", urlnorm.ErrInvalidURL)
+
+// Categories — все поддерживаемые категории.
+var Categories = []string{Loopback, Private, LinkLocal, Self}
+
+// Internal — категории внутренней сети, без Self.
+var Internal = []string{Loopback, Private, LinkLocal}
+
+// internal запрещает внутреннюю сеть для DenyInternal.
+var internal = &Policy{deny: map[string]bool{Loopback: true, Private: true, LinkLocal: true}}
+
+// sharedAddressSpace — диапазон RFC 6598, который net.IP.IsPrivate не учитывает.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// Resolver разрешает имя хоста в IP-адреса; *net.Resolver ему соответствует.
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+type Policy struct {
+	resolver Resolver
+	timeout  time.Duration
+	deny     map[string]bool
+	ownHosts []string
+}
+
+// New создает политику, запрещающую перечисленные категории. ownHosts — адреса или хосты
+// самого сервиса (обычно BaseURL), которые проверяются для категории Self.
+// Пустой список категорий дает nil: проверка отключена.
+func New(categories []string, ownHosts []string, resolver Resolver) (*Policy, error) {
+	if len(categories) == 0 {
+		return nil, nil
+	}
+
+	p := &Policy{resolver: resolver, timeout: DefaultTimeout, deny: make(map[string]bool)}
+
+	if p.resolver == nil {
+		p.resolver = net.DefaultResolver
+	}
+
+	for _, category := range categories {
+		switch category {
+		case All:
+			for _, c := range Categories {
+				p.deny[c] = true
+			}
+		case Loopback, Private, LinkLocal, Self:
+			p.deny[category] = true
+		default:
+			return nil, fmt.Errorf("неизвестная категория адресов %q", category)
+		}
+	}
+
+	for _, own := range ownHosts {
+		if host := hostOf(own); host != "" && !slices.Contains(p.ownHosts, host) {
+			p.ownHosts = append(p.ownHosts, host)
+		}
+	}
+
+	return p, nil
+}
+
+// hostOf выделяет хост из адреса вида http://host:port/path или host:port.
+func hostOf(raw string) string {
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+
+	u, err := url.Parse(raw)
+
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+}
+
+// Check проверяет нормализованный адрес. Если хост не удалось разрешить,
+// адрес тоже отклоняется: проверить его невозможно.
+func (p *Policy) Check(ctx context.Context, rawURL string) error {
+	if p == nil {
+		return nil
+	}
+
+	host := hostOf(rawURL)
+
+	if host == "" {
+		return fmt.Errorf("%w: не указан хост", ErrForbidden)
+	}
+
+	if p.deny[Self] && slices.Contains(p.ownHosts, host) {
+		return fmt.Errorf("%w: ссылка ведет на сам сервис", ErrForbidden)
+	}
+
+	ips, err := p.resolve(ctx, host)
+
+	if err != nil {
+		return fmt.Errorf("%w: не удалось разрешить хост %s", ErrForbidden, host)
+	}
+
+	// Разрешенный адрес в ошибку не попадает, чтобы не раскрывать клиенту устройство внутренней сети.
+	for _, ip := range ips {
+		if category := p.classify(ip); category != "" {
+			return fmt.Errorf("%w: %s ведет в запрещенный диапазон адресов (%s)", ErrForbidden, host, category)
+		}
+	}
+
+	return nil
+}
+
+func (p *Policy) resolve(ctx context.Context, host string) ([]netip.Addr, error) {
+	if ip, ok := parseIP(host); ok {
+		return []netip.Addr{ip}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	addrs, err := p.resolver.LookupIPAddr(ctx, host)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(addrs) == 0 {
+		return nil, errors.New("нет адресов")
+	}
+
+	ips := make([]netip.Addr, 0, len(addrs))
+
+	for _, addr := range addrs {
+		if ip, ok := netip.AddrFromSlice(addr.IP); ok {
+			ips = append(ips, ip.Unmap())
+		}
+	}
+
+	return ips, nil
+}
+
+// DenyInternal подходит для net.Dialer.Control: запрещает соединение с адресом внутренней сети.
+// Проверяется адрес, с которым действительно устанавливается соединение, поэтому смена ответа DNS
+// после проверки при создании (DNS rebinding) запрет не обходит.
+func DenyInternal(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+
+	if err != nil {
+		return fmt.Errorf("%w: некорректный адрес соединения", ErrForbidden)
+	}
+
+	if category := internal.classify(addrPort.Addr()); category != "" {
+		return fmt.Errorf("%w: соединение с запрещенным диапазоном адресов (%s)", ErrForbidden, category)
+	}
+
+	return nil
+}
+
+// classify возвращает запрещенную категорию адреса или пустую строку.
+func (p *Policy) classify(ip netip.Addr) string {
+	ip = ip.Unmap()
+
+	switch {
+	case p.deny[Loopback] && (ip.IsLoopback() || ip.IsUnspecified()):
+		return Loopback
+	case p.deny[LinkLocal] && (ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast()):
+		return LinkLocal
+	case p.deny[Private] && (ip.IsPrivate() || sharedAddressSpace.Contains(ip)):
+		return Private
+	case p.deny[Self] && slices.Contains(p.ownHosts, ip.String()):
+		return Self
+	}
+
+	return ""
+}
+
+// parseIP разбирает IP-адрес, в том числе устаревшие формы IPv4, которые понимают браузеры:
+// 2130706433, 0x7f000001, 0177.0.0.1, 127.1.
+func parseIP(host string) (netip.Addr, bool) {
+	if ip, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil {
+		return ip.Unmap(), true
+	}
+
+	parts := strings.Split(host, ".")
+
+	if len(parts) > 4 {
+		return netip.Addr{}, false
+	}
+
+	values := make([]uint64, len(parts))
+
+	for i, part := range parts {
+		v, err := strconv.ParseUint(part, 0, 32)
+
+		if err != nil {
+			return netip.Addr{}, false
+		}
+
+		values[i] = v
+	}
+
+	// Последняя часть заполняет все оставшиеся байты адреса.
+	var addr uint64
+
+	for i, v := range values[:len(values)-1] {
+		if v > 0xff {
+			return netip.Addr{}, false
+		}
+
+		addr |= v << (8 * (3 - i))
+	}
+
+	last := values[len(values)-1]
+
+	if last >= 1<<(8*(5-len(values))) {
+		return netip.Addr{}, false
+	}
+
+	addr |= last
+
+	return netip.AddrFrom4([4]byte{byte(addr >> 24), byte(addr >> 16), byte(addr >> 8), byte(addr)}), true
+}
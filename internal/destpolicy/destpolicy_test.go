@@ -0,0 +1,104 @@
+package destpolicy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/urlnorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticResolver разрешает имена по таблице, без обращения к сети.
+type staticResolver map[string][]string
+
+func (s staticResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	ips, ok := s[host]
+
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	addrs := make([]net.IPAddr, 0, len(ips))
+
+	for _, ip := range ips {
+		addrs = append(addrs, net.IPAddr{IP: net.ParseIP(ip)})
+	}
+
+	return addrs, nil
+}
+
+func TestCheck(t *testing.T) {
+	resolver := staticResolver{
+		"practicum.yandex.ru": {"77.88.55.88"},
+		"localhost":           {"127.0.0.1", "::1"},
+		"intranet.example":    {"10.1.2.3"},
+		"mixed.example":       {"93.184.216.34", "192.168.0.10"},
+		"metadata.example":    {"169.254.169.254"},
+		"cgnat.example":       {"100.64.0.1"},
+		"ula.example":         {"fd00::1"},
+		"short.example":       {"93.184.216.35"},
+	}
+
+	p, err := New([]string{All}, []string{"http://short.example:8080/", "localhost:8080"}, resolver)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		url     string
+		allowed bool
+	}{
+		{name: "публичный адрес", url: "https://practicum.yandex.ru/", allowed: true},
+		{name: "localhost", url: "http://localhost:6060/debug/pprof"},
+		{name: "loopback IPv4", url: "http://127.0.0.1/"},
+		{name: "loopback IPv6", url: "http://[::1]/"},
+		{name: "IPv4 в IPv6", url: "http://[::ffff:127.0.0.1]/"},
+		{name: "десятичная запись", url: "http://2130706433/"},
+		{name: "шестнадцатеричная запись", url: "http://0x7f.1/"},
+		{name: "восьмеричная запись", url: "http://0177.0.0.1/"},
+		{name: "0.0.0.0", url: "http://0.0.0.0:8080/"},
+		{name: "RFC 1918", url: "http://192.168.1.1/admin"},
+		{name: "частный адрес через DNS", url: "https://intranet.example/"},
+		{name: "хотя бы один частный адрес", url: "https://mixed.example/"},
+		{name: "метаданные облака", url: "http://metadata.example/latest/meta-data"},
+		{name: "CGNAT", url: "http://cgnat.example/"},
+		{name: "ULA IPv6", url: "http://ula.example/"},
+		{name: "link-local IPv6", url: "http://[fe80::1]/"},
+		{name: "сам сервис", url: "https://short.example/abc"},
+		{name: "неразрешимый хост", url: "https://unknown.example/"},
+		{name: "публичный IP", url: "http://8.8.8.8/", allowed: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := p.Check(context.Background(), test.url)
+
+			if test.allowed {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.True(t, errors.Is(err, ErrForbidden), "Ожидается запрет адреса, получено %v", err)
+			assert.True(t, errors.Is(err, urlnorm.ErrInvalidURL), "Запрет должен обрабатываться как некорректный адрес")
+		})
+	}
+}
+
+func TestCategories(t *testing.T) {
+	resolver := staticResolver{"intranet.example": {"10.1.2.3"}}
+
+	p, err := New([]string{Loopback}, nil, resolver)
+	require.NoError(t, err)
+
+	assert.NoError(t, p.Check(context.Background(), "http://intranet.example/"), "Частные адреса не запрещены настройкой")
+	assert.Error(t, p.Check(context.Background(), "http://127.0.0.1/"))
+
+	p, err = New(nil, nil, resolver)
+	require.NoError(t, err)
+	assert.NoError(t, p.Check(context.Background(), "http://127.0.0.1/"), "Без категорий проверка отключена")
+
+	_, err = New([]string{"public"}, nil, resolver)
+	assert.Error(t, err, "Неизвестная категория должна отклоняться")
+}
@@ -0,0 +1,170 @@
+package facade
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/audit"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/storage"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/webhook"
+)
+
+// Link — ссылка с владельцем и состоянием, как ее видит оператор.
+type Link struct {
+	ShortCode   string `json:"short_code"`
+	ShortURL    string `json:"short_url"`
+	OriginalURL string `json:"original_url"`
+	UserID      string `json:"user_id"`
+	Deleted     bool   `json:"deleted"`
+	Blocked     bool   `json:"blocked"`
+}
+
+// User — пользователь и все его ссылки, включая удаленные и заблокированные.
+type User struct {
+	ID       string `json:"id"`
+	Disabled bool   `json:"disabled"`
+	Links    []Link `json:"links"`
+}
+
+// AdminLinkFacade возвращает ссылку по коду независимо от владельца и состояния.
+func (f *Facade) AdminLinkFacade(ctx context.Context, shortURL string) (link Link, err error) {
+	details, found := f.Store.Get(ctx, shortURL)
+
+	defer func() {
+		f.audit(ctx, audit.ActionViewLink, details.UserID, shortURL, details.OriginalURL, err)
+	}()
+
+	if !found {
+		return Link{}, ErrNotFound
+	}
+
+	return f.link(details)
+}
+
+// AdminDeleteLinkFacade удаляет ссылку независимо от владельца. Если ссылка была активна,
+// владелец получает событие вебхука deleted, а квота за нее возвращается.
+func (f *Facade) AdminDeleteLinkFacade(ctx context.Context, shortURL string) (link Link, err error) {
+	details, found := f.Store.Get(ctx, shortURL)
+
+	defer func() {
+		f.audit(ctx, audit.ActionForceDelete, details.UserID, shortURL, details.OriginalURL, err)
+	}()
+
+	if !found {
+		return Link{}, ErrNotFound
+	}
+
+	if err := f.Store.ForceDelete(ctx, shortURL); err != nil {
+		return Link{}, err
+	}
+
+	if !details.IsDeleted {
+		f.webhook(webhook.EventDeleted, details.UserID, shortURL, details.OriginalURL)
+		f.releaseQuota(ctx, details.UserID, 1)
+	}
+
+	details.IsDeleted = true
+
+	return f.link(details)
+}
+
+// AdminBlockLinkFacade блокирует ссылку или снимает блокировку. Заблокированная ссылка
+// остается у владельца, но переход по ней запрещен.
+func (f *Facade) AdminBlockLinkFacade(ctx context.Context, shortURL string, blocked bool) (link Link, err error) {
+	details, found := f.Store.Get(ctx, shortURL)
+
+	defer func() {
+		action := audit.ActionBlock
+
+		if !blocked {
+			action = audit.ActionUnblock
+		}
+
+		f.audit(ctx, action, details.UserID, shortURL, details.OriginalURL, err)
+	}()
+
+	if !found {
+		return Link{}, ErrNotFound
+	}
+
+	if err := f.Store.SetBlocked(ctx, shortURL, blocked); err != nil {
+		return Link{}, err
+	}
+
+	details.IsBlocked = blocked
+
+	return f.link(details)
+}
+
+// AdminUserFacade возвращает пользователя со всеми его ссылками.
+func (f *Facade) AdminUserFacade(ctx context.Context, userID string) (user User, err error) {
+	defer func() {
+		f.audit(ctx, audit.ActionViewUser, userID, "", "", err)
+	}()
+
+	return f.adminUser(ctx, userID)
+}
+
+// adminUser собирает пользователя со ссылками без записи в аудит: ее делает вызывающее действие.
+func (f *Facade) adminUser(ctx context.Context, userID string) (User, error) {
+	user := User{
+		ID:       userID,
+		Disabled: f.Store.UserDisabled(ctx, userID),
+		Links:    []Link{},
+	}
+
+	for _, details := range f.Store.LinksByUserID(ctx, userID) {
+		link, err := f.link(details)
+
+		if err != nil {
+			return User{}, err
+		}
+
+		user.Links = append(user.Links, link)
+	}
+
+	return user, nil
+}
+
+// AdminDisableUserFacade отключает пользователя или включает его снова. Отключенный пользователь
+// не может действовать от своего имени, а переходы по его ссылкам запрещены.
+func (f *Facade) AdminDisableUserFacade(ctx context.Context, userID string, disabled bool) (user User, err error) {
+	defer func() {
+		action := audit.ActionDisableUser
+
+		if !disabled {
+			action = audit.ActionEnableUser
+		}
+
+		f.audit(ctx, action, userID, "", "", err)
+	}()
+
+	if err := f.Store.SetUserDisabled(ctx, userID, disabled); err != nil {
+		return User{}, err
+	}
+
+	return f.adminUser(ctx, userID)
+}
+
+// AuditOperatorRead записывает в аудит чтение оператора, которое идет в обход фасада:
+// состояние сервиса или журнал аудита. userID и shortCode — условия запроса, если они заданы.
+func (f *Facade) AuditOperatorRead(ctx context.Context, action, userID, shortCode string, err error) {
+	f.audit(ctx, action, userID, shortCode, "", err)
+}
+
+func (f *Facade) link(details storage.URLDetails) (Link, error) {
+	shortURL, err := url.JoinPath(f.BaseURL, details.ShortURL)
+
+	if err != nil {
+		return Link{}, err
+	}
+
+	return Link{
+		ShortCode:   details.ShortURL,
+		ShortURL:    shortURL,
+		OriginalURL: details.OriginalURL,
+		UserID:      details.UserID,
+		Deleted:     details.IsDeleted,
+		Blocked:     details.IsBlocked,
+	}, nil
+}
@@ -0,0 +1,49 @@
+package facade
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/blocklist"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/quota"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/storage"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/urlnorm"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Ошибки предметной области. Транспортные слои (HTTP, gRPC) сопоставляют им коды ответа;
+// остальные ошибки фасада считаются внутренними и клиенту не раскрываются.
+var (
+	// ErrInvalidURL — адрес не прошел проверку или запрещен политикой адресов назначения.
+	ErrInvalidURL = urlnorm.ErrInvalidURL
+	// ErrNotFound — короткой ссылки не существует.
+	ErrNotFound = errors.New("short URL not found")
+	// ErrConflict — ссылка на этот адрес уже создана; вместе с ошибкой возвращается существующая ссылка.
+	ErrConflict = errors.New("ссылка уже существует")
+	// ErrLinkBlocked — оператор заблокировал ссылку или отключил ее владельца.
+	ErrLinkBlocked = errors.New("ссылка заблокирована администратором")
+	// ErrUserDisabled — оператор отключил пользователя; действовать от его имени нельзя.
+	ErrUserDisabled = errors.New("пользователь отключен администратором")
+	// ErrDeleted — владелец или оператор удалил ссылку; переход по ней невозможен.
+	ErrDeleted = errors.New("short URL deleted")
+)
+
+// BlockedError — адрес попадает под правило списка блокировок.
+type BlockedError = blocklist.BlockedError
+
+// QuotaExceededError — создание ссылок превысит квоту пользователя.
+type QuotaExceededError = quota.ExceededError
+
+// conflictError помечает уже существующую ссылку и нарушение уникальности в базе как ErrConflict,
+// сохраняя исходную ошибку.
+func conflictError(err error) error {
+	var pgErr *pgconn.PgError
+
+	if errors.Is(err, storage.ErrExists) || (errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation) {
+		return fmt.Errorf("%w: %w", ErrConflict, err)
+	}
+
+	return err
+}
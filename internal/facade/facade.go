@@ -2,17 +2,48 @@ package facade
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
+	"time"
 
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/audit"
 	"github.com/flash1nho/go-musthave-shortener-tpl/internal/authenticator"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/blocklist"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/destpolicy"
 	"github.com/flash1nho/go-musthave-shortener-tpl/internal/helpers"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/quota"
 	"github.com/flash1nho/go-musthave-shortener-tpl/internal/storage"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/urlnorm"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/webhook"
+)
+
+// Окно статистики по умолчанию и длина списков самых популярных ссылок и доменов.
+const (
+	DefaultStatsWindow = 24 * time.Hour
+	DefaultStatsTop    = 10
 )
 
 type Facade struct {
 	Store   *storage.Storage
 	BaseURL string
+	// Quota ограничивает создание ссылок пользователем; nil — без квот.
+	Quota *quota.Manager
+	// Normalizer проверяет и приводит к каноническому виду каждый сокращаемый адрес.
+	Normalizer *urlnorm.Normalizer
+	// Blocklist запрещает сокращать адреса и переходить по ссылкам из списка; nil — без проверки.
+	Blocklist *blocklist.List
+	// Destinations запрещает адреса во внутренней сети и на самом сервисе; nil — без проверки.
+	Destinations *destpolicy.Policy
+	// Audit получает событие о каждом действии над ссылками, в том числе неудачном; nil — без аудита.
+	Audit audit.Notifier
+	// AuditLog читает сохраненные события аудита; nil — ни один приемник не поддерживает чтение.
+	AuditLog audit.Querier
+	// AuditLogFilter — фильтр приемника, из которого читается AuditLog. Непустой фильтр означает,
+	// что в журнал попадают не все события.
+	AuditLogFilter audit.Filter
+	// Webhooks уведомляет владельцев о событиях их ссылок; nil — вебхуки отключены.
+	Webhooks *webhook.Manager
 }
 
 type BatchUserShortenResponse struct {
@@ -20,40 +51,247 @@ type BatchUserShortenResponse struct {
 	OriginalURL string `json:"original_url"`
 }
 
+type BatchShortenItem struct {
+	CorrelationID string
+	OriginalURL   string
+}
+
+type BatchShortenResult struct {
+	CorrelationID string
+	ShortURL      string
+}
+
 func NewFacade(store *storage.Storage, BaseURL string) *Facade {
 	return &Facade{
-		Store:   store,
-		BaseURL: BaseURL,
+		Store:      store,
+		BaseURL:    BaseURL,
+		Normalizer: urlnorm.New(urlnorm.DefaultOptions()),
 	}
 }
 
-func (f *Facade) PostURLFacade(ctx context.Context, userID string, originalURL string) (string, error) {
-	shortURL := helpers.GenerateShortURL(originalURL)
-	err := f.Store.Set(ctx, shortURL, originalURL, userID)
+func (f *Facade) PostURLFacade(ctx context.Context, userID string, originalURL string) (result string, err error) {
+	var shortURL string
+
+	defer func() {
+		f.audit(ctx, audit.ActionShorten, userID, shortURL, originalURL, err)
+
+		if err == nil {
+			f.webhook(webhook.EventCreated, userID, shortURL, originalURL)
+			f.Store.RecordActivity(userID)
+		}
+	}()
+
+	normalized, err := f.Normalizer.Normalize(originalURL)
 
 	if err != nil {
 		return "", err
 	}
 
-	result, err := url.JoinPath(f.BaseURL, shortURL)
+	originalURL = normalized
+
+	if err := f.checkCreate(ctx, originalURL); err != nil {
+		return "", err
+	}
+
+	shortURL = helpers.GenerateShortURL(originalURL)
+	result, err = url.JoinPath(f.BaseURL, shortURL)
 
 	if err != nil {
 		return "", err
 	}
 
-	return result, nil
+	// Повторное сокращение существующей ссылки закончится конфликтом, поэтому квоту не расходует.
+	reserved := 0
+
+	if _, exists := f.Store.Get(ctx, shortURL); !exists {
+		if err := f.reserveQuota(ctx, userID, 1); err != nil {
+			return "", err
+		}
+
+		reserved = 1
+	}
+
+	// Сокращенная ссылка возвращается и при ошибке сохранения:
+	// при конфликте уникальности она уже существует и клиенту нужна.
+	err = f.Store.Set(ctx, shortURL, originalURL, userID)
+
+	if err != nil {
+		f.releaseQuota(ctx, userID, reserved)
+	}
+
+	return result, conflictError(err)
+}
+
+func (f *Facade) PostBatchURLFacade(ctx context.Context, userID string, items []BatchShortenItem) ([]BatchShortenResult, error) {
+	response := make([]BatchShortenResult, 0, len(items))
+	batch := make(map[string]string, len(items))
+
+	for _, item := range items {
+		originalURL, err := f.Normalizer.Normalize(item.OriginalURL)
+
+		if err == nil {
+			err = f.checkCreate(ctx, originalURL)
+		}
+
+		if err != nil {
+			err = fmt.Errorf("correlation_id %q: %w", item.CorrelationID, err)
+			f.audit(ctx, audit.ActionShorten, userID, "", item.OriginalURL, err)
+
+			return nil, err
+		}
+
+		sURL := helpers.GenerateShortURL(originalURL)
+		shortURL, err := url.JoinPath(f.BaseURL, sURL)
+
+		if err != nil {
+			return nil, err
+		}
+
+		batch[sURL] = originalURL
+
+		response = append(response, BatchShortenResult{
+			CorrelationID: item.CorrelationID,
+			ShortURL:      shortURL,
+		})
+	}
+
+	// Пакет создается целиком или не создается вовсе, поэтому квота резервируется сразу на все новые ссылки.
+	reserved := f.newLinks(ctx, batch)
+	err := f.reserveQuota(ctx, userID, reserved)
+
+	if err == nil {
+		err = conflictError(f.Store.SetBatch(ctx, batch, userID))
+
+		if err != nil {
+			f.releaseQuota(ctx, userID, reserved)
+		}
+	}
+
+	for shortURL, originalURL := range batch {
+		f.audit(ctx, audit.ActionShorten, userID, shortURL, originalURL, err)
+
+		if err == nil {
+			f.webhook(webhook.EventCreated, userID, shortURL, originalURL)
+		}
+	}
+
+	if err == nil {
+		f.Store.RecordActivity(userID)
+	}
+
+	if err != nil {
+		// Как и для одиночной ссылки, при конфликте клиенту нужны уже существующие ссылки.
+		if errors.Is(err, ErrConflict) {
+			return response, err
+		}
+
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// QuotaFacade возвращает использование квоты пользователем.
+func (f *Facade) QuotaFacade(ctx context.Context, userID string) (quota.Usage, error) {
+	if f.Quota == nil {
+		return quota.Usage{}, nil
+	}
+
+	return f.Quota.Usage(ctx, userID)
+}
+
+func (f *Facade) reserveQuota(ctx context.Context, userID string, n int) error {
+	if f.Quota == nil || userID == "" {
+		return nil
+	}
+
+	_, err := f.Quota.Reserve(ctx, userID, n)
+
+	return err
+}
+
+// releaseQuota возвращает квоту за несозданные или удаленные ссылки.
+func (f *Facade) releaseQuota(ctx context.Context, userID string, n int) {
+	if f.Quota == nil || userID == "" || n == 0 {
+		return
+	}
+
+	f.Quota.Release(ctx, userID, n)
 }
 
-func (f *Facade) GetURLFacade(shortURL string) (storage.URLDetails, error) {
-	URLDetails, found := f.Store.Get(shortURL)
+// newLinks считает ссылки пакета, которых еще нет в хранилище: только они расходуют квоту.
+func (f *Facade) newLinks(ctx context.Context, batch map[string]string) int {
+	n := 0
+
+	for shortURL := range batch {
+		if _, exists := f.Store.Get(ctx, shortURL); !exists {
+			n++
+		}
+	}
+
+	return n
+}
+
+func (f *Facade) GetURLFacade(ctx context.Context, shortURL string) (URLDetails storage.URLDetails, err error) {
+	defer func() {
+		outcome := err
+
+		// Переход по ссылке с предупреждением разрешен: решение остается за пользователем.
+		if blocked, ok := blocklist.IsBlocked(err); ok && blocked.Rule.Action == blocklist.ActionWarn {
+			outcome = nil
+		}
+
+		// Переход доступен и отключенным пользователям, поэтому их идентификатор берется в обход проверки.
+		userID, _ := authenticator.FromContext(ctx)
+		f.audit(ctx, audit.ActionFollow, userID, shortURL, URLDetails.OriginalURL, outcome)
+
+		if outcome == nil {
+			f.webhook(webhook.EventClicked, URLDetails.UserID, shortURL, URLDetails.OriginalURL)
+			f.Store.RecordRedirect(shortURL, userID)
+		}
+	}()
+
+	URLDetails, found := f.Store.Get(ctx, shortURL)
 
 	if !found {
-		return URLDetails, fmt.Errorf("short URL not found")
+		return URLDetails, ErrNotFound
+	}
+
+	if URLDetails.IsDeleted {
+		return URLDetails, ErrDeleted
+	}
+
+	if URLDetails.IsBlocked || f.Store.UserDisabled(ctx, URLDetails.UserID) {
+		return URLDetails, ErrLinkBlocked
+	}
+
+	// Правила проверяются и при переходе, чтобы ссылки на недавно заблокированные ресурсы перестали работать.
+	// Для правил с предупреждением вместе с данными ссылки возвращается *blocklist.BlockedError.
+	if f.Blocklist != nil {
+		return URLDetails, f.Blocklist.Check(URLDetails.OriginalURL)
 	}
 
 	return URLDetails, nil
 }
 
+// checkCreate запрещает сокращать адреса под правилами блокировки и политикой адресов назначения.
+// Адреса под правилами предупреждения сокращаются: предупреждение показывается при переходе.
+func (f *Facade) checkCreate(ctx context.Context, originalURL string) error {
+	if f.Blocklist != nil {
+		err := f.Blocklist.Check(originalURL)
+
+		if blocked, ok := blocklist.IsBlocked(err); ok && blocked.Rule.Action == blocklist.ActionWarn {
+			err = nil
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return f.Destinations.Check(ctx, originalURL)
+}
+
 func (f *Facade) APIUserURLFacade(ctx context.Context, userID string) ([]BatchUserShortenResponse, error) {
 	var response []BatchUserShortenResponse
 
@@ -81,6 +319,95 @@ func (f *Facade) APIUserURLFacade(ctx context.Context, userID string) ([]BatchUs
 	return response, nil
 }
 
+func (f *Facade) DeleteUserURLFacade(ctx context.Context, userID string, shortURLs []string) error {
+	// Владельцу сообщается только о ссылках, которые удалил именно этот запрос.
+	active := make(map[string]storage.URLDetails, len(shortURLs))
+
+	for _, shortURL := range shortURLs {
+		if details, found := f.Store.Get(ctx, shortURL); found && details.UserID == userID && !details.IsDeleted {
+			active[shortURL] = details
+		}
+	}
+
+	err := f.Store.DeleteBatch(ctx, userID, shortURLs)
+
+	if err == nil {
+		f.Store.RecordActivity(userID)
+	}
+
+	deleted := 0
+
+	for _, shortURL := range shortURLs {
+		f.audit(ctx, audit.ActionDelete, userID, shortURL, "", err)
+
+		details, wasActive := active[shortURL]
+
+		if !wasActive {
+			continue
+		}
+
+		if now, _ := f.Store.Get(ctx, shortURL); now.IsDeleted {
+			f.webhook(webhook.EventDeleted, userID, shortURL, details.OriginalURL)
+			deleted++
+		}
+	}
+
+	// Удаленные ссылки возвращают квоту владельцу.
+	if err == nil {
+		f.releaseQuota(ctx, userID, deleted)
+	}
+
+	return err
+}
+
+// StatsFacade возвращает статистику за последние window с точностью до шага: часа для окон
+// до двух суток и суток для более длинных. Текущий неполный шаг входит в окно.
+func (f *Facade) StatsFacade(ctx context.Context, window time.Duration, top int) (*storage.Stats, error) {
+	bucket := time.Hour
+
+	if window > 48*time.Hour {
+		bucket = 24 * time.Hour
+	}
+
+	to := time.Now().UTC().Truncate(bucket).Add(bucket)
+	from := to.Add(-window).Truncate(bucket)
+
+	return f.Store.GetStats(ctx, storage.StatsQuery{From: from, To: to, Bucket: bucket, Top: top})
+}
+
+// TotalsFacade возвращает общее число ссылок и пользователей без статистики за окно.
+func (f *Facade) TotalsFacade(ctx context.Context) (urls, users int) {
+	return f.Store.GetTotals(ctx)
+}
+
+// audit отправляет событие о действии над ссылкой. Доставка идет после ответа клиенту,
+// поэтому отмена контекста запроса на нее не влияет.
+func (f *Facade) audit(ctx context.Context, action, userID, shortURL, originalURL string, err error) {
+	if f.Audit == nil {
+		return
+	}
+
+	f.Audit.NotifyAll(context.WithoutCancel(ctx), audit.NewEvent(ctx, action, userID, shortURL, originalURL, err, ErrConflict))
+}
+
+// webhook передает событие ссылки вебхукам ее владельца. Ссылки без владельца
+// (созданные до появления пользователей) не уведомляют никого.
+func (f *Facade) webhook(event, ownerID, shortURL, originalURL string) {
+	if f.Webhooks == nil || ownerID == "" {
+		return
+	}
+
+	link, err := url.JoinPath(f.BaseURL, shortURL)
+
+	if err != nil {
+		return
+	}
+
+	f.Webhooks.Notify(ownerID, event, webhook.Link{ShortCode: shortURL, ShortURL: link, OriginalURL: originalURL})
+}
+
+// GetUserFromContext возвращает пользователя запроса; пустую строку, если пользователя нет,
+// и ErrUserDisabled, если оператор его отключил.
 func (f *Facade) GetUserFromContext(ctx context.Context) (string, error) {
 	userID, err := authenticator.FromContext(ctx)
 
@@ -88,5 +415,9 @@ func (f *Facade) GetUserFromContext(ctx context.Context) (string, error) {
 		return "", nil
 	}
 
+	if f.Store.UserDisabled(ctx, userID) {
+		return "", ErrUserDisabled
+	}
+
 	return userID, nil
 }
@@ -0,0 +1,490 @@
+package facade
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/audit"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/authenticator"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/clientip"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/config"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/helpers"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/logger"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/quota"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/storage"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/webhook"
+)
+
+// recorder запоминает события аудита синхронно.
+type recorder struct {
+	mu     sync.Mutex
+	events []audit.Event
+}
+
+func (r *recorder) NotifyAll(_ context.Context, e audit.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, e)
+}
+
+func TestAuditEvents(t *testing.T) {
+	const (
+		userID      = "user-1"
+		originalURL = "https://practicum.yandex.ru"
+	)
+
+	shortCode := helpers.GenerateShortURL(originalURL)
+
+	ctx := context.WithValue(context.Background(), authenticator.GetUserKey(), userID)
+	ctx = logger.ContextWithRequestID(ctx, "req-1")
+	ctx = clientip.WithIP(ctx, net.ParseIP("203.0.113.7"))
+
+	tests := []struct {
+		name string
+		call func(f *Facade)
+		want []audit.Event
+	}{
+		{
+			name: "сокращение",
+			call: func(f *Facade) { f.PostURLFacade(ctx, userID, originalURL) },
+			want: []audit.Event{{Action: audit.ActionShorten, Status: audit.StatusSuccess, ShortCode: shortCode, URL: originalURL}},
+		},
+		{
+			name: "некорректный адрес",
+			call: func(f *Facade) { f.PostURLFacade(ctx, userID, "ftp://example.com") },
+			want: []audit.Event{{Action: audit.ActionShorten, Status: audit.StatusFailure, URL: "ftp://example.com"}},
+		},
+		{
+			name: "пакет",
+			call: func(f *Facade) {
+				f.PostBatchURLFacade(ctx, userID, []BatchShortenItem{{CorrelationID: "1", OriginalURL: originalURL}})
+			},
+			want: []audit.Event{{Action: audit.ActionShorten, Status: audit.StatusSuccess, ShortCode: shortCode, URL: originalURL}},
+		},
+		{
+			name: "переход",
+			call: func(f *Facade) {
+				f.Store.Set(ctx, shortCode, originalURL, "owner")
+				f.GetURLFacade(ctx, shortCode)
+			},
+			want: []audit.Event{{Action: audit.ActionFollow, Status: audit.StatusSuccess, ShortCode: shortCode, URL: originalURL}},
+		},
+		{
+			name: "переход по несуществующей ссылке",
+			call: func(f *Facade) { f.GetURLFacade(ctx, "missing") },
+			want: []audit.Event{{Action: audit.ActionFollow, Status: audit.StatusFailure, ShortCode: "missing"}},
+		},
+		{
+			name: "переход по удаленной ссылке",
+			call: func(f *Facade) {
+				f.Store.Set(ctx, shortCode, originalURL, userID)
+				f.Store.DeleteBatch(ctx, userID, []string{shortCode})
+				f.GetURLFacade(ctx, shortCode)
+			},
+			want: []audit.Event{{Action: audit.ActionFollow, Status: audit.StatusFailure, ShortCode: shortCode, URL: originalURL}},
+		},
+		{
+			name: "удаление",
+			call: func(f *Facade) { f.DeleteUserURLFacade(ctx, userID, []string{"a", "b"}) },
+			want: []audit.Event{
+				{Action: audit.ActionDelete, Status: audit.StatusSuccess, ShortCode: "a"},
+				{Action: audit.ActionDelete, Status: audit.StatusSuccess, ShortCode: "b"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store, err := storage.NewStorage("", "")
+			require.NoError(t, err)
+
+			events := &recorder{}
+			f := NewFacade(store, config.DefaultURL)
+			f.Audit = events
+
+			test.call(f)
+
+			require.Len(t, events.events, len(test.want), "Число событий аудита не совпадает с ожидаемым")
+
+			for i, want := range test.want {
+				got := events.events[i]
+
+				assert.NotZero(t, got.Timestamp)
+				assert.Equal(t, userID, got.UserID, "Пользователь в событии не совпадает с ожидаемым")
+				assert.Equal(t, "203.0.113.7", got.ClientIP, "Адрес клиента в событии не совпадает с ожидаемым")
+				assert.Equal(t, "req-1", got.RequestID, "Идентификатор запроса в событии не совпадает с ожидаемым")
+				assert.Equal(t, want.Status == audit.StatusFailure, got.Error != "", "Причина неудачи должна быть только у неудачных действий")
+
+				got.Timestamp, got.UserID, got.ClientIP, got.RequestID, got.Error = 0, "", "", "", ""
+
+				assert.Equal(t, want, got, "Событие аудита не совпадает с ожидаемым")
+			}
+		})
+	}
+}
+
+func TestWebhookEvents(t *testing.T) {
+	const (
+		owner       = "owner"
+		originalURL = "https://practicum.yandex.ru"
+	)
+
+	var (
+		mu     sync.Mutex
+		events []string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		events = append(events, r.Header.Get(webhook.EventHeader))
+	}))
+	defer server.Close()
+
+	store, err := storage.NewStorage("", "")
+	require.NoError(t, err)
+
+	f := NewFacade(store, config.DefaultURL)
+	f.Webhooks = webhook.NewManager(webhook.NewMemoryStore(), webhook.Options{PollInterval: 5 * time.Millisecond, AllowInternal: true}, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		f.Webhooks.Run(ctx)
+		close(done)
+	}()
+
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	_, err = f.Webhooks.Create(ctx, owner, server.URL, webhook.Events)
+	require.NoError(t, err)
+
+	_, err = f.PostURLFacade(ctx, owner, originalURL)
+	require.NoError(t, err)
+
+	shortCode := helpers.GenerateShortURL(originalURL)
+	visitor := context.WithValue(ctx, authenticator.GetUserKey(), "visitor")
+
+	_, err = f.GetURLFacade(visitor, shortCode)
+	require.NoError(t, err)
+
+	// Повторное удаление и удаление чужим пользователем владельцу не сообщаются.
+	require.NoError(t, f.DeleteUserURLFacade(ctx, "visitor", []string{shortCode}))
+	require.NoError(t, f.DeleteUserURLFacade(ctx, owner, []string{shortCode}))
+	require.NoError(t, f.DeleteUserURLFacade(ctx, owner, []string{shortCode}))
+
+	// Переход по удаленной ссылке — неудачный и тоже не сообщается.
+	_, err = f.GetURLFacade(visitor, shortCode)
+	assert.ErrorIs(t, err, ErrDeleted, "Ожидается ошибка перехода по удаленной ссылке")
+
+	want := []string{webhook.EventCreated, webhook.EventClicked, webhook.EventDeleted}
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return len(events) == len(want)
+	}, time.Second, 5*time.Millisecond, "Не все события доставлены")
+
+	time.Sleep(20 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.ElementsMatch(t, want, events, "События владельца ссылки не совпадают с ожидаемыми")
+}
+
+func TestAdmin(t *testing.T) {
+	const (
+		owner       = "owner"
+		originalURL = "https://practicum.yandex.ru"
+	)
+
+	shortCode := helpers.GenerateShortURL(originalURL)
+	path := filepath.Join(t.TempDir(), "urls.json")
+
+	store, err := storage.NewStorage(path, "")
+	require.NoError(t, err)
+
+	events := &recorder{}
+	f := NewFacade(store, config.DefaultURL)
+	f.Audit = events
+
+	ctx := context.Background()
+	ownerCtx := context.WithValue(ctx, authenticator.GetUserKey(), owner)
+
+	_, err = f.PostURLFacade(ctx, owner, originalURL)
+	require.NoError(t, err)
+	require.NoError(t, f.Store.Set(ctx, "other", "https://example.com", "someone"))
+
+	_, err = f.AdminLinkFacade(ctx, "missing")
+	assert.ErrorIs(t, err, ErrNotFound, "Ожидается ошибка поиска отсутствующей ссылки")
+
+	link, err := f.AdminBlockLinkFacade(ctx, shortCode, true)
+	require.NoError(t, err)
+	assert.True(t, link.Blocked, "Ссылка должна быть заблокирована")
+	assert.Equal(t, owner, link.UserID, "Владелец ссылки не совпадает с ожидаемым")
+
+	_, err = f.GetURLFacade(ctx, shortCode)
+	assert.ErrorIs(t, err, ErrLinkBlocked, "Переход по заблокированной ссылке должен быть запрещен")
+
+	// Повторное сокращение того же адреса не снимает блокировку и не меняет владельца.
+	_, err = f.PostURLFacade(ctx, "intruder", originalURL)
+	assert.ErrorIs(t, err, ErrConflict, "Ожидается конфликт при повторном сокращении")
+
+	_, err = f.PostBatchURLFacade(ctx, "intruder", []BatchShortenItem{{CorrelationID: "1", OriginalURL: originalURL}})
+	assert.ErrorIs(t, err, ErrConflict, "Ожидается конфликт при повторном сокращении пакетом")
+
+	link, err = f.AdminLinkFacade(ctx, shortCode)
+	require.NoError(t, err)
+	assert.True(t, link.Blocked, "Повторное сокращение не должно снимать блокировку")
+	assert.Equal(t, owner, link.UserID, "Повторное сокращение не должно менять владельца")
+
+	_, err = f.GetURLFacade(ctx, shortCode)
+	assert.ErrorIs(t, err, ErrLinkBlocked, "Переход по заблокированной ссылке должен быть запрещен")
+
+	_, err = f.AdminBlockLinkFacade(ctx, shortCode, false)
+	require.NoError(t, err)
+
+	_, err = f.GetURLFacade(ctx, shortCode)
+	assert.NoError(t, err, "После снятия блокировки переход должен быть разрешен")
+
+	user, err := f.AdminDisableUserFacade(ctx, owner, true)
+	require.NoError(t, err)
+	assert.True(t, user.Disabled, "Пользователь должен быть отключен")
+	require.Len(t, user.Links, 1, "Число ссылок пользователя не совпадает с ожидаемым")
+	assert.Equal(t, shortCode, user.Links[0].ShortCode)
+
+	_, err = f.GetUserFromContext(ownerCtx)
+	assert.ErrorIs(t, err, ErrUserDisabled, "Отключенный пользователь не должен действовать от своего имени")
+
+	_, err = f.GetURLFacade(ctx, shortCode)
+	assert.ErrorIs(t, err, ErrLinkBlocked, "Переход по ссылкам отключенного пользователя должен быть запрещен")
+
+	_, err = f.AdminDisableUserFacade(ctx, owner, false)
+	require.NoError(t, err)
+
+	userID, err := f.GetUserFromContext(ownerCtx)
+	require.NoError(t, err)
+	assert.Equal(t, owner, userID)
+
+	user, err = f.AdminUserFacade(ctx, owner)
+	require.NoError(t, err)
+	assert.False(t, user.Disabled, "Пользователь должен быть включен")
+
+	link, err = f.AdminDeleteLinkFacade(ctx, shortCode)
+	require.NoError(t, err)
+	assert.True(t, link.Deleted, "Ссылка должна быть удалена")
+
+	_, err = f.AdminBlockLinkFacade(ctx, "other", true)
+	require.NoError(t, err)
+
+	stats, err := f.StatsFacade(ctx, DefaultStatsWindow, DefaultStatsTop)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.URLs, "Число ссылок без базы не совпадает с ожидаемым")
+	assert.Equal(t, 2, stats.Users, "Число пользователей без базы не совпадает с ожидаемым")
+
+	var actions []string
+
+	for _, e := range events.events[1:] {
+		actions = append(actions, e.Action)
+	}
+
+	// Чтения оператора тоже попадают в аудит, а отключение пользователя не порождает лишнего чтения.
+	assert.Equal(t, []string{
+		audit.ActionViewLink, audit.ActionBlock, audit.ActionFollow, audit.ActionShorten, audit.ActionShorten, audit.ActionViewLink, audit.ActionFollow,
+		audit.ActionUnblock, audit.ActionFollow, audit.ActionDisableUser, audit.ActionFollow, audit.ActionEnableUser, audit.ActionViewUser,
+		audit.ActionForceDelete, audit.ActionBlock,
+	}, actions, "Действия оператора должны попадать в аудит")
+	assert.Equal(t, audit.StatusFailure, events.events[1].Status, "Чтение отсутствующей ссылки должно быть неудачным")
+
+	_, err = f.AdminDisableUserFacade(ctx, "someone", true)
+	require.NoError(t, err)
+
+	// Удаление, блокировка и отключение пользователя сохраняются в файле вместе с владельцем ссылки.
+	require.NoError(t, store.Close())
+
+	reopened, err := storage.NewStorage(path, "")
+	require.NoError(t, err)
+
+	f = NewFacade(reopened, config.DefaultURL)
+
+	link, err = f.AdminLinkFacade(ctx, shortCode)
+	require.NoError(t, err)
+	assert.Equal(t, Link{ShortCode: shortCode, ShortURL: config.DefaultURL + "/" + shortCode, OriginalURL: originalURL, UserID: owner, Deleted: true}, link)
+
+	link, err = f.AdminLinkFacade(ctx, "other")
+	require.NoError(t, err)
+	assert.True(t, link.Blocked, "Блокировка должна сохраняться в файле")
+
+	user, err = f.AdminUserFacade(ctx, "someone")
+	require.NoError(t, err)
+	assert.True(t, user.Disabled, "Отключение пользователя должно сохраняться в файле")
+
+	user, err = f.AdminUserFacade(ctx, owner)
+	require.NoError(t, err)
+	assert.False(t, user.Disabled, "Включенный пользователь не должен оставаться в файле")
+}
+
+func TestQuota(t *testing.T) {
+	const owner = "owner"
+
+	store, err := storage.NewStorage("", "")
+	require.NoError(t, err)
+
+	f := NewFacade(store, config.DefaultURL)
+	f.Quota = quota.NewManager(quota.NewMemoryStore(), quota.Limits{Total: 2})
+
+	ctx := context.Background()
+
+	usage := func() int {
+		t.Helper()
+
+		u, err := f.QuotaFacade(ctx, owner)
+		require.NoError(t, err)
+
+		return u.Total
+	}
+
+	_, err = f.PostURLFacade(ctx, owner, "https://practicum.yandex.ru")
+	require.NoError(t, err)
+
+	_, err = f.PostURLFacade(ctx, owner, "https://practicum.yandex.ru")
+	assert.ErrorIs(t, err, ErrConflict, "Ожидается конфликт при повторном сокращении")
+	assert.Equal(t, 1, usage(), "Повторное сокращение не должно расходовать квоту")
+
+	_, err = f.PostBatchURLFacade(ctx, owner, []BatchShortenItem{
+		{CorrelationID: "1", OriginalURL: "https://practicum.yandex.ru"},
+		{CorrelationID: "2", OriginalURL: "https://ya.ru"},
+	})
+	assert.ErrorIs(t, err, ErrConflict, "Пакет с существующей ссылкой не должен создаваться")
+	assert.Equal(t, 1, usage(), "Несозданный пакет не должен расходовать квоту")
+
+	_, err = f.PostBatchURLFacade(ctx, owner, []BatchShortenItem{{CorrelationID: "1", OriginalURL: "https://ya.ru"}})
+	require.NoError(t, err)
+	assert.Equal(t, 2, usage(), "Число использованных ссылок не совпадает с ожидаемым")
+
+	require.NoError(t, f.DeleteUserURLFacade(ctx, owner, []string{helpers.GenerateShortURL("https://ya.ru"), "missing"}))
+	assert.Equal(t, 1, usage(), "Удаленная ссылка должна возвращать квоту")
+
+	_, err = f.AdminDeleteLinkFacade(ctx, helpers.GenerateShortURL("https://practicum.yandex.ru"))
+	require.NoError(t, err)
+	assert.Equal(t, 0, usage(), "Удаленная оператором ссылка должна возвращать квоту")
+
+	_, err = f.AdminDeleteLinkFacade(ctx, helpers.GenerateShortURL("https://practicum.yandex.ru"))
+	require.NoError(t, err)
+	assert.Equal(t, 0, usage(), "Повторное удаление не должно возвращать квоту")
+}
+
+func TestStats(t *testing.T) {
+	store, err := storage.NewStorage("", "")
+	require.NoError(t, err)
+
+	f := NewFacade(store, config.DefaultURL)
+	ctx := context.Background()
+	visitorCtx := context.WithValue(ctx, authenticator.GetUserKey(), "visitor")
+
+	links := map[string]string{
+		"https://Example.com/a":       "alice",
+		"https://example.com/b":       "alice",
+		"https://practicum.yandex.ru": "bob",
+	}
+
+	codes := make(map[string]string, len(links))
+	normalized := make(map[string]string, len(links))
+
+	for originalURL, userID := range links {
+		shortURL, err := f.PostURLFacade(ctx, userID, originalURL)
+		require.NoError(t, err)
+
+		codes[originalURL] = path.Base(shortURL)
+
+		details, found := f.Store.Get(ctx, codes[originalURL])
+		require.True(t, found, "Ссылка должна быть сохранена")
+
+		normalized[originalURL] = details.OriginalURL
+	}
+
+	// Переходы: 3 по /a, 2 по /b (домен example.com набирает 5) и 4 по practicum.yandex.ru.
+	follows := map[string]int{"https://Example.com/a": 3, "https://example.com/b": 2, "https://practicum.yandex.ru": 4}
+
+	for originalURL, n := range follows {
+		for range n {
+			_, err := f.GetURLFacade(visitorCtx, codes[originalURL])
+			require.NoError(t, err)
+		}
+	}
+
+	require.NoError(t, f.DeleteUserURLFacade(ctx, "bob", []string{codes["https://practicum.yandex.ru"]}))
+
+	_, err = f.GetURLFacade(ctx, codes["https://practicum.yandex.ru"])
+	require.ErrorIs(t, err, ErrDeleted)
+
+	stats, err := f.StatsFacade(ctx, DefaultStatsWindow, 2)
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, stats.URLs, "Число ссылок не совпадает с ожидаемым")
+	assert.Equal(t, 2, stats.Users, "Число пользователей не совпадает с ожидаемым")
+	assert.Equal(t, 2, stats.ActiveURLs, "Число активных ссылок не совпадает с ожидаемым")
+	assert.Equal(t, 1, stats.DeletedURLs, "Число удаленных ссылок не совпадает с ожидаемым")
+	assert.Equal(t, 3, stats.ActiveUsers, "Число активных пользователей не совпадает с ожидаемым")
+	assert.Equal(t, "hour", stats.Bucket, "Шаг рядов для суточного окна должен быть часом")
+	assert.Equal(t, DefaultStatsWindow, stats.To.Sub(stats.From), "Окно не совпадает с ожидаемым")
+
+	require.Len(t, stats.Series, 24, "Число точек ряда не совпадает с ожидаемым")
+
+	// Между созданием ссылок и запросом статистики может смениться час, поэтому
+	// проверяются суммы по окну, а не отдельные точки.
+	assertSeries(t, stats, time.Hour, 3, 9)
+
+	assert.Equal(t, []storage.TopLink{
+		{ShortCode: codes["https://practicum.yandex.ru"], OriginalURL: normalized["https://practicum.yandex.ru"], Redirects: 4},
+		{ShortCode: codes["https://Example.com/a"], OriginalURL: normalized["https://Example.com/a"], Redirects: 3},
+	}, stats.TopLinks, "Самые популярные ссылки не совпадают с ожидаемыми")
+
+	assert.Equal(t, []storage.TopDomain{
+		{Domain: "example.com", Redirects: 5},
+		{Domain: "practicum.yandex.ru", Redirects: 4},
+	}, stats.TopDomains, "Самые популярные домены не совпадают с ожидаемыми")
+
+	stats, err = f.StatsFacade(ctx, 7*24*time.Hour, DefaultStatsTop)
+	require.NoError(t, err)
+	assert.Equal(t, "day", stats.Bucket, "Шаг рядов для недельного окна должен быть сутками")
+	require.Len(t, stats.Series, 7, "Число точек ряда не совпадает с ожидаемым")
+	assertSeries(t, stats, 24*time.Hour, 3, 9)
+}
+
+// assertSeries проверяет, что точки ряда идут подряд с шагом bucket от начала окна,
+// и сравнивает суммы созданных ссылок и переходов по всему окну.
+func assertSeries(t *testing.T, stats *storage.Stats, bucket time.Duration, created, redirects int) {
+	t.Helper()
+
+	var gotCreated, gotRedirects int
+
+	for i, point := range stats.Series {
+		assert.Equal(t, stats.From.Add(time.Duration(i)*bucket), point.Time, "Время точки ряда не совпадает с ожидаемым")
+
+		gotCreated += point.Created
+		gotRedirects += point.Redirects
+	}
+
+	assert.Equal(t, created, gotCreated, "Число созданных ссылок в окне не совпадает с ожидаемым")
+	assert.Equal(t, redirects, gotRedirects, "Число переходов в окне не совпадает с ожидаемым")
+}
@@ -1,6 +1,6 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.36.11
+// 	protoc-gen-go v1.36.12
 // 	protoc        v3.12.4
 // source: internal/grpc/grpc.proto
 
@@ -10,6 +10,7 @@ import (
 	reflect "reflect"
 	unsafe "unsafe"
 
+	_ "google.golang.org/genproto/googleapis/api/annotations"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 )
@@ -422,11 +423,485 @@ func (b0 URLData_builder) Build() *URLData {
 	return m0
 }
 
+type BatchShortenItem struct {
+	state         protoimpl.MessageState `protogen:"opaque.v1"`
+	CorrelationID string                 `protobuf:"bytes,1,opt,name=correlation_id,json=correlationId,proto3"`
+	OriginalURL   string                 `protobuf:"bytes,2,opt,name=original_url,json=originalUrl,proto3"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchShortenItem) Reset() {
+	*x = BatchShortenItem{}
+	mi := &file_internal_grpc_grpc_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchShortenItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchShortenItem) ProtoMessage() {}
+
+func (x *BatchShortenItem) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_grpc_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *BatchShortenItem) GetCorrelationId() string {
+	if x != nil {
+		return x.CorrelationID
+	}
+	return ""
+}
+
+func (x *BatchShortenItem) GetOriginalUrl() string {
+	if x != nil {
+		return x.OriginalURL
+	}
+	return ""
+}
+
+func (x *BatchShortenItem) SetCorrelationId(v string) {
+	x.CorrelationID = v
+}
+
+func (x *BatchShortenItem) SetOriginalUrl(v string) {
+	x.OriginalURL = v
+}
+
+type BatchShortenItem_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	CorrelationId string
+	OriginalUrl   string
+}
+
+func (b0 BatchShortenItem_builder) Build() *BatchShortenItem {
+	m0 := &BatchShortenItem{}
+	b, x := &b0, m0
+	_, _ = b, x
+	x.CorrelationID = b.CorrelationId
+	x.OriginalURL = b.OriginalUrl
+	return m0
+}
+
+type BatchShortenRequest struct {
+	state         protoimpl.MessageState `protogen:"opaque.v1"`
+	Items         *[]*BatchShortenItem   `protobuf:"bytes,1,rep,name=items,proto3"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchShortenRequest) Reset() {
+	*x = BatchShortenRequest{}
+	mi := &file_internal_grpc_grpc_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchShortenRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchShortenRequest) ProtoMessage() {}
+
+func (x *BatchShortenRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_grpc_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *BatchShortenRequest) GetItems() []*BatchShortenItem {
+	if x != nil {
+		if x.Items != nil {
+			return *x.Items
+		}
+	}
+	return nil
+}
+
+func (x *BatchShortenRequest) SetItems(v []*BatchShortenItem) {
+	x.Items = &v
+}
+
+type BatchShortenRequest_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	Items []*BatchShortenItem
+}
+
+func (b0 BatchShortenRequest_builder) Build() *BatchShortenRequest {
+	m0 := &BatchShortenRequest{}
+	b, x := &b0, m0
+	_, _ = b, x
+	x.Items = &b.Items
+	return m0
+}
+
+type BatchShortenResult struct {
+	state         protoimpl.MessageState `protogen:"opaque.v1"`
+	CorrelationID string                 `protobuf:"bytes,1,opt,name=correlation_id,json=correlationId,proto3"`
+	ShortURL      string                 `protobuf:"bytes,2,opt,name=short_url,json=shortUrl,proto3"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchShortenResult) Reset() {
+	*x = BatchShortenResult{}
+	mi := &file_internal_grpc_grpc_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchShortenResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchShortenResult) ProtoMessage() {}
+
+func (x *BatchShortenResult) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_grpc_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *BatchShortenResult) GetCorrelationId() string {
+	if x != nil {
+		return x.CorrelationID
+	}
+	return ""
+}
+
+func (x *BatchShortenResult) GetShortUrl() string {
+	if x != nil {
+		return x.ShortURL
+	}
+	return ""
+}
+
+func (x *BatchShortenResult) SetCorrelationId(v string) {
+	x.CorrelationID = v
+}
+
+func (x *BatchShortenResult) SetShortUrl(v string) {
+	x.ShortURL = v
+}
+
+type BatchShortenResult_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	CorrelationId string
+	ShortUrl      string
+}
+
+func (b0 BatchShortenResult_builder) Build() *BatchShortenResult {
+	m0 := &BatchShortenResult{}
+	b, x := &b0, m0
+	_, _ = b, x
+	x.CorrelationID = b.CorrelationId
+	x.ShortURL = b.ShortUrl
+	return m0
+}
+
+type BatchShortenResponse struct {
+	state         protoimpl.MessageState `protogen:"opaque.v1"`
+	Items         *[]*BatchShortenResult `protobuf:"bytes,1,rep,name=items,proto3"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchShortenResponse) Reset() {
+	*x = BatchShortenResponse{}
+	mi := &file_internal_grpc_grpc_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchShortenResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchShortenResponse) ProtoMessage() {}
+
+func (x *BatchShortenResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_grpc_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *BatchShortenResponse) GetItems() []*BatchShortenResult {
+	if x != nil {
+		if x.Items != nil {
+			return *x.Items
+		}
+	}
+	return nil
+}
+
+func (x *BatchShortenResponse) SetItems(v []*BatchShortenResult) {
+	x.Items = &v
+}
+
+type BatchShortenResponse_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	Items []*BatchShortenResult
+}
+
+func (b0 BatchShortenResponse_builder) Build() *BatchShortenResponse {
+	m0 := &BatchShortenResponse{}
+	b, x := &b0, m0
+	_, _ = b, x
+	x.Items = &b.Items
+	return m0
+}
+
+type DeleteUserURLsRequest struct {
+	state         protoimpl.MessageState `protogen:"opaque.v1"`
+	IDs           []string               `protobuf:"bytes,1,rep,name=ids,proto3"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteUserURLsRequest) Reset() {
+	*x = DeleteUserURLsRequest{}
+	mi := &file_internal_grpc_grpc_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteUserURLsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteUserURLsRequest) ProtoMessage() {}
+
+func (x *DeleteUserURLsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_grpc_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *DeleteUserURLsRequest) GetIds() []string {
+	if x != nil {
+		return x.IDs
+	}
+	return nil
+}
+
+func (x *DeleteUserURLsRequest) SetIds(v []string) {
+	x.IDs = v
+}
+
+type DeleteUserURLsRequest_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	Ids []string
+}
+
+func (b0 DeleteUserURLsRequest_builder) Build() *DeleteUserURLsRequest {
+	m0 := &DeleteUserURLsRequest{}
+	b, x := &b0, m0
+	_, _ = b, x
+	x.IDs = b.Ids
+	return m0
+}
+
+type DeleteUserURLsResponse struct {
+	state         protoimpl.MessageState `protogen:"opaque.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteUserURLsResponse) Reset() {
+	*x = DeleteUserURLsResponse{}
+	mi := &file_internal_grpc_grpc_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteUserURLsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteUserURLsResponse) ProtoMessage() {}
+
+func (x *DeleteUserURLsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_grpc_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+type DeleteUserURLsResponse_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+}
+
+func (b0 DeleteUserURLsResponse_builder) Build() *DeleteUserURLsResponse {
+	m0 := &DeleteUserURLsResponse{}
+	b, x := &b0, m0
+	_, _ = b, x
+	return m0
+}
+
+type StatsRequest struct {
+	state         protoimpl.MessageState `protogen:"opaque.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatsRequest) Reset() {
+	*x = StatsRequest{}
+	mi := &file_internal_grpc_grpc_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatsRequest) ProtoMessage() {}
+
+func (x *StatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_grpc_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+type StatsRequest_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+}
+
+func (b0 StatsRequest_builder) Build() *StatsRequest {
+	m0 := &StatsRequest{}
+	b, x := &b0, m0
+	_, _ = b, x
+	return m0
+}
+
+type StatsResponse struct {
+	state         protoimpl.MessageState `protogen:"opaque.v1"`
+	URLs          int64                  `protobuf:"varint,1,opt,name=urls,proto3"`
+	Users         int64                  `protobuf:"varint,2,opt,name=users,proto3"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatsResponse) Reset() {
+	*x = StatsResponse{}
+	mi := &file_internal_grpc_grpc_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatsResponse) ProtoMessage() {}
+
+func (x *StatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_grpc_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *StatsResponse) GetUrls() int64 {
+	if x != nil {
+		return x.URLs
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetUsers() int64 {
+	if x != nil {
+		return x.Users
+	}
+	return 0
+}
+
+func (x *StatsResponse) SetUrls(v int64) {
+	x.URLs = v
+}
+
+func (x *StatsResponse) SetUsers(v int64) {
+	x.Users = v
+}
+
+type StatsResponse_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	Urls  int64
+	Users int64
+}
+
+func (b0 StatsResponse_builder) Build() *StatsResponse {
+	m0 := &StatsResponse{}
+	b, x := &b0, m0
+	_, _ = b, x
+	x.URLs = b.Urls
+	x.Users = b.Users
+	return m0
+}
+
 var File_internal_grpc_grpc_proto protoreflect.FileDescriptor
 
 const file_internal_grpc_grpc_proto_rawDesc = "" +
 	"\n" +
-	"\x18internal/grpc/grpc.proto\x12\x04grpc\"%\n" +
+	"\x18internal/grpc/grpc.proto\x12\x04grpc\x1a\x1cgoogle/api/annotations.proto\"%\n" +
 	"\x11URLShortenRequest\x12\x10\n" +
 	"\x03url\x18\x01 \x01(\tR\x03url\",\n" +
 	"\x12URLShortenResponse\x12\x16\n" +
@@ -440,36 +915,72 @@ const file_internal_grpc_grpc_proto_rawDesc = "" +
 	"\x04urls\x18\x01 \x03(\v2\r.grpc.URLDataR\x04urls\"I\n" +
 	"\aURLData\x12\x1b\n" +
 	"\tshort_url\x18\x01 \x01(\tR\bshortUrl\x12!\n" +
-	"\foriginal_url\x18\x02 \x01(\tR\voriginalUrl2\xd0\x01\n" +
-	"\x10ShortenerService\x12?\n" +
+	"\foriginal_url\x18\x02 \x01(\tR\voriginalUrl\"\\\n" +
+	"\x10BatchShortenItem\x12%\n" +
+	"\x0ecorrelation_id\x18\x01 \x01(\tR\rcorrelationId\x12!\n" +
+	"\foriginal_url\x18\x02 \x01(\tR\voriginalUrl\"C\n" +
+	"\x13BatchShortenRequest\x12,\n" +
+	"\x05items\x18\x01 \x03(\v2\x16.grpc.BatchShortenItemR\x05items\"X\n" +
+	"\x12BatchShortenResult\x12%\n" +
+	"\x0ecorrelation_id\x18\x01 \x01(\tR\rcorrelationId\x12\x1b\n" +
+	"\tshort_url\x18\x02 \x01(\tR\bshortUrl\"F\n" +
+	"\x14BatchShortenResponse\x12.\n" +
+	"\x05items\x18\x01 \x03(\v2\x18.grpc.BatchShortenResultR\x05items\")\n" +
+	"\x15DeleteUserURLsRequest\x12\x10\n" +
+	"\x03ids\x18\x01 \x03(\tR\x03ids\"\x18\n" +
+	"\x16DeleteUserURLsResponse\"\x0e\n" +
+	"\fStatsRequest\"9\n" +
+	"\rStatsResponse\x12\x12\n" +
+	"\x04urls\x18\x01 \x01(\x03R\x04urls\x12\x14\n" +
+	"\x05users\x18\x02 \x01(\x03R\x05users2\xb5\x04\n" +
+	"\x10ShortenerService\x12W\n" +
 	"\n" +
-	"ShortenURL\x12\x17.grpc.URLShortenRequest\x1a\x18.grpc.URLShortenResponse\x12<\n" +
-	"\tExpandURL\x12\x16.grpc.URLExpandRequest\x1a\x17.grpc.URLExpandResponse\x12=\n" +
-	"\fListUserURLs\x12\x15.grpc.UserURLsRequest\x1a\x16.grpc.UserURLsResponseB\vZ\tgrpc/grpcb\x06proto3"
-
-var file_internal_grpc_grpc_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+	"ShortenURL\x12\x17.grpc.URLShortenRequest\x1a\x18.grpc.URLShortenResponse\"\x16\x82\xd3\xe4\x93\x02\x10:\x01*\"\v/v2/shorten\x12U\n" +
+	"\tExpandURL\x12\x16.grpc.URLExpandRequest\x1a\x17.grpc.URLExpandResponse\"\x17\x82\xd3\xe4\x93\x02\x11\x12\x0f/v2/expand/{id}\x12T\n" +
+	"\fListUserURLs\x12\x15.grpc.UserURLsRequest\x1a\x16.grpc.UserURLsResponse\"\x15\x82\xd3\xe4\x93\x02\x0f\x12\r/v2/user/urls\x12c\n" +
+	"\fShortenBatch\x12\x19.grpc.BatchShortenRequest\x1a\x1a.grpc.BatchShortenResponse\"\x1c\x82\xd3\xe4\x93\x02\x16:\x01*\"\x11/v2/shorten/batch\x12e\n" +
+	"\x0eDeleteUserURLs\x12\x1b.grpc.DeleteUserURLsRequest\x1a\x1c.grpc.DeleteUserURLsResponse\"\x18\x82\xd3\xe4\x93\x02\x12:\x01**\r/v2/user/urls\x12O\n" +
+	"\bGetStats\x12\x12.grpc.StatsRequest\x1a\x13.grpc.StatsResponse\"\x1a\x82\xd3\xe4\x93\x02\x14\x12\x12/v2/internal/statsB\vZ\tgrpc/grpcb\x06proto3"
+
+var file_internal_grpc_grpc_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
 var file_internal_grpc_grpc_proto_goTypes = []any{
-	(*URLShortenRequest)(nil),  // 0: grpc.URLShortenRequest
-	(*URLShortenResponse)(nil), // 1: grpc.URLShortenResponse
-	(*URLExpandRequest)(nil),   // 2: grpc.URLExpandRequest
-	(*URLExpandResponse)(nil),  // 3: grpc.URLExpandResponse
-	(*UserURLsRequest)(nil),    // 4: grpc.UserURLsRequest
-	(*UserURLsResponse)(nil),   // 5: grpc.UserURLsResponse
-	(*URLData)(nil),            // 6: grpc.URLData
+	(*URLShortenRequest)(nil),      // 0: grpc.URLShortenRequest
+	(*URLShortenResponse)(nil),     // 1: grpc.URLShortenResponse
+	(*URLExpandRequest)(nil),       // 2: grpc.URLExpandRequest
+	(*URLExpandResponse)(nil),      // 3: grpc.URLExpandResponse
+	(*UserURLsRequest)(nil),        // 4: grpc.UserURLsRequest
+	(*UserURLsResponse)(nil),       // 5: grpc.UserURLsResponse
+	(*URLData)(nil),                // 6: grpc.URLData
+	(*BatchShortenItem)(nil),       // 7: grpc.BatchShortenItem
+	(*BatchShortenRequest)(nil),    // 8: grpc.BatchShortenRequest
+	(*BatchShortenResult)(nil),     // 9: grpc.BatchShortenResult
+	(*BatchShortenResponse)(nil),   // 10: grpc.BatchShortenResponse
+	(*DeleteUserURLsRequest)(nil),  // 11: grpc.DeleteUserURLsRequest
+	(*DeleteUserURLsResponse)(nil), // 12: grpc.DeleteUserURLsResponse
+	(*StatsRequest)(nil),           // 13: grpc.StatsRequest
+	(*StatsResponse)(nil),          // 14: grpc.StatsResponse
 }
 var file_internal_grpc_grpc_proto_depIdxs = []int32{
-	6, // 0: grpc.UserURLsResponse.urls:type_name -> grpc.URLData
-	0, // 1: grpc.ShortenerService.ShortenURL:input_type -> grpc.URLShortenRequest
-	2, // 2: grpc.ShortenerService.ExpandURL:input_type -> grpc.URLExpandRequest
-	4, // 3: grpc.ShortenerService.ListUserURLs:input_type -> grpc.UserURLsRequest
-	1, // 4: grpc.ShortenerService.ShortenURL:output_type -> grpc.URLShortenResponse
-	3, // 5: grpc.ShortenerService.ExpandURL:output_type -> grpc.URLExpandResponse
-	5, // 6: grpc.ShortenerService.ListUserURLs:output_type -> grpc.UserURLsResponse
-	4, // [4:7] is the sub-list for method output_type
-	1, // [1:4] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	6,  // 0: grpc.UserURLsResponse.urls:type_name -> grpc.URLData
+	7,  // 1: grpc.BatchShortenRequest.items:type_name -> grpc.BatchShortenItem
+	9,  // 2: grpc.BatchShortenResponse.items:type_name -> grpc.BatchShortenResult
+	0,  // 3: grpc.ShortenerService.ShortenURL:input_type -> grpc.URLShortenRequest
+	2,  // 4: grpc.ShortenerService.ExpandURL:input_type -> grpc.URLExpandRequest
+	4,  // 5: grpc.ShortenerService.ListUserURLs:input_type -> grpc.UserURLsRequest
+	8,  // 6: grpc.ShortenerService.ShortenBatch:input_type -> grpc.BatchShortenRequest
+	11, // 7: grpc.ShortenerService.DeleteUserURLs:input_type -> grpc.DeleteUserURLsRequest
+	13, // 8: grpc.ShortenerService.GetStats:input_type -> grpc.StatsRequest
+	1,  // 9: grpc.ShortenerService.ShortenURL:output_type -> grpc.URLShortenResponse
+	3,  // 10: grpc.ShortenerService.ExpandURL:output_type -> grpc.URLExpandResponse
+	5,  // 11: grpc.ShortenerService.ListUserURLs:output_type -> grpc.UserURLsResponse
+	10, // 12: grpc.ShortenerService.ShortenBatch:output_type -> grpc.BatchShortenResponse
+	12, // 13: grpc.ShortenerService.DeleteUserURLs:output_type -> grpc.DeleteUserURLsResponse
+	14, // 14: grpc.ShortenerService.GetStats:output_type -> grpc.StatsResponse
+	9,  // [9:15] is the sub-list for method output_type
+	3,  // [3:9] is the sub-list for method input_type
+	3,  // [3:3] is the sub-list for extension type_name
+	3,  // [3:3] is the sub-list for extension extendee
+	0,  // [0:3] is the sub-list for field type_name
 }
 
 func init() { file_internal_grpc_grpc_proto_init() }
@@ -483,7 +994,7 @@ func file_internal_grpc_grpc_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_internal_grpc_grpc_proto_rawDesc), len(file_internal_grpc_grpc_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   7,
+			NumMessages:   15,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -2,6 +2,7 @@ package grpc
 
 import (
 	context "context"
+	"errors"
 
 	grpc "google.golang.org/grpc"
 	codes "google.golang.org/grpc/codes"
@@ -9,22 +10,23 @@ import (
 	status "google.golang.org/grpc/status"
 
 	"github.com/flash1nho/go-musthave-shortener-tpl/internal/authenticator"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/logger"
+
+	"github.com/google/uuid"
 )
 
 type grpcProvider struct{}
 
 func (p *grpcProvider) GetCookie(ctx context.Context, _ string) (string, error) {
-	var cookie string
-
 	if md, ok := metadata.FromIncomingContext(ctx); ok {
 		values := md.Get("authorization")
 
-		if len(values) > 0 {
-			cookie = values[0]
+		if len(values) > 0 && values[0] != "" {
+			return values[0], nil
 		}
 	}
 
-	return cookie, nil
+	return "", errors.New("authorization не передан")
 }
 
 func (p *grpcProvider) SetCookie(ctx context.Context, cookieName, cookieValue string) error {
@@ -42,3 +44,23 @@ func Auth(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc
 
 	return handler(ctx, req)
 }
+
+// RequestID кладет идентификатор вызова в контекст для последующих логов.
+// Идентификатор берется из метаданных x-request-id или генерируется и возвращается в заголовке ответа.
+func RequestID(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	var requestID string
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("x-request-id"); len(values) > 0 && len(values[0]) <= 128 {
+			requestID = values[0]
+		}
+	}
+
+	if requestID == "" {
+		requestID = uuid.NewString()
+	}
+
+	grpc.SetHeader(ctx, metadata.Pairs("x-request-id", requestID))
+
+	return handler(logger.ContextWithRequestID(ctx, requestID), req)
+}
@@ -0,0 +1,36 @@
+package grpc
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// GatewayPrefix — префикс, под которым REST-мост к gRPC сервису монтируется в HTTP-роутер.
+const GatewayPrefix = "/v2"
+
+// NewGateway создает REST-мост к gRPC сервису, описанный HTTP-аннотациями в grpc.proto.
+//
+// Вызовы обрабатываются в том же процессе напрямую через GrpcHandler, поэтому
+// контекст запроса (в том числе userID, положенный middlewares.Auth) передается в хэндлер как есть.
+func NewGateway(ctx context.Context, g *GrpcHandler) (http.Handler, error) {
+	mux := runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			MarshalOptions: protojson.MarshalOptions{
+				UseProtoNames:   true,
+				EmitUnpopulated: true,
+			},
+			UnmarshalOptions: protojson.UnmarshalOptions{
+				DiscardUnknown: true,
+			},
+		}),
+	)
+
+	if err := RegisterShortenerServiceHandlerServer(ctx, mux, g); err != nil {
+		return nil, err
+	}
+
+	return mux, nil
+}
@@ -0,0 +1,89 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/authenticator"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/config"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/facade"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/helpers"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGateway(t *testing.T) {
+	store, err := storage.NewStorage("", "")
+	require.NoError(t, err)
+
+	f := facade.NewFacade(store, config.DefaultURL)
+	gateway, err := NewGateway(t.Context(), NewHandler(f))
+	require.NoError(t, err)
+
+	userID, err := authenticator.GenerateUniqueUserID()
+	require.NoError(t, err)
+
+	originalURL := "https://practicum.yandex.ru"
+	shortURL := helpers.GenerateShortURL(originalURL)
+	expected, _ := url.JoinPath(config.DefaultURL, shortURL)
+
+	// описываем набор данных: метод запроса, path запроса, тело запроса, ожидаемый код ответа, ожидаемое тело ответа
+	testCases := []struct {
+		name         string
+		method       string
+		path         string
+		requestBody  string
+		status       int
+		responseBody map[string]any
+	}{
+		{
+			name:         "shorten",
+			method:       http.MethodPost,
+			path:         GatewayPrefix + "/shorten",
+			requestBody:  `{"url":"` + originalURL + `"}`,
+			status:       http.StatusOK,
+			responseBody: map[string]any{"result": expected},
+		},
+		{
+			name:         "expand",
+			method:       http.MethodGet,
+			path:         GatewayPrefix + "/expand/" + shortURL,
+			status:       http.StatusOK,
+			responseBody: map[string]any{"result": originalURL},
+		},
+		{
+			name:   "user urls",
+			method: http.MethodGet,
+			path:   GatewayPrefix + "/user/urls",
+			status: http.StatusOK,
+			responseBody: map[string]any{"urls": []any{
+				map[string]any{"short_url": expected, "original_url": originalURL},
+			}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.requestBody))
+			w := httptest.NewRecorder()
+
+			ctx := context.WithValue(r.Context(), authenticator.GetUserKey(), userID)
+			r = r.WithContext(ctx)
+
+			gateway.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.status, w.Code, "Код ответа не совпадает с ожидаемым")
+
+			var body map[string]any
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, tc.responseBody, body, "Тело ответа не совпадает с ожидаемым")
+		})
+	}
+}
@@ -2,8 +2,18 @@ package grpc
 
 import (
 	"context"
+	"errors"
+	"strconv"
 
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/blocklist"
 	"github.com/flash1nho/go-musthave-shortener-tpl/internal/facade"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/logger"
 )
 
 type GrpcHandler struct {
@@ -24,13 +34,13 @@ func (g *GrpcHandler) ShortenURL(ctx context.Context, req *URLShortenRequest) (*
 	userID, err := g.facade.GetUserFromContext(ctx)
 
 	if err != nil {
-		return nil, err
+		return nil, statusError(ctx, err)
 	}
 
 	result, err := g.facade.PostURLFacade(ctx, userID, req.URL)
 
 	if err != nil {
-		return nil, err
+		return nil, statusError(ctx, err)
 	}
 
 	response.Result = result
@@ -41,10 +51,16 @@ func (g *GrpcHandler) ShortenURL(ctx context.Context, req *URLShortenRequest) (*
 func (g *GrpcHandler) ExpandURL(ctx context.Context, req *URLExpandRequest) (*URLExpandResponse, error) {
 	var response URLExpandResponse
 
-	URLDetails, err := g.facade.GetURLFacade(req.ID)
+	URLDetails, err := g.facade.GetURLFacade(ctx, req.ID)
+
+	// У gRPC-клиента нет страницы предупреждения, поэтому оно передается в заголовке ответа.
+	if blocked, ok := blocklist.IsBlocked(err); ok && blocked.Rule.Action == blocklist.ActionWarn {
+		grpc.SetHeader(ctx, metadata.Pairs("x-blocklist-warning", blocked.Error()))
+		err = nil
+	}
 
 	if err != nil {
-		return nil, err
+		return nil, statusError(ctx, err)
 	}
 
 	response.Result = URLDetails.OriginalURL
@@ -58,13 +74,13 @@ func (g *GrpcHandler) ListUserURLs(ctx context.Context, req *UserURLsRequest) (*
 	userID, err := g.facade.GetUserFromContext(ctx)
 
 	if err != nil {
-		return nil, err
+		return nil, statusError(ctx, err)
 	}
 
 	result, err := g.facade.APIUserURLFacade(ctx, userID)
 
 	if err != nil {
-		return nil, err
+		return nil, statusError(ctx, err)
 	}
 
 	grpcURLs := make([]*URLData, 0, len(result))
@@ -80,3 +96,101 @@ func (g *GrpcHandler) ListUserURLs(ctx context.Context, req *UserURLsRequest) (*
 
 	return &response, nil
 }
+
+func (g *GrpcHandler) ShortenBatch(ctx context.Context, req *BatchShortenRequest) (*BatchShortenResponse, error) {
+	var response BatchShortenResponse
+
+	userID, err := g.facade.GetUserFromContext(ctx)
+
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+
+	reqItems := req.GetItems()
+	items := make([]facade.BatchShortenItem, 0, len(reqItems))
+
+	for _, v := range reqItems {
+		items = append(items, facade.BatchShortenItem{
+			CorrelationID: v.CorrelationID,
+			OriginalURL:   v.OriginalURL,
+		})
+	}
+
+	result, err := g.facade.PostBatchURLFacade(ctx, userID, items)
+
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+
+	grpcItems := make([]*BatchShortenResult, 0, len(result))
+
+	for _, v := range result {
+		grpcItems = append(grpcItems, &BatchShortenResult{
+			CorrelationID: v.CorrelationID,
+			ShortURL:      v.ShortURL,
+		})
+	}
+
+	response.Items = &grpcItems
+
+	return &response, nil
+}
+
+func (g *GrpcHandler) DeleteUserURLs(ctx context.Context, req *DeleteUserURLsRequest) (*DeleteUserURLsResponse, error) {
+	userID, err := g.facade.GetUserFromContext(ctx)
+
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+
+	err = g.facade.DeleteUserURLFacade(ctx, userID, req.IDs)
+
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+
+	return &DeleteUserURLsResponse{}, nil
+}
+
+func (g *GrpcHandler) GetStats(ctx context.Context, req *StatsRequest) (*StatsResponse, error) {
+	var response StatsResponse
+
+	urls, users := g.facade.TotalsFacade(ctx)
+
+	response.URLs = int64(urls)
+	response.Users = int64(users)
+
+	return &response, nil
+}
+
+// statusError переводит ошибки фасада в коды gRPC по тем же правилам, что и HTTP-обработчики:
+// некорректный адрес — codes.InvalidArgument, адрес из списка блокировок, ссылка, заблокированная
+// оператором, и отключенный пользователь — codes.PermissionDenied,
+// отсутствующая ссылка — codes.NotFound, существующая — codes.AlreadyExists,
+// превышение квоты — codes.ResourceExhausted с остатком в заголовке x-quota-remaining.
+// Остальные ошибки возвращаются как codes.Internal без подробностей.
+func statusError(ctx context.Context, err error) error {
+	var (
+		exceeded *facade.QuotaExceededError
+		blocked  *facade.BlockedError
+	)
+
+	switch {
+	case errors.As(err, &exceeded):
+		grpc.SetHeader(ctx, metadata.Pairs("x-quota-remaining", strconv.Itoa(max(exceeded.Usage.Remaining(), 0))))
+
+		return status.Error(codes.ResourceExhausted, exceeded.Error())
+	case errors.As(err, &blocked), errors.Is(err, facade.ErrLinkBlocked), errors.Is(err, facade.ErrUserDisabled):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, facade.ErrInvalidURL):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, facade.ErrNotFound), errors.Is(err, facade.ErrDeleted):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, facade.ErrConflict):
+		return status.Error(codes.AlreadyExists, facade.ErrConflict.Error())
+	}
+
+	logger.WithContext(ctx, nil).Error("Внутренняя ошибка обработки вызова", zap.Error(err))
+
+	return status.Error(codes.Internal, "внутренняя ошибка сервера")
+}
@@ -0,0 +1,80 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/config"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/facade"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/helpers"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+func TestExpandURL(t *testing.T) {
+	store, err := storage.NewStorage("", "")
+	require.NoError(t, err)
+
+	f := facade.NewFacade(store, config.DefaultURL)
+	g := NewHandler(f)
+	ctx := context.Background()
+
+	links := map[string]string{
+		"active":  "https://practicum.yandex.ru",
+		"deleted": "https://ya.ru",
+		"blocked": "https://go.dev",
+	}
+
+	for _, originalURL := range links {
+		require.NoError(t, store.Set(ctx, helpers.GenerateShortURL(originalURL), originalURL, "owner"))
+	}
+
+	require.NoError(t, store.DeleteBatch(ctx, "owner", []string{helpers.GenerateShortURL(links["deleted"])}))
+	require.NoError(t, store.SetBlocked(ctx, helpers.GenerateShortURL(links["blocked"]), true))
+
+	testCases := []struct {
+		name string
+		id   string
+		code codes.Code
+	}{
+		{name: "активная ссылка", id: helpers.GenerateShortURL(links["active"]), code: codes.OK},
+		{name: "удаленная ссылка", id: helpers.GenerateShortURL(links["deleted"]), code: codes.NotFound},
+		{name: "заблокированная ссылка", id: helpers.GenerateShortURL(links["blocked"]), code: codes.PermissionDenied},
+		{name: "несуществующая ссылка", id: "missing", code: codes.NotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			response, err := g.ExpandURL(ctx, &URLExpandRequest{ID: tc.id})
+
+			assert.Equal(t, tc.code, status.Code(err), "Код ответа не совпадает с ожидаемым")
+
+			if tc.code != codes.OK {
+				assert.Nil(t, response, "Адрес недоступной ссылки не должен возвращаться")
+			}
+		})
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	store, err := storage.NewStorage("", "")
+	require.NoError(t, err)
+
+	g := NewHandler(facade.NewFacade(store, config.DefaultURL))
+	ctx := context.Background()
+
+	require.NoError(t, store.SetBatch(ctx, map[string]string{"a": "https://practicum.yandex.ru", "b": "https://ya.ru"}, "first"))
+	require.NoError(t, store.Set(ctx, "c", "https://go.dev", "second"))
+	require.NoError(t, store.Set(ctx, "d", "https://go.dev/doc", ""))
+	require.NoError(t, store.DeleteBatch(ctx, "first", []string{"a"}))
+
+	response, err := g.GetStats(ctx, &StatsRequest{})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(4), response.URLs, "Число ссылок не совпадает с ожидаемым")
+	assert.Equal(t, int64(2), response.Users, "Число пользователей не совпадает с ожидаемым")
+}
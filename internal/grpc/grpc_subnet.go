@@ -0,0 +1,29 @@
+package grpc
+
+import (
+	context "context"
+	"slices"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/clientip"
+
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// TrustedSubnet ограничивает вызов перечисленных методов клиентами из доверенных подсетей.
+// Адрес клиента берется из контекста: его сохраняет clientip.Resolver.UnaryServerInterceptor,
+// который должен стоять в цепочке раньше. Пустой список запрещает вызовы всем.
+func TrustedSubnet(subnets clientip.Subnets, methods ...string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !slices.Contains(methods, info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		if !subnets.Contains(clientip.IP(ctx)) {
+			return nil, status.Error(codes.PermissionDenied, "IP-адрес клиента не входит в доверенные подсети")
+		}
+
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,46 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/clientip"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+func TestTrustedSubnet(t *testing.T) {
+	subnets, err := clientip.ParseSubnets([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	handler := func(context.Context, any) (any, error) { return "ok", nil }
+
+	testCases := []struct {
+		name    string
+		subnets clientip.Subnets
+		method  string
+		ip      string
+		code    codes.Code
+	}{
+		{name: "доверенная подсеть", subnets: subnets, method: ShortenerService_GetStats_FullMethodName, ip: "10.2.3.4", code: codes.OK},
+		{name: "чужой адрес", subnets: subnets, method: ShortenerService_GetStats_FullMethodName, ip: "192.0.2.1", code: codes.PermissionDenied},
+		{name: "подсети не заданы", method: ShortenerService_GetStats_FullMethodName, ip: "10.2.3.4", code: codes.PermissionDenied},
+		{name: "метод без ограничения", method: ShortenerService_ShortenURL_FullMethodName, ip: "192.0.2.1", code: codes.OK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := clientip.WithIP(context.Background(), net.ParseIP(tc.ip))
+			interceptor := TrustedSubnet(tc.subnets, ShortenerService_GetStats_FullMethodName)
+
+			_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tc.method}, handler)
+
+			assert.Equal(t, tc.code, status.Code(err), "Код ответа не совпадает с ожидаемым")
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/audit"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/facade"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/health"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/webhook"
+)
+
+// generate:reset
+type AdminHealthResponse struct {
+	Status string                        `json:"status"`
+	Checks map[string]health.CheckResult `json:"checks"`
+	// AuditQueues — очереди приемников аудита в порядке регистрации.
+	AuditQueues []audit.QueueStats `json:"audit_queues"`
+	// Webhooks — очередь доставок вебхуков; отсутствует, если вебхуки отключены.
+	Webhooks *webhook.QueueStats `json:"webhooks,omitempty"`
+}
+
+// writeLink отвечает ссылкой или ошибкой фасада.
+func (h *Handler) writeLink(w http.ResponseWriter, r *http.Request, link facade.Link, err error) {
+	if err != nil {
+		h.writeError(w, r, fmt.Errorf("ошибка обработки ссылки: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	json.NewEncoder(w).Encode(link)
+}
+
+// writeUser отвечает пользователем или ошибкой фасада.
+func (h *Handler) writeUser(w http.ResponseWriter, r *http.Request, user facade.User, err error) {
+	if err != nil {
+		h.writeError(w, r, fmt.Errorf("ошибка обработки пользователя: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	json.NewEncoder(w).Encode(user)
+}
+
+// APIInternalLinkHandler - возвращает любую ссылку по коду вместе с владельцем и состоянием:
+//
+//	{"short_code": "abc", "short_url": "http://localhost:8080/abc", "original_url": "https://example.com",
+//	 "user_id": "...", "deleted": false, "blocked": false}
+//
+// @Tags admin
+// @Summary Возвращает ссылку по коду
+// @ID APIInternalLinkHandler
+// @Param code path string true "Код ссылки"
+// @Produce json
+// @Success 200
+// @Failure 403
+// @Failure 404
+// @Router /api/internal/links/{code} [GET]
+func (h *Handler) APIInternalLinkHandler(w http.ResponseWriter, r *http.Request) {
+	link, err := h.Facade.AdminLinkFacade(r.Context(), chi.URLParam(r, "code"))
+
+	h.writeLink(w, r, link, err)
+}
+
+// APIInternalLinkDeleteHandler - удаляет ссылку независимо от владельца и возвращает ее.
+// Владелец получает событие вебхука deleted.
+//
+// @Tags admin
+// @Summary Принудительно удаляет ссылку
+// @ID APIInternalLinkDeleteHandler
+// @Param code path string true "Код ссылки"
+// @Produce json
+// @Success 200
+// @Failure 403
+// @Failure 404
+// @Failure 500
+// @Router /api/internal/links/{code} [DELETE]
+func (h *Handler) APIInternalLinkDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	link, err := h.Facade.AdminDeleteLinkFacade(r.Context(), chi.URLParam(r, "code"))
+
+	h.writeLink(w, r, link, err)
+}
+
+// APIInternalLinkBlockHandler - блокирует ссылку: переход по ней отвечает 403, пока блокировку не снимут.
+//
+// @Tags admin
+// @Summary Блокирует ссылку
+// @ID APIInternalLinkBlockHandler
+// @Param code path string true "Код ссылки"
+// @Produce json
+// @Success 200
+// @Failure 403
+// @Failure 404
+// @Failure 500
+// @Router /api/internal/links/{code}/block [POST]
+func (h *Handler) APIInternalLinkBlockHandler(w http.ResponseWriter, r *http.Request) {
+	link, err := h.Facade.AdminBlockLinkFacade(r.Context(), chi.URLParam(r, "code"), true)
+
+	h.writeLink(w, r, link, err)
+}
+
+// APIInternalLinkUnblockHandler - снимает блокировку ссылки.
+//
+// @Tags admin
+// @Summary Снимает блокировку ссылки
+// @ID APIInternalLinkUnblockHandler
+// @Param code path string true "Код ссылки"
+// @Produce json
+// @Success 200
+// @Failure 403
+// @Failure 404
+// @Failure 500
+// @Router /api/internal/links/{code}/block [DELETE]
+func (h *Handler) APIInternalLinkUnblockHandler(w http.ResponseWriter, r *http.Request) {
+	link, err := h.Facade.AdminBlockLinkFacade(r.Context(), chi.URLParam(r, "code"), false)
+
+	h.writeLink(w, r, link, err)
+}
+
+// APIInternalUserHandler - возвращает пользователя и все его ссылки, включая удаленные и заблокированные:
+//
+//	{"id": "...", "disabled": false, "links": [...]}
+//
+// @Tags admin
+// @Summary Возвращает пользователя и его ссылки
+// @ID APIInternalUserHandler
+// @Param id path string true "Идентификатор пользователя"
+// @Produce json
+// @Success 200
+// @Failure 403
+// @Failure 500
+// @Router /api/internal/users/{id} [GET]
+func (h *Handler) APIInternalUserHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := h.Facade.AdminUserFacade(r.Context(), chi.URLParam(r, "id"))
+
+	h.writeUser(w, r, user, err)
+}
+
+// APIInternalUserDisableHandler - отключает пользователя: его запросы отклоняются с 403,
+// а переходы по его ссылкам запрещены.
+//
+// @Tags admin
+// @Summary Отключает пользователя
+// @ID APIInternalUserDisableHandler
+// @Param id path string true "Идентификатор пользователя"
+// @Produce json
+// @Success 200
+// @Failure 403
+// @Failure 500
+// @Router /api/internal/users/{id}/disable [POST]
+func (h *Handler) APIInternalUserDisableHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := h.Facade.AdminDisableUserFacade(r.Context(), chi.URLParam(r, "id"), true)
+
+	h.writeUser(w, r, user, err)
+}
+
+// APIInternalUserEnableHandler - снова включает отключенного пользователя.
+//
+// @Tags admin
+// @Summary Включает пользователя
+// @ID APIInternalUserEnableHandler
+// @Param id path string true "Идентификатор пользователя"
+// @Produce json
+// @Success 200
+// @Failure 403
+// @Failure 500
+// @Router /api/internal/users/{id}/disable [DELETE]
+func (h *Handler) APIInternalUserEnableHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := h.Facade.AdminDisableUserFacade(r.Context(), chi.URLParam(r, "id"), false)
+
+	h.writeUser(w, r, user, err)
+}
+
+// APIInternalHealthHandler - возвращает проверки хранилища и приемников аудита вместе с состоянием
+// очередей аудита и вебхуков. В отличие от /readyz отвечает 200 и при неудачных проверках:
+// итог передается в поле status.
+//
+// @Tags admin
+// @Summary Возвращает состояние хранилища и очередей
+// @ID APIInternalHealthHandler
+// @Produce json
+// @Success 200
+// @Failure 403
+// @Router /api/internal/health [GET]
+func (h *Handler) APIInternalHealthHandler(w http.ResponseWriter, r *http.Request) {
+	resp := AdminHealthResponse{
+		Status:      health.StatusOK,
+		Checks:      map[string]health.CheckResult{},
+		AuditQueues: []audit.QueueStats{},
+	}
+
+	if h.Health != nil {
+		report := h.Health.Ready(r.Context())
+		resp.Status, resp.Checks = report.Status, report.Checks
+	}
+
+	if h.AuditQueues != nil {
+		resp.AuditQueues = append(resp.AuditQueues, h.AuditQueues()...)
+	}
+
+	// Состояние сервиса читает оператор, поэтому запрос попадает в аудит.
+	h.Facade.AuditOperatorRead(r.Context(), audit.ActionViewHealth, "", "", nil)
+
+	if h.Facade.Webhooks != nil {
+		stats, err := h.webhookStats(r.Context())
+
+		if err != nil {
+			resp.Status = health.StatusFail
+			resp.Checks["webhooks"] = health.CheckResult{Status: health.StatusFail, Error: err.Error(), Latency: "0s"}
+		} else {
+			resp.Webhooks = &stats
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *Handler) webhookStats(ctx context.Context) (webhook.QueueStats, error) {
+	ctx, cancel := context.WithTimeout(ctx, health.DefaultTimeout)
+	defer cancel()
+
+	return h.Facade.Webhooks.Stats(ctx)
+}
@@ -0,0 +1,208 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/audit"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/logger"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/problem"
+
+	"go.uber.org/zap"
+)
+
+// Размер страницы журнала аудита.
+const (
+	DefaultAuditLimit = 100
+	MaxAuditLimit     = 1000
+)
+
+// generate:reset
+type AuditEventsResponse struct {
+	Events []audit.Event `json:"events"`
+	// NextOffset — offset следующей страницы; отсутствует на последней странице.
+	NextOffset *int `json:"next_offset,omitempty"`
+	// Filter — фильтр приемника журнала; отсутствует, если журнал содержит все события.
+	Filter *audit.Filter `json:"filter,omitempty"`
+}
+
+// errStopExport прерывает выгрузку, когда запись клиенту не удалась.
+var errStopExport = errors.New("выгрузка прервана")
+
+// APIInternalAuditHandler - возвращает события аудита в порядке записи. Параметры запроса:
+//
+//	user_id, action, short_code — точное совпадение;
+//	from, to — интервал времени в RFC 3339, from включительно, to не включительно;
+//	limit (по умолчанию 100, не больше 1000), offset — страница;
+//	format=ndjson — выгрузка всех подходящих событий потоком по одному JSON на строку.
+//
+// Страница возвращается как {"events": [...], "next_offset": 200}. При выгрузке limit и offset
+// применяются, только если заданы явно.
+//
+// Если журнал читается из приемника с фильтром, он содержит не все события: фильтр возвращается
+// в поле filter и, в том числе при выгрузке, в заголовке X-Audit-Filter.
+//
+// @Tags audit
+// @Summary Возвращает события аудита
+// @ID APIInternalAuditHandler
+// @Produce json
+// @Produce application/x-ndjson
+// @Success 200
+// @Failure 400
+// @Failure 403
+// @Failure 404
+// @Failure 500
+// @Router /api/internal/audit [GET]
+func (h *Handler) APIInternalAuditHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Facade.AuditLog == nil {
+		problem.Error(w, r, http.StatusNotFound, problem.TypeNotFound, "журнал аудита недоступен: ни один приемник не поддерживает чтение")
+		return
+	}
+
+	export := r.URL.Query().Get("format") == "ndjson"
+	q, err := parseAuditQuery(r, export)
+
+	if err != nil {
+		badRequest(w, r, err.Error())
+		return
+	}
+
+	// Чтение журнала тоже попадает в аудит, уже после выборки, чтобы не оказаться в ней.
+	defer func() {
+		h.Facade.AuditOperatorRead(r.Context(), audit.ActionAuditQuery, q.UserID, q.ShortCode, err)
+	}()
+
+	filter := h.Facade.AuditLogFilter
+
+	if !filter.IsZero() {
+		w.Header().Set("X-Audit-Filter", filter.String())
+	}
+
+	if export {
+		err = h.exportAudit(w, r, q)
+		return
+	}
+
+	// Лишнее событие показывает, есть ли следующая страница.
+	limit := q.Limit
+	q.Limit++
+
+	resp := AuditEventsResponse{Events: []audit.Event{}}
+
+	if !filter.IsZero() {
+		resp.Filter = &filter
+	}
+
+	err = h.Facade.AuditLog.Query(r.Context(), q, func(e audit.Event) error {
+		resp.Events = append(resp.Events, e)
+		return nil
+	})
+
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	if len(resp.Events) > limit {
+		resp.Events = resp.Events[:limit]
+		next := q.Offset + limit
+		resp.NextOffset = &next
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+// exportAudit пишет события потоком. Заголовки отправляются с первым событием, поэтому ошибка
+// до него возвращается обычным ответом, а после него обрывает выгрузку. Возвращает ошибку выборки.
+func (h *Handler) exportAudit(w http.ResponseWriter, r *http.Request, q audit.Query) error {
+	enc := json.NewEncoder(w)
+	started := false
+
+	err := h.Facade.AuditLog.Query(r.Context(), q, func(e audit.Event) error {
+		if !started {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.Header().Set("Content-Disposition", `attachment; filename="audit.ndjson"`)
+			started = true
+		}
+
+		if err := enc.Encode(e); err != nil {
+			return errStopExport
+		}
+
+		return nil
+	})
+
+	switch {
+	case err == nil && !started:
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+	case err != nil && !started:
+		h.writeError(w, r, err)
+	case err != nil && !errors.Is(err, errStopExport):
+		logger.WithContext(r.Context(), h.log).Error("Ошибка выгрузки журнала аудита", zap.Error(err))
+	}
+
+	return err
+}
+
+// parseAuditQuery разбирает параметры выборки. Для выгрузки limit по умолчанию не ограничен.
+func parseAuditQuery(r *http.Request, export bool) (audit.Query, error) {
+	values := r.URL.Query()
+
+	q := audit.Query{
+		UserID:    values.Get("user_id"),
+		Action:    values.Get("action"),
+		ShortCode: values.Get("short_code"),
+	}
+
+	for name, dst := range map[string]*time.Time{"from": &q.From, "to": &q.To} {
+		value := values.Get(name)
+
+		if value == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, value)
+
+		if err != nil {
+			return q, errors.New("параметр " + name + " должен быть временем в формате RFC 3339")
+		}
+
+		*dst = t
+	}
+
+	if !q.From.IsZero() && !q.To.IsZero() && !q.From.Before(q.To) {
+		return q, errors.New("параметр from должен быть раньше to")
+	}
+
+	if !export {
+		q.Limit = DefaultAuditLimit
+	}
+
+	if value := values.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+
+		if err != nil || limit <= 0 || (!export && limit > MaxAuditLimit) {
+			return q, errors.New("параметр limit должен быть целым числом от 1 до " + strconv.Itoa(MaxAuditLimit))
+		}
+
+		q.Limit = limit
+	}
+
+	if value := values.Get("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+
+		if err != nil || offset < 0 {
+			return q, errors.New("параметр offset должен быть неотрицательным целым числом")
+		}
+
+		q.Offset = offset
+	}
+
+	return q, nil
+}
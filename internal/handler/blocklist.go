@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/blocklist"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/problem"
+)
+
+// generate:reset
+type BlocklistRuleRequest struct {
+	Type    string `json:"type"`
+	Pattern string `json:"pattern"`
+	Action  string `json:"action,omitempty"`
+}
+
+var interstitial = template.Must(template.New("interstitial").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>Внимание: подозрительная ссылка</title>
+</head>
+<body>
+<h1>Ссылка ведет на подозрительный ресурс</h1>
+<p>Адрес назначения отмечен как потенциально опасный. Переходите, только если доверяете отправителю.</p>
+<p><code>{{.}}</code></p>
+<p><a href="{{.}}" rel="noopener noreferrer nofollow">Все равно перейти</a></p>
+</body>
+</html>
+`))
+
+// writeInterstitial показывает страницу-предупреждение вместо перенаправления.
+func writeInterstitial(w http.ResponseWriter, originalURL string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	interstitial.Execute(w, originalURL)
+}
+
+// APIInternalBlocklistHandler - возвращает действующие правила списка блокировок:
+//
+//	[{"type": "suffix", "pattern": "phishing.example", "action": "block"}, ...]
+//
+// @Tags blocklist
+// @Summary Возвращает правила списка блокировок
+// @ID APIInternalBlocklistHandler
+// @Produce json
+// @Success 200
+// @Failure 403
+// @Router /api/internal/blocklist [GET]
+func (h *Handler) APIInternalBlocklistHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	rules := []blocklist.Rule{}
+
+	if h.Facade.Blocklist != nil {
+		rules = append(rules, h.Facade.Blocklist.Rules()...)
+	}
+
+	json.NewEncoder(w).Encode(rules)
+}
+
+// APIInternalBlocklistAddHandler - добавляет правило или меняет действие существующего:
+//
+//	{"type": "domain|suffix|regex", "pattern": "<шаблон>", "action": "block|warn"}
+//
+// Возвращает ответ http.StatusCreated (201) с сохраненным правилом.
+//
+// @Tags blocklist
+// @Summary Добавляет правило в список блокировок
+// @ID APIInternalBlocklistAddHandler
+// @Accept  json
+// @Produce json
+// @Success 201
+// @Failure 400
+// @Failure 403
+// @Failure 500
+// @Router /api/internal/blocklist [POST]
+func (h *Handler) APIInternalBlocklistAddHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if h.Facade.Blocklist == nil {
+		problem.Error(w, r, http.StatusNotFound, problem.TypeNotFound, "список блокировок отключен")
+		return
+	}
+
+	var req BlocklistRuleRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, r, "Invalid request body")
+		return
+	}
+
+	rule, err := blocklist.NewRule(req.Type, req.Pattern, req.Action)
+
+	if err != nil {
+		badRequest(w, r, err.Error())
+		return
+	}
+
+	if err := h.Facade.Blocklist.Add(rule); err != nil {
+		h.writeError(w, r, fmt.Errorf("ошибка сохранения списка блокировок: %w", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+
+	json.NewEncoder(w).Encode(rule)
+}
+
+// APIInternalBlocklistDeleteHandler - удаляет правило:
+//
+//	{"type": "domain|suffix|regex", "pattern": "<шаблон>"}
+//
+// Возвращает ответ http.StatusNoContent (204) или http.StatusNotFound (404), если правила нет.
+//
+// @Tags blocklist
+// @Summary Удаляет правило из списка блокировок
+// @ID APIInternalBlocklistDeleteHandler
+// @Accept  json
+// @Success 204
+// @Failure 400
+// @Failure 403
+// @Failure 404
+// @Failure 500
+// @Router /api/internal/blocklist [DELETE]
+func (h *Handler) APIInternalBlocklistDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Facade.Blocklist == nil {
+		problem.Error(w, r, http.StatusNotFound, problem.TypeNotFound, "список блокировок отключен")
+		return
+	}
+
+	var req BlocklistRuleRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, r, "Invalid request body")
+		return
+	}
+
+	err := h.Facade.Blocklist.Remove(req.Type, req.Pattern)
+
+	if errors.Is(err, blocklist.ErrRuleNotFound) {
+		problem.Error(w, r, http.StatusNotFound, problem.TypeNotFound, err.Error())
+		return
+	}
+
+	if err != nil {
+		badRequest(w, r, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -1,22 +1,31 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/audit"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/blocklist"
 	"github.com/flash1nho/go-musthave-shortener-tpl/internal/config"
 	"github.com/flash1nho/go-musthave-shortener-tpl/internal/facade"
-	"github.com/flash1nho/go-musthave-shortener-tpl/internal/helpers"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/health"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/metrics"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/quota"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/storage"
 
-	"github.com/jackc/pgerrcode"
-	"github.com/jackc/pgx/v5/pgconn"
 	"go.uber.org/zap"
 )
 
+// pingTimeout ограничивает время проверки доступности хранилища в /ping.
+const pingTimeout = 2 * time.Second
+
 // generate:reset
 type ShortenRequest struct {
 	URL string `json:"url"`
@@ -40,14 +49,27 @@ type BatchShortenResponse struct {
 }
 
 // generate:reset
-type Batch struct {
-	urlMappings map[string]string
+type QuotaCounter struct {
+	Used      int  `json:"used"`
+	Limit     *int `json:"limit"`
+	Remaining *int `json:"remaining"`
+}
+
+// generate:reset
+type QuotaResponse struct {
+	Daily    QuotaCounter `json:"daily"`
+	Total    QuotaCounter `json:"total"`
+	ResetsAt time.Time    `json:"resets_at"`
 }
 
 // generate:reset
 type Handler struct {
 	Facade *facade.Facade
-	log    *zap.Logger
+	// Health выполняет проверки хранилища и приемников аудита для /api/internal/health; nil — без проверок.
+	Health *health.Checker
+	// AuditQueues возвращает состояние очередей аудита; nil — аудит не настроен.
+	AuditQueues func() []audit.QueueStats
+	log         *zap.Logger
 }
 
 func NewHandler(facade *facade.Facade, settings config.SettingsObject) *Handler {
@@ -61,14 +83,14 @@ func (h *Handler) PostURLHandler(w http.ResponseWriter, r *http.Request) {
 	userID, err := h.Facade.GetUserFromContext(r.Context())
 
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		h.writeTextError(w, r, err)
 		return
 	}
 
 	body, err := io.ReadAll(r.Body)
 
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
@@ -82,7 +104,13 @@ func (h *Handler) PostURLHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	result, err := h.Facade.PostURLFacade(r.Context(), userID, originalURL)
-	handleStatusConflict(w, err)
+
+	if err != nil && !errors.Is(err, facade.ErrConflict) {
+		h.writeTextError(w, r, err)
+		return
+	}
+
+	writeCreatedStatus(w, err)
 
 	fmt.Fprintln(w, result)
 }
@@ -95,18 +123,44 @@ func (h *Handler) GetURLHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	URLDetails, err := h.Facade.GetURLFacade(shortURL)
+	URLDetails, err := h.Facade.GetURLFacade(r.Context(), shortURL)
 
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if blocked, ok := blocklist.IsBlocked(err); ok {
+		if blocked.Rule.Action == blocklist.ActionWarn {
+			metrics.Redirect(metrics.RedirectWarned)
+			writeInterstitial(w, URLDetails.OriginalURL)
+			return
+		}
+
+		metrics.Redirect(metrics.RedirectBlocked)
+		http.Error(w, "ссылка заблокирована", http.StatusForbidden)
+		return
+	}
+
+	if errors.Is(err, facade.ErrLinkBlocked) {
+		metrics.Redirect(metrics.RedirectBlocked)
+		h.writeTextError(w, r, err)
+		return
+	}
+
+	if errors.Is(err, facade.ErrNotFound) {
+		metrics.Redirect(metrics.RedirectNotFound)
+		h.writeTextError(w, r, err)
 		return
 	}
 
-	if URLDetails.IsDeleted {
+	if errors.Is(err, facade.ErrDeleted) {
+		metrics.Redirect(metrics.RedirectGone)
 		w.WriteHeader(http.StatusGone)
 		return
 	}
 
+	if err != nil {
+		h.writeTextError(w, r, err)
+		return
+	}
+
+	metrics.Redirect(metrics.RedirectFound)
 	http.Redirect(w, r, URLDetails.OriginalURL, http.StatusTemporaryRedirect)
 }
 
@@ -138,20 +192,30 @@ func (h *Handler) APIShortenPostURLHandler(w http.ResponseWriter, r *http.Reques
 	err := json.NewDecoder(r.Body).Decode(&req)
 
 	if err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		badRequest(w, r, "Invalid request body")
 		return
 	}
 
 	if req.URL == "" {
-		http.Error(w, "body is missing", http.StatusBadRequest)
+		badRequest(w, r, "body is missing")
 		return
 	}
 
-	shortURL := helpers.GenerateShortURL(req.URL)
-	err = h.Facade.Store.Set(r.Context(), shortURL, req.URL, "")
-	handleStatusConflict(w, err)
+	userID, err := h.Facade.GetUserFromContext(r.Context())
+
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
 
-	shortURL, _ = url.JoinPath(h.Facade.BaseURL, shortURL)
+	shortURL, err := h.Facade.PostURLFacade(r.Context(), userID, req.URL)
+
+	if err != nil && !errors.Is(err, facade.ErrConflict) {
+		h.writeError(w, r, err)
+		return
+	}
+
+	writeCreatedStatus(w, err)
 
 	response := ShortenResponse{
 		Result: shortURL,
@@ -161,9 +225,11 @@ func (h *Handler) APIShortenPostURLHandler(w http.ResponseWriter, r *http.Reques
 }
 
 func (h *Handler) Ping(w http.ResponseWriter, r *http.Request) {
-	if h.Facade.Store.Pool == nil {
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-		h.log.Error("ошибка пинга базы данных")
+	ctx, cancel := context.WithTimeout(r.Context(), pingTimeout)
+	defer cancel()
+
+	if err := h.Facade.Store.Ping(ctx); err != nil {
+		h.writeError(w, r, fmt.Errorf("ошибка пинга хранилища: %w", err))
 		return
 	}
 
@@ -209,44 +275,45 @@ func (h *Handler) APIShortenBatchPostURLHandler(w http.ResponseWriter, r *http.R
 	err := json.NewDecoder(r.Body).Decode(&req)
 
 	if err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		badRequest(w, r, "Invalid request body")
 		return
 	}
 
 	if len(req) == 0 {
-		http.Error(w, "body is missing", http.StatusBadRequest)
+		badRequest(w, r, "body is missing")
 		return
 	}
 
-	var response []BatchShortenResponse
+	userID, err := h.Facade.GetUserFromContext(r.Context())
 
-	batch := &Batch{
-		urlMappings: make(map[string]string),
+	if err != nil {
+		h.writeError(w, r, err)
+		return
 	}
 
+	items := make([]facade.BatchShortenItem, 0, len(req))
+
 	for _, item := range req {
-		sURL := helpers.GenerateShortURL(item.OriginalURL)
-		shortURL, err := url.JoinPath(h.Facade.BaseURL, sURL)
+		items = append(items, facade.BatchShortenItem{CorrelationID: item.CorrelationID, OriginalURL: item.OriginalURL})
+	}
 
-		if err != nil {
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-			h.log.Error(fmt.Sprintf("Ошибка батчинга: %v", err))
-			return
-		}
+	result, err := h.Facade.PostBatchURLFacade(r.Context(), userID, items)
 
-		resp := BatchShortenResponse{
-			CorrelationID: item.CorrelationID,
-			ShortURL:      shortURL,
-		}
+	if err != nil && !errors.Is(err, facade.ErrConflict) {
+		h.writeError(w, r, err)
+		return
+	}
 
-		batch.urlMappings[sURL] = item.OriginalURL
+	response := make([]BatchShortenResponse, 0, len(req))
 
-		response = append(response, resp)
+	for _, item := range result {
+		response = append(response, BatchShortenResponse{
+			CorrelationID: item.CorrelationID,
+			ShortURL:      item.ShortURL,
+		})
 	}
 
-	h.Facade.Store.SetBatch(r.Context(), batch.urlMappings)
-
-	w.WriteHeader(http.StatusCreated)
+	writeCreatedStatus(w, err)
 
 	json.NewEncoder(w).Encode(response)
 }
@@ -257,16 +324,14 @@ func (h *Handler) APIUserURLHandler(w http.ResponseWriter, r *http.Request) {
 	userID, err := h.Facade.GetUserFromContext(r.Context())
 
 	if err != nil {
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-		h.log.Error(err.Error())
+		h.writeError(w, r, err)
 		return
 	}
 
 	result, err := h.Facade.APIUserURLFacade(r.Context(), userID)
 
 	if err != nil {
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-		h.log.Error(fmt.Sprintf("Ошибка получения URLs по user_id: %v", err))
+		h.writeError(w, r, fmt.Errorf("ошибка получения URLs по user_id: %w", err))
 		return
 	}
 
@@ -304,8 +369,7 @@ func (h *Handler) APIUserDeleteURLHandler(w http.ResponseWriter, r *http.Request
 	userID, err := h.Facade.GetUserFromContext(r.Context())
 
 	if err != nil {
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-		h.log.Error(err.Error())
+		h.writeError(w, r, err)
 		return
 	}
 
@@ -314,45 +378,175 @@ func (h *Handler) APIUserDeleteURLHandler(w http.ResponseWriter, r *http.Request
 	err = json.NewDecoder(r.Body).Decode(&urls)
 
 	if err != nil {
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
-		h.log.Error(err.Error())
+		badRequest(w, r, "Invalid request body")
 		return
 	}
 
-	err = h.Facade.Store.DeleteBatch(r.Context(), userID, urls)
+	err = h.Facade.DeleteUserURLFacade(r.Context(), userID, urls)
 
 	if err != nil {
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		h.writeError(w, r, fmt.Errorf("ошибка удаления ссылок: %w", err))
 		return
 	}
 
 	w.WriteHeader(http.StatusAccepted)
 }
 
+// MaxStatsTop ограничивает длину списков самых популярных ссылок и доменов в статистике.
+const MaxStatsTop = 100
+
+// APIInternalStats - возвращает статистику для панели эксплуатации. Параметры запроса:
+//
+//	window — окно: длительность Go (6h, 90m) или число суток (7d), по умолчанию 24h, не больше 30d;
+//	top — длина списков самых популярных ссылок и доменов, по умолчанию 10, не больше 100.
+//
+// Ряды series идут по часам для окон до двух суток и по суткам для более длинных, в UTC;
+// текущий неполный час или день входит в окно.
+//
+// @Tags internal
+// @Summary Возвращает статистику сервиса
+// @ID APIInternalStats
+// @Produce json
+// @Success 200
+// @Failure 400
+// @Failure 403
+// @Failure 500
+// @Router /api/internal/stats [GET]
 func (h *Handler) APIInternalStats(w http.ResponseWriter, r *http.Request) {
+	window, top, err := parseStatsQuery(r)
+
+	if err != nil {
+		badRequest(w, r, err.Error())
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 
-	stats, err := h.Facade.Store.GetStats(r.Context())
+	stats, err := h.Facade.StatsFacade(r.Context(), window, top)
 
 	if err != nil {
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		h.writeError(w, r, fmt.Errorf("ошибка получения статистики: %w", err))
 		return
 	}
 
 	json.NewEncoder(w).Encode(stats)
 }
 
-func handleStatusConflict(w http.ResponseWriter, err error) {
-	if err != nil {
-		var pgErr *pgconn.PgError
+// parseStatsQuery разбирает окно и длину списков статистики.
+func parseStatsQuery(r *http.Request) (time.Duration, int, error) {
+	window, top := facade.DefaultStatsWindow, facade.DefaultStatsTop
+
+	if value := r.URL.Query().Get("window"); value != "" {
+		var err error
 
-		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
-			w.WriteHeader(http.StatusConflict)
+		if days, ok := strings.CutSuffix(value, "d"); ok {
+			var n int
+
+			n, err = strconv.Atoi(days)
+			window = time.Duration(n) * 24 * time.Hour
 		} else {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
-			return
+			window, err = time.ParseDuration(value)
 		}
-	} else {
-		w.WriteHeader(http.StatusCreated)
+
+		if err != nil || window < time.Hour || window > storage.StatsRetention {
+			return 0, 0, fmt.Errorf("некорректный window %q: ожидается длительность от 1h до 30d, например 24h или 7d", value)
+		}
+	}
+
+	if value := r.URL.Query().Get("top"); value != "" {
+		n, err := strconv.Atoi(value)
+
+		if err != nil || n < 1 || n > MaxStatsTop {
+			return 0, 0, fmt.Errorf("некорректный top %q: ожидается число от 1 до %d", value, MaxStatsTop)
+		}
+
+		top = n
 	}
+
+	return window, top, nil
+}
+
+// APIUserQuotaHandler - возвращает использование квоты пользователя на создание ссылок:
+//
+//	{
+//	    "daily": {"used": 3, "limit": 100, "remaining": 97},
+//	    "total": {"used": 42, "limit": null, "remaining": null},
+//	    "resets_at": "2025-01-02T00:00:00Z"
+//	}
+//
+// limit и remaining равны null, если квота не ограничена.
+//
+// @Tags quota
+// @Summary Возвращает использование квоты пользователя
+// @Security Auth
+// @ID APIUserQuotaHandler
+// @Produce json
+// @Success 200
+// @Failure 401
+// @Failure 500
+// @Router /api/user/quota [GET]
+func (h *Handler) APIUserQuotaHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := h.Facade.GetUserFromContext(r.Context())
+
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	usage, err := h.Facade.QuotaFacade(r.Context(), userID)
+
+	if err != nil {
+		h.writeError(w, r, fmt.Errorf("ошибка получения квоты: %w", err))
+		return
+	}
+
+	setQuotaHeaders(w, usage)
+	json.NewEncoder(w).Encode(newQuotaResponse(usage))
+}
+
+func newQuotaResponse(usage quota.Usage) QuotaResponse {
+	counter := func(used, limit, remaining int) QuotaCounter {
+		c := QuotaCounter{Used: used}
+
+		if remaining != quota.Unlimited {
+			c.Limit = &limit
+			c.Remaining = &remaining
+		}
+
+		return c
+	}
+
+	return QuotaResponse{
+		Daily:    counter(usage.Daily, usage.Limits.Daily, usage.RemainingDaily()),
+		Total:    counter(usage.Total, usage.Limits.Total, usage.RemainingTotal()),
+		ResetsAt: quota.ResetsAt(time.Now()),
+	}
+}
+
+// setQuotaHeaders сообщает клиенту остаток квоты; для неограниченной квоты заголовок не ставится.
+func setQuotaHeaders(w http.ResponseWriter, usage quota.Usage) {
+	if remaining := usage.Remaining(); remaining != quota.Unlimited {
+		w.Header().Set("X-Quota-Remaining", strconv.Itoa(remaining))
+	}
+}
+
+// setQuotaExceededHeaders дополняет остаток квоты заголовком Retry-After, если квота восстановится со сменой суток.
+func setQuotaExceededHeaders(w http.ResponseWriter, exceeded *quota.ExceededError) {
+	setQuotaHeaders(w, exceeded.Usage)
+
+	if total := exceeded.Usage.RemainingTotal(); total == quota.Unlimited || total >= exceeded.Requested {
+		w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(quota.ResetsAt(time.Now())).Seconds())+1))
+	}
+}
+
+// writeCreatedStatus отвечает 201 для новой ссылки и 409, если ссылка на этот адрес уже существовала.
+func writeCreatedStatus(w http.ResponseWriter, err error) {
+	if errors.Is(err, facade.ErrConflict) {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
 }
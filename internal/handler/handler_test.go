@@ -4,20 +4,29 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/audit"
 	"github.com/flash1nho/go-musthave-shortener-tpl/internal/authenticator"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/blocklist"
 	"github.com/flash1nho/go-musthave-shortener-tpl/internal/config"
 	"github.com/flash1nho/go-musthave-shortener-tpl/internal/facade"
 	"github.com/flash1nho/go-musthave-shortener-tpl/internal/helpers"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/problem"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/quota"
 	"github.com/flash1nho/go-musthave-shortener-tpl/internal/storage"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/webhook"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
 )
 
 type TestData struct {
@@ -46,6 +55,15 @@ func testData(tb testing.TB) (*TestData, error) {
 	return &TestData{h: h, originalURL: originalURL, shortURL: shortURL, userID: userID}, nil
 }
 
+// invalidRequest возвращает ожидаемое тело ответа 400 в формате problem+json.
+func invalidRequest(detail string) string {
+	p := problem.New(http.StatusBadRequest, problem.TypeInvalidRequest, detail)
+	p.Instance = "/"
+	body, _ := json.Marshal(p)
+
+	return string(body)
+}
+
 func TestPostURLHandler(t *testing.T) {
 	data, err := testData(t)
 
@@ -64,6 +82,9 @@ func TestPostURLHandler(t *testing.T) {
 	}{
 		{method: http.MethodPost, status: http.StatusBadRequest, responseBody: "body is missing", requestBody: ""},
 		{method: http.MethodPost, status: http.StatusCreated, responseBody: shortURL, requestBody: data.originalURL},
+		{method: http.MethodPost, status: http.StatusConflict, responseBody: shortURL, requestBody: data.originalURL + "\n"},
+		{method: http.MethodPost, status: http.StatusConflict, responseBody: shortURL, requestBody: "practicum.yandex.ru"},
+		{method: http.MethodPost, status: http.StatusBadRequest, responseBody: "", requestBody: "javascript:alert(1)"},
 	}
 
 	for _, tc := range testCases {
@@ -103,7 +124,7 @@ func TestGetURLHandler(t *testing.T) {
 		path         string
 	}{
 		{method: http.MethodGet, status: http.StatusBadRequest, responseBody: "id parameter is missing", path: "/"},
-		{method: http.MethodGet, status: http.StatusBadRequest, responseBody: "short URL not found", path: "/short_url_not_found"},
+		{method: http.MethodGet, status: http.StatusNotFound, responseBody: "short URL not found", path: "/short_url_not_found"},
 		{method: http.MethodGet, status: http.StatusTemporaryRedirect, responseBody: "", path: "/" + data.shortURL},
 	}
 
@@ -152,8 +173,8 @@ func TestAPIShortenPostURLHandler(t *testing.T) {
 		responseBody string
 		requestBody  string
 	}{
-		{method: http.MethodPost, status: http.StatusBadRequest, responseBody: "Invalid request body", requestBody: ""},
-		{method: http.MethodPost, status: http.StatusBadRequest, responseBody: "body is missing", requestBody: `{"result":""}`},
+		{method: http.MethodPost, status: http.StatusBadRequest, responseBody: invalidRequest("Invalid request body"), requestBody: ""},
+		{method: http.MethodPost, status: http.StatusBadRequest, responseBody: invalidRequest("body is missing"), requestBody: `{"result":""}`},
 		{method: http.MethodPost, status: http.StatusCreated, responseBody: responseBody, requestBody: requestBody},
 	}
 
@@ -201,8 +222,8 @@ func TestAPIShortenBatchPostURLHandler(t *testing.T) {
 		responseBody string
 		requestBody  string
 	}{
-		{method: http.MethodPost, status: http.StatusBadRequest, responseBody: "Invalid request body", requestBody: ""},
-		{method: http.MethodPost, status: http.StatusBadRequest, responseBody: "body is missing", requestBody: "[]"},
+		{method: http.MethodPost, status: http.StatusBadRequest, responseBody: invalidRequest("Invalid request body"), requestBody: ""},
+		{method: http.MethodPost, status: http.StatusBadRequest, responseBody: invalidRequest("body is missing"), requestBody: "[]"},
 		{method: http.MethodPost, status: http.StatusCreated, responseBody: responseBody, requestBody: requestBody},
 	}
 
@@ -287,3 +308,496 @@ func BenchmarkPostURLHandler(b *testing.B) {
 		data.h.PostURLHandler(w, r)
 	}
 }
+
+func TestUserQuota(t *testing.T) {
+	data, err := testData(t)
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	data.h.Facade.Quota = quota.NewManager(quota.NewMemoryStore(), quota.Limits{Daily: 2})
+
+	ctx := context.WithValue(context.Background(), authenticator.GetUserKey(), data.userID)
+
+	// описываем набор данных: обработчик, тело запроса, ожидаемый код ответа, ожидаемый остаток квоты
+	testCases := []struct {
+		name      string
+		handler   http.HandlerFunc
+		body      string
+		status    int
+		remaining string
+	}{
+		{name: "первая ссылка", handler: data.h.APIShortenPostURLHandler, body: `{"url":"https://a.example"}`, status: http.StatusCreated},
+		{name: "пакет сверх квоты", handler: data.h.APIShortenBatchPostURLHandler, body: `[{"correlation_id":"1","original_url":"https://b.example"},{"correlation_id":"2","original_url":"https://c.example"}]`, status: http.StatusTooManyRequests, remaining: "1"},
+		{name: "последняя ссылка", handler: data.h.PostURLHandler, body: "https://d.example", status: http.StatusCreated},
+		{name: "квота исчерпана", handler: data.h.APIShortenPostURLHandler, body: `{"url":"https://e.example"}`, status: http.StatusTooManyRequests, remaining: "0"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body)).WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			tc.handler(w, r)
+
+			assert.Equal(t, tc.status, w.Code, "Код ответа не совпадает с ожидаемым")
+
+			if tc.status == http.StatusTooManyRequests {
+				assert.Equal(t, tc.remaining, w.Header().Get("X-Quota-Remaining"), "Остаток квоты не совпадает с ожидаемым")
+				assert.NotEmpty(t, w.Header().Get("Retry-After"), "Суточная квота восстанавливается, нужен Retry-After")
+			}
+		})
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/user/quota", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	data.h.APIUserQuotaHandler(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code, "Код ответа не совпадает с ожидаемым")
+
+	var response QuotaResponse
+
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Equal(t, 2, response.Daily.Used)
+	assert.Equal(t, 0, *response.Daily.Remaining)
+	assert.Nil(t, response.Total.Limit, "Общая квота не ограничена")
+}
+
+func TestBlocklist(t *testing.T) {
+	data, err := testData(t)
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	list, err := blocklist.Load("", zap.NewNop())
+	assert.NoError(t, err)
+
+	data.h.Facade.Blocklist = list
+	ctx := context.WithValue(context.Background(), authenticator.GetUserKey(), data.userID)
+
+	// ссылки создаются до появления правил, чтобы проверить блокировку при переходе
+	for _, originalURL := range []string{"https://blocked.example/a", "https://warned.example/<b>"} {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(originalURL)).WithContext(ctx)
+		w := httptest.NewRecorder()
+
+		data.h.PostURLHandler(w, r)
+		assert.Equal(t, http.StatusCreated, w.Code, "Код ответа не совпадает с ожидаемым")
+	}
+
+	for _, body := range []string{`{"type":"suffix","pattern":"blocked.example"}`, `{"type":"domain","pattern":"warned.example","action":"warn"}`} {
+		r := httptest.NewRequest(http.MethodPost, "/api/internal/blocklist", strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		data.h.APIInternalBlocklistAddHandler(w, r)
+		assert.Equal(t, http.StatusCreated, w.Code, "Код ответа не совпадает с ожидаемым")
+	}
+
+	// описываем набор данных: метод, путь, тело запроса, ожидаемый код ответа
+	testCases := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		path    string
+		body    string
+		status  int
+	}{
+		{name: "создание заблокированной ссылки", handler: data.h.PostURLHandler, method: http.MethodPost, path: "/", body: "https://www.blocked.example/b", status: http.StatusForbidden},
+		{name: "создание ссылки с предупреждением", handler: data.h.APIShortenPostURLHandler, method: http.MethodPost, path: "/", body: `{"url":"https://warned.example/c"}`, status: http.StatusCreated},
+		{name: "переход по заблокированной ссылке", handler: data.h.GetURLHandler, method: http.MethodGet, path: "/" + helpers.GenerateShortURL("https://blocked.example/a"), status: http.StatusForbidden},
+		{name: "переход по ссылке с предупреждением", handler: data.h.GetURLHandler, method: http.MethodGet, path: "/" + helpers.GenerateShortURL("https://warned.example/%3Cb%3E"), status: http.StatusOK},
+		{name: "некорректное правило", handler: data.h.APIInternalBlocklistAddHandler, method: http.MethodPost, path: "/api/internal/blocklist", body: `{"type":"regex","pattern":"("}`, status: http.StatusBadRequest},
+		{name: "удаление правила", handler: data.h.APIInternalBlocklistDeleteHandler, method: http.MethodDelete, path: "/api/internal/blocklist", body: `{"type":"suffix","pattern":"blocked.example"}`, status: http.StatusNoContent},
+		{name: "удаление отсутствующего правила", handler: data.h.APIInternalBlocklistDeleteHandler, method: http.MethodDelete, path: "/api/internal/blocklist", body: `{"type":"suffix","pattern":"blocked.example"}`, status: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body)).WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			tc.handler(w, r)
+
+			assert.Equal(t, tc.status, w.Code, "Код ответа не совпадает с ожидаемым")
+
+			if tc.status != http.StatusOK {
+				return
+			}
+
+			assert.Contains(t, w.Header().Get("Content-Type"), "text/html", "Ожидается страница-предупреждение")
+			assert.Contains(t, w.Body.String(), `href="https://warned.example/%3Cb%3E"`, "Ссылка на странице должна быть экранирована")
+		})
+	}
+}
+
+// auditLog — журнал аудита в памяти.
+// auditRecorder запоминает события аудита синхронно.
+type auditRecorder struct {
+	mu     sync.Mutex
+	events []audit.Event
+}
+
+func (r *auditRecorder) NotifyAll(_ context.Context, e audit.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, e)
+}
+
+func (r *auditRecorder) actions() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var actions []string
+
+	for _, e := range r.events {
+		actions = append(actions, e.Action)
+	}
+
+	return actions
+}
+
+type auditLog []audit.Event
+
+func (l auditLog) Query(_ context.Context, q audit.Query, fn func(audit.Event) error) error {
+	skip, left := q.Offset, q.Limit
+
+	for _, e := range l {
+		if !q.Match(e) {
+			continue
+		}
+
+		if skip > 0 {
+			skip--
+			continue
+		}
+
+		if err := fn(e); err != nil {
+			return err
+		}
+
+		if left--; left == 0 {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func TestAPIInternalAuditHandler(t *testing.T) {
+	data, err := testData(t)
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/internal/audit", nil)
+	w := httptest.NewRecorder()
+
+	data.h.APIInternalAuditHandler(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Code, "Без журнала аудита ожидается 404")
+
+	var log auditLog
+
+	for i := range 5 {
+		log = append(log, audit.Event{Timestamp: int64(1000 + i), Action: audit.ActionShorten, UserID: "u" + strconv.Itoa(i%2)})
+	}
+
+	data.h.Facade.AuditLog = log
+
+	// описываем набор данных: строка запроса, ожидаемый код ответа, события и следующая страница
+	testCases := []struct {
+		name       string
+		query      string
+		status     int
+		events     []int64
+		nextOffset *int
+	}{
+		{name: "первая страница", query: "?limit=2", status: http.StatusOK, events: []int64{1000, 1001}, nextOffset: intPtr(2)},
+		{name: "последняя страница", query: "?limit=2&offset=4", status: http.StatusOK, events: []int64{1004}},
+		{name: "фильтр по пользователю", query: "?user_id=u1", status: http.StatusOK, events: []int64{1001, 1003}},
+		{name: "фильтр по времени", query: "?from=1970-01-01T00:16:41Z&to=1970-01-01T00:16:43Z", status: http.StatusOK, events: []int64{1001, 1002}},
+		{name: "пустая выборка", query: "?action=delete", status: http.StatusOK, events: []int64{}},
+		{name: "некорректное время", query: "?from=yesterday", status: http.StatusBadRequest},
+		{name: "пустой интервал", query: "?from=1970-01-01T00:16:43Z&to=1970-01-01T00:16:41Z", status: http.StatusBadRequest},
+		{name: "слишком большая страница", query: "?limit=1001", status: http.StatusBadRequest},
+		{name: "отрицательный offset", query: "?offset=-1", status: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/internal/audit"+tc.query, nil)
+			w := httptest.NewRecorder()
+
+			data.h.APIInternalAuditHandler(w, r)
+
+			assert.Equal(t, tc.status, w.Code, "Код ответа не совпадает с ожидаемым")
+
+			if tc.status != http.StatusOK {
+				return
+			}
+
+			var resp AuditEventsResponse
+
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp), "Ответ не разбирается")
+
+			events := []int64{}
+
+			for _, e := range resp.Events {
+				events = append(events, e.Timestamp)
+			}
+
+			assert.Equal(t, tc.events, events, "События не совпадают с ожидаемыми")
+			assert.Equal(t, tc.nextOffset, resp.NextOffset, "Следующая страница не совпадает с ожидаемой")
+		})
+	}
+
+	t.Run("выгрузка", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/api/internal/audit?format=ndjson&user_id=u0", nil)
+		w := httptest.NewRecorder()
+
+		data.h.APIInternalAuditHandler(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code, "Код ответа не совпадает с ожидаемым")
+		assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"), "Тип содержимого не совпадает с ожидаемым")
+
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		assert.Len(t, lines, 3, "Выгрузка должна содержать все подходящие события без ограничения страницы")
+		assert.Empty(t, w.Header().Get("X-Audit-Filter"), "Полный журнал не должен сообщать фильтр")
+	})
+
+	t.Run("журнал с фильтром", func(t *testing.T) {
+		data.h.Facade.AuditLogFilter = audit.Filter{Actions: []string{audit.ActionShorten}, Statuses: []string{audit.StatusFailure}}
+
+		r := httptest.NewRequest(http.MethodGet, "/api/internal/audit", nil)
+		w := httptest.NewRecorder()
+
+		data.h.APIInternalAuditHandler(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code, "Код ответа не совпадает с ожидаемым")
+		assert.Equal(t, "actions=shorten; statuses=failure", w.Header().Get("X-Audit-Filter"), "Заголовок фильтра не совпадает с ожидаемым")
+
+		var resp AuditEventsResponse
+
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp), "Ответ не разбирается")
+		assert.Equal(t, &data.h.Facade.AuditLogFilter, resp.Filter, "Фильтр журнала должен возвращаться в ответе")
+	})
+
+	t.Run("чтение журнала в аудите", func(t *testing.T) {
+		events := &auditRecorder{}
+		data.h.Facade.Audit = events
+
+		r := httptest.NewRequest(http.MethodGet, "/api/internal/audit?user_id=u1&short_code=abc", nil)
+		w := httptest.NewRecorder()
+
+		data.h.APIInternalAuditHandler(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code, "Код ответа не совпадает с ожидаемым")
+		if !assert.Len(t, events.events, 1, "Чтение журнала должно попадать в аудит") {
+			return
+		}
+
+		assert.Equal(t, audit.ActionAuditQuery, events.events[0].Action)
+		assert.Equal(t, "u1", events.events[0].UserID, "Условие выборки по пользователю должно попадать в событие")
+		assert.Equal(t, "abc", events.events[0].ShortCode, "Условие выборки по ссылке должно попадать в событие")
+	})
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+// exampleResolver разрешает любое имя в публичный адрес, без обращения к сети.
+type exampleResolver struct{}
+
+func (exampleResolver) LookupIPAddr(context.Context, string) ([]net.IPAddr, error) {
+	return []net.IPAddr{{IP: net.ParseIP("93.184.216.34")}}, nil
+}
+
+func TestWebhookHandlers(t *testing.T) {
+	data, err := testData(t)
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	data.h.Facade.Webhooks = webhook.NewManager(webhook.NewMemoryStore(), webhook.Options{Resolver: exampleResolver{}}, zap.NewNop())
+	ctx := context.WithValue(context.Background(), authenticator.GetUserKey(), data.userID)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/user/webhooks", strings.NewReader(`{"url":"https://example.com/hook","events":["created"]}`)).WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	data.h.APIUserWebhookCreateHandler(w, r)
+	assert.Equal(t, http.StatusCreated, w.Code, "Код ответа не совпадает с ожидаемым")
+
+	var hook webhook.Webhook
+
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &hook), "Ответ не разбирается")
+	assert.NotEmpty(t, hook.Secret, "Секрет должен возвращаться при создании")
+
+	// описываем набор данных: обработчик, метод, путь, параметр пути, тело запроса, ожидаемый код ответа
+	testCases := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		id      string
+		body    string
+		status  int
+	}{
+		{name: "некорректный адрес", handler: data.h.APIUserWebhookCreateHandler, method: http.MethodPost, body: `{"url":"example.com","events":["created"]}`, status: http.StatusBadRequest},
+		{name: "неизвестное событие", handler: data.h.APIUserWebhookCreateHandler, method: http.MethodPost, body: `{"url":"https://example.com","events":["viewed"]}`, status: http.StatusBadRequest},
+		{name: "адрес метаданных облака", handler: data.h.APIUserWebhookCreateHandler, method: http.MethodPost, body: `{"url":"http://169.254.169.254/latest/meta-data","events":["created"]}`, status: http.StatusBadRequest},
+		{name: "список", handler: data.h.APIUserWebhooksHandler, method: http.MethodGet, status: http.StatusOK},
+		{name: "недоставленные события", handler: data.h.APIUserWebhookDeadLettersHandler, method: http.MethodGet, status: http.StatusOK},
+		{name: "повтор отсутствующей доставки", handler: data.h.APIUserWebhookRedeliverHandler, method: http.MethodPost, id: "missing", status: http.StatusNotFound},
+		{name: "удаление", handler: data.h.APIUserWebhookDeleteHandler, method: http.MethodDelete, id: hook.ID, status: http.StatusNoContent},
+		{name: "повторное удаление", handler: data.h.APIUserWebhookDeleteHandler, method: http.MethodDelete, id: hook.ID, status: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tc.id)
+
+			r := httptest.NewRequest(tc.method, "/api/user/webhooks", strings.NewReader(tc.body)).
+				WithContext(context.WithValue(ctx, chi.RouteCtxKey, rctx))
+			w := httptest.NewRecorder()
+
+			tc.handler(w, r)
+
+			assert.Equal(t, tc.status, w.Code, "Код ответа не совпадает с ожидаемым")
+		})
+	}
+}
+
+func TestAdminHandlers(t *testing.T) {
+	data, err := testData(t)
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	ctx := context.WithValue(context.Background(), authenticator.GetUserKey(), data.userID)
+
+	_, err = data.h.Facade.PostURLFacade(ctx, data.userID, data.originalURL)
+	assert.NoError(t, err)
+
+	events := &auditRecorder{}
+	data.h.Facade.Audit = events
+
+	// описываем набор данных: обработчик, параметр пути, ожидаемый код ответа
+	testCases := []struct {
+		name    string
+		handler http.HandlerFunc
+		param   string
+		value   string
+		status  int
+	}{
+		{name: "поиск ссылки", handler: data.h.APIInternalLinkHandler, param: "code", value: data.shortURL, status: http.StatusOK},
+		{name: "поиск отсутствующей ссылки", handler: data.h.APIInternalLinkHandler, param: "code", value: "missing", status: http.StatusNotFound},
+		{name: "блокировка", handler: data.h.APIInternalLinkBlockHandler, param: "code", value: data.shortURL, status: http.StatusOK},
+		{name: "блокировка отсутствующей ссылки", handler: data.h.APIInternalLinkBlockHandler, param: "code", value: "missing", status: http.StatusNotFound},
+		{name: "отключение пользователя", handler: data.h.APIInternalUserDisableHandler, param: "id", value: data.userID, status: http.StatusOK},
+		{name: "пользователь", handler: data.h.APIInternalUserHandler, param: "id", value: data.userID, status: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add(tc.param, tc.value)
+
+			r := httptest.NewRequest(http.MethodPost, "/api/internal", nil).
+				WithContext(context.WithValue(context.Background(), chi.RouteCtxKey, rctx))
+			w := httptest.NewRecorder()
+
+			tc.handler(w, r)
+
+			assert.Equal(t, tc.status, w.Code, "Код ответа не совпадает с ожидаемым")
+		})
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/"+data.shortURL, nil)
+	w := httptest.NewRecorder()
+
+	data.h.GetURLHandler(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Code, "Переход по заблокированной ссылке должен отвечать 403")
+
+	r = httptest.NewRequest(http.MethodGet, "/api/user/urls", nil).WithContext(ctx)
+	w = httptest.NewRecorder()
+
+	data.h.APIUserURLHandler(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Code, "Отключенный пользователь должен получать 403")
+
+	data.h.Facade.Webhooks = webhook.NewManager(webhook.NewMemoryStore(), webhook.Options{}, zap.NewNop())
+
+	r = httptest.NewRequest(http.MethodGet, "/api/internal/health", nil)
+	w = httptest.NewRecorder()
+
+	data.h.APIInternalHealthHandler(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, "Код ответа не совпадает с ожидаемым")
+
+	var report AdminHealthResponse
+
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report), "Ответ не разбирается")
+	assert.Equal(t, "ok", report.Status, "Состояние сервиса не совпадает с ожидаемым")
+	assert.Equal(t, &webhook.QueueStats{}, report.Webhooks, "Очередь вебхуков должна быть пустой")
+
+	assert.Equal(t, []string{
+		audit.ActionViewLink, audit.ActionViewLink, audit.ActionBlock, audit.ActionBlock, audit.ActionDisableUser,
+		audit.ActionViewUser, audit.ActionFollow, audit.ActionViewHealth,
+	}, events.actions(), "Чтения оператора должны попадать в аудит")
+}
+
+func TestAPIInternalStats(t *testing.T) {
+	data, err := testData(t)
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	_, err = data.h.Facade.PostURLFacade(context.Background(), data.userID, data.originalURL)
+	assert.NoError(t, err)
+
+	// описываем набор данных: строка запроса, ожидаемый код ответа, шаг и число точек ряда
+	testCases := []struct {
+		name   string
+		query  string
+		status int
+		bucket string
+		points int
+	}{
+		{name: "окно по умолчанию", query: "", status: http.StatusOK, bucket: "hour", points: 24},
+		{name: "окно в часах", query: "?window=6h&top=5", status: http.StatusOK, bucket: "hour", points: 6},
+		{name: "окно в сутках", query: "?window=7d", status: http.StatusOK, bucket: "day", points: 7},
+		{name: "некорректное окно", query: "?window=week", status: http.StatusBadRequest},
+		{name: "слишком короткое окно", query: "?window=30m", status: http.StatusBadRequest},
+		{name: "слишком длинное окно", query: "?window=31d", status: http.StatusBadRequest},
+		{name: "некорректный top", query: "?top=0", status: http.StatusBadRequest},
+		{name: "слишком большой top", query: "?top=101", status: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/internal/stats"+tc.query, nil)
+			w := httptest.NewRecorder()
+
+			data.h.APIInternalStats(w, r)
+
+			assert.Equal(t, tc.status, w.Code, "Код ответа не совпадает с ожидаемым")
+
+			if tc.status != http.StatusOK {
+				return
+			}
+
+			var stats storage.Stats
+
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats), "Ответ не разбирается")
+			assert.Equal(t, 1, stats.URLs, "Число ссылок не совпадает с ожидаемым")
+			assert.Equal(t, 1, stats.ActiveUsers, "Число активных пользователей не совпадает с ожидаемым")
+			assert.Equal(t, tc.bucket, stats.Bucket, "Шаг рядов не совпадает с ожидаемым")
+			assert.Len(t, stats.Series, tc.points, "Число точек ряда не совпадает с ожидаемым")
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/facade"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/logger"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/problem"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/webhook"
+
+	"go.uber.org/zap"
+)
+
+// problemFor сопоставляет ошибку фасада проблеме RFC 7807. Для неизвестных ошибок возвращается
+// внутренняя ошибка без подробностей и false: текст такой ошибки клиенту не показывается.
+func problemFor(err error) (problem.Problem, bool) {
+	var (
+		exceeded *facade.QuotaExceededError
+		blocked  *facade.BlockedError
+	)
+
+	switch {
+	case errors.As(err, &exceeded):
+		return problem.New(http.StatusTooManyRequests, problem.TypeQuotaExceeded, exceeded.Error()).
+			With("quota", newQuotaResponse(exceeded.Usage)), true
+	case errors.As(err, &blocked):
+		return problem.New(http.StatusForbidden, problem.TypeBlocked, err.Error()), true
+	case errors.Is(err, facade.ErrInvalidURL):
+		return problem.New(http.StatusBadRequest, problem.TypeInvalidURL, err.Error()), true
+	case errors.Is(err, facade.ErrNotFound):
+		return problem.New(http.StatusNotFound, problem.TypeNotFound, err.Error()), true
+	case errors.Is(err, facade.ErrLinkBlocked):
+		return problem.New(http.StatusForbidden, problem.TypeBlocked, err.Error()), true
+	case errors.Is(err, facade.ErrUserDisabled):
+		return problem.New(http.StatusForbidden, problem.TypeForbidden, err.Error()), true
+	case errors.Is(err, facade.ErrConflict):
+		return problem.New(http.StatusConflict, problem.TypeConflict, facade.ErrConflict.Error()), true
+	case errors.Is(err, webhook.ErrInvalid):
+		return problem.New(http.StatusBadRequest, problem.TypeInvalidRequest, err.Error()), true
+	case errors.Is(err, webhook.ErrNotFound):
+		return problem.New(http.StatusNotFound, problem.TypeNotFound, err.Error()), true
+	}
+
+	return problem.New(http.StatusInternalServerError, problem.TypeInternal, ""), false
+}
+
+// writeError отвечает проблемой, соответствующей ошибке фасада. Внутренняя ошибка пишется в лог,
+// а клиент получает request_id, по которому ее можно найти.
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	p, known := problemFor(err)
+
+	if !known {
+		logger.WithContext(r.Context(), h.log).Error("Внутренняя ошибка обработки запроса", zap.String("path", r.URL.Path), zap.Error(err))
+	}
+
+	var exceeded *facade.QuotaExceededError
+
+	if errors.As(err, &exceeded) {
+		setQuotaExceededHeaders(w, exceeded)
+	}
+
+	problem.Write(w, r, p)
+}
+
+// writeTextError — writeError для маршрутов с текстовым телом ответа: код ответа тот же,
+// а вместо объекта проблемы передается только detail.
+func (h *Handler) writeTextError(w http.ResponseWriter, r *http.Request, err error) {
+	p, known := problemFor(err)
+
+	if !known {
+		logger.WithContext(r.Context(), h.log).Error("Внутренняя ошибка обработки запроса", zap.String("path", r.URL.Path), zap.Error(err))
+		p.Detail = http.StatusText(p.Status)
+	}
+
+	var exceeded *facade.QuotaExceededError
+
+	if errors.As(err, &exceeded) {
+		setQuotaExceededHeaders(w, exceeded)
+	}
+
+	http.Error(w, p.Detail, p.Status)
+}
+
+// badRequest отвечает 400 для тела запроса, которое не удалось разобрать или которое не заполнено.
+func badRequest(w http.ResponseWriter, r *http.Request, detail string) {
+	problem.Error(w, r, http.StatusBadRequest, problem.TypeInvalidRequest, detail)
+}
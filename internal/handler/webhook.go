@@ -0,0 +1,221 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/problem"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/webhook"
+)
+
+// generate:reset
+type WebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+// webhooksEnabled отвечает 404, если вебхуки отключены.
+func (h *Handler) webhooksEnabled(w http.ResponseWriter, r *http.Request) bool {
+	if h.Facade.Webhooks == nil {
+		problem.Error(w, r, http.StatusNotFound, problem.TypeNotFound, "вебхуки отключены")
+		return false
+	}
+
+	return true
+}
+
+// APIUserWebhookCreateHandler - регистрирует вебхук пользователя:
+//
+//	{"url": "https://example.com/hook", "events": ["created", "clicked", "deleted", "expired"]}
+//
+// Возвращает ответ http.StatusCreated (201) с вебхуком и секретом подписи. Секрет показывается
+// только в этом ответе: получатель проверяет им заголовок X-Webhook-Signature.
+// Адреса во внутренней сети отклоняются с http.StatusBadRequest (400).
+//
+// @Tags webhooks
+// @Summary Регистрирует вебхук
+// @Security Auth
+// @ID APIUserWebhookCreateHandler
+// @Accept  json
+// @Produce json
+// @Success 201
+// @Failure 400
+// @Failure 401
+// @Failure 500
+// @Router /api/user/webhooks [POST]
+func (h *Handler) APIUserWebhookCreateHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.webhooksEnabled(w, r) {
+		return
+	}
+
+	userID, err := h.Facade.GetUserFromContext(r.Context())
+
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	var req WebhookRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, r, "Invalid request body")
+		return
+	}
+
+	hook, err := h.Facade.Webhooks.Create(r.Context(), userID, req.URL, req.Events)
+
+	if err != nil {
+		h.writeError(w, r, fmt.Errorf("ошибка создания вебхука: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	json.NewEncoder(w).Encode(hook)
+}
+
+// APIUserWebhooksHandler - возвращает вебхуки пользователя без секретов.
+//
+// @Tags webhooks
+// @Summary Возвращает вебхуки пользователя
+// @Security Auth
+// @ID APIUserWebhooksHandler
+// @Produce json
+// @Success 200
+// @Failure 401
+// @Failure 500
+// @Router /api/user/webhooks [GET]
+func (h *Handler) APIUserWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.webhooksEnabled(w, r) {
+		return
+	}
+
+	userID, err := h.Facade.GetUserFromContext(r.Context())
+
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	hooks, err := h.Facade.Webhooks.List(r.Context(), userID)
+
+	if err != nil {
+		h.writeError(w, r, fmt.Errorf("ошибка получения вебхуков: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	json.NewEncoder(w).Encode(append([]webhook.Webhook{}, hooks...))
+}
+
+// APIUserWebhookDeleteHandler - удаляет вебхук пользователя вместе с недоставленными событиями.
+// Возвращает ответ http.StatusNoContent (204) или http.StatusNotFound (404).
+//
+// @Tags webhooks
+// @Summary Удаляет вебхук
+// @Security Auth
+// @ID APIUserWebhookDeleteHandler
+// @Param id path string true "Идентификатор вебхука"
+// @Success 204
+// @Failure 401
+// @Failure 404
+// @Failure 500
+// @Router /api/user/webhooks/{id} [DELETE]
+func (h *Handler) APIUserWebhookDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.webhooksEnabled(w, r) {
+		return
+	}
+
+	userID, err := h.Facade.GetUserFromContext(r.Context())
+
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	if err := h.Facade.Webhooks.Delete(r.Context(), userID, chi.URLParam(r, "id")); err != nil {
+		h.writeError(w, r, fmt.Errorf("ошибка удаления вебхука: %w", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// APIUserWebhookDeadLettersHandler - возвращает доставки, попытки которых исчерпаны, новые первыми.
+//
+// @Tags webhooks
+// @Summary Возвращает недоставленные события
+// @Security Auth
+// @ID APIUserWebhookDeadLettersHandler
+// @Produce json
+// @Success 200
+// @Failure 401
+// @Failure 500
+// @Router /api/user/webhooks/dead-letters [GET]
+func (h *Handler) APIUserWebhookDeadLettersHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.webhooksEnabled(w, r) {
+		return
+	}
+
+	userID, err := h.Facade.GetUserFromContext(r.Context())
+
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	deliveries, err := h.Facade.Webhooks.DeadLetters(r.Context(), userID)
+
+	if err != nil {
+		h.writeError(w, r, fmt.Errorf("ошибка получения недоставленных событий: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	json.NewEncoder(w).Encode(append([]webhook.Delivery{}, deliveries...))
+}
+
+// APIUserWebhookRedeliverHandler - ставит доставку в очередь заново с полным набором попыток.
+// Возвращает ответ http.StatusAccepted (202) с доставкой или http.StatusNotFound (404).
+//
+// @Tags webhooks
+// @Summary Повторяет доставку события
+// @Security Auth
+// @ID APIUserWebhookRedeliverHandler
+// @Param id path string true "Идентификатор доставки"
+// @Produce json
+// @Success 202
+// @Failure 401
+// @Failure 404
+// @Failure 500
+// @Router /api/user/webhooks/deliveries/{id}/redeliver [POST]
+func (h *Handler) APIUserWebhookRedeliverHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.webhooksEnabled(w, r) {
+		return
+	}
+
+	userID, err := h.Facade.GetUserFromContext(r.Context())
+
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	delivery, err := h.Facade.Webhooks.Redeliver(r.Context(), userID, chi.URLParam(r, "id"))
+
+	if err != nil {
+		h.writeError(w, r, fmt.Errorf("ошибка повторной доставки: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+
+	json.NewEncoder(w).Encode(delivery)
+}
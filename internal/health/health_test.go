@@ -0,0 +1,96 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadiness(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	failing := func(context.Context) error { return errors.New("connection refused") }
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	tests := []struct {
+		name         string
+		checks       map[string]CheckFunc
+		shuttingDown bool
+		wantCode     int
+		wantFailed   []string
+	}{
+		{
+			name:     "все зависимости доступны",
+			checks:   map[string]CheckFunc{"storage": ok, "audit_file": ok},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:       "хранилище недоступно",
+			checks:     map[string]CheckFunc{"storage": failing, "audit_file": ok},
+			wantCode:   http.StatusServiceUnavailable,
+			wantFailed: []string{"storage"},
+		},
+		{
+			name:       "проверка превысила таймаут",
+			checks:     map[string]CheckFunc{"storage": slow},
+			wantCode:   http.StatusServiceUnavailable,
+			wantFailed: []string{"storage"},
+		},
+		{
+			name:         "сервис останавливается",
+			checks:       map[string]CheckFunc{"storage": ok},
+			shuttingDown: true,
+			wantCode:     http.StatusServiceUnavailable,
+			wantFailed:   []string{"shutdown"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := NewChecker(50 * time.Millisecond)
+
+			for name, fn := range test.checks {
+				c.Register(name, fn)
+			}
+
+			if test.shuttingDown {
+				c.SetShuttingDown()
+			}
+
+			w := httptest.NewRecorder()
+			c.Readiness(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			assert.Equal(t, test.wantCode, w.Code, "Код ответа не совпадает с ожидаемым")
+
+			var report Report
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+
+			assert.Len(t, report.Checks, len(test.checks)+1, "В ответе должны быть все проверки")
+
+			for _, name := range test.wantFailed {
+				assert.Equal(t, StatusFail, report.Checks[name].Status, "Проверка %s должна завершиться ошибкой", name)
+				assert.NotEmpty(t, report.Checks[name].Error, "Для проверки %s не указана ошибка", name)
+			}
+		})
+	}
+}
+
+func TestLiveness(t *testing.T) {
+	c := NewChecker(0)
+	c.Register("storage", func(context.Context) error { return errors.New("down") })
+
+	w := httptest.NewRecorder()
+	c.Liveness(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code, "Код ответа не совпадает с ожидаемым")
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+}
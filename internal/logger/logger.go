@@ -1,12 +1,24 @@
 package logger
 
 import (
+	"context"
+
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	DefaultLevel    = "info"
+	DefaultEncoding = "json"
 )
 
+type requestIDKey struct{}
+
 var Log *zap.Logger = zap.NewNop()
 
-func Initialize(level string) error {
+// Initialize настраивает глобальный логгер.
+// encoding — json (по умолчанию) или console.
+func Initialize(level string, encoding string) error {
 	lvl, err := zap.ParseAtomicLevel(level)
 	if err != nil {
 		return err
@@ -15,6 +27,15 @@ func Initialize(level string) error {
 	cfg := zap.NewProductionConfig()
 	cfg.Level = lvl
 
+	if encoding != "" {
+		cfg.Encoding = encoding
+	}
+
+	if cfg.Encoding == "console" {
+		cfg.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+		cfg.EncoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+	}
+
 	zl, err := cfg.Build()
 	if err != nil {
 		return err
@@ -23,3 +44,29 @@ func Initialize(level string) error {
 	Log = zl
 	return nil
 }
+
+// ContextWithRequestID сохраняет идентификатор запроса в контексте.
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext возвращает идентификатор запроса, сохраненный в контексте.
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+
+	return requestID
+}
+
+// WithContext дополняет логгер идентификатором запроса из контекста.
+// Если l не задан, используется глобальный Log.
+func WithContext(ctx context.Context, l *zap.Logger) *zap.Logger {
+	if l == nil {
+		l = Log
+	}
+
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		return l.With(zap.String("request_id", requestID))
+	}
+
+	return l
+}
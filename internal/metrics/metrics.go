@@ -0,0 +1,230 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+const namespace = "shortener"
+
+// Registry — реестр метрик сервиса, отдаваемый на /metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Количество HTTP-запросов по шаблону маршрута chi.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Время обработки HTTP-запросов по шаблону маршрута chi.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	grpcRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_requests_total",
+		Help:      "Количество gRPC-вызовов по методу и коду ответа.",
+	}, []string{"method", "code"})
+
+	grpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_request_duration_seconds",
+		Help:      "Время обработки gRPC-вызовов по методу.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	redirects = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "redirects_total",
+		Help:      "Количество переходов по коротким ссылкам по результату.",
+	}, []string{"result"})
+
+	storageDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "storage_operation_duration_seconds",
+		Help:      "Время выполнения операций хранилища по методу Storage.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	auditQueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "audit_queue_depth",
+		Help:      "Количество событий аудита, ожидающих доставки, по приемнику.",
+	}, []string{"sink"})
+
+	auditFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "audit_failures_total",
+		Help:      "Количество событий аудита, которые не удалось доставить, по приемнику.",
+	}, []string{"sink"})
+
+	auditDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "audit_dropped_total",
+		Help:      "Количество событий аудита, отброшенных из-за переполнения очереди, по приемнику.",
+	}, []string{"sink"})
+
+	auditBatches = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "audit_batch_size",
+		Help:      "Размер пачек событий аудита, переданных приемнику.",
+		Buckets:   prometheus.ExponentialBuckets(1, 4, 6),
+	}, []string{"sink"})
+
+	webhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Количество попыток доставки вебхуков по итогу: delivered, retry или dead.",
+	}, []string{"result"})
+
+	rateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "Количество запросов, отклоненных ограничителем частоты, по классу маршрутов.",
+	}, []string{"class"})
+
+	buildInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "build_info",
+		Help:      "Информация о сборке сервиса.",
+	}, []string{"version", "commit", "date"})
+)
+
+// Результаты перехода по короткой ссылке.
+const (
+	RedirectFound    = "found"
+	RedirectGone     = "gone"
+	RedirectNotFound = "not_found"
+	RedirectBlocked  = "blocked"
+	RedirectWarned   = "warned"
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration,
+		grpcRequests, grpcDuration,
+		redirects, storageDuration,
+		auditQueueDepth, auditFailures, auditDropped, auditBatches,
+		webhookDeliveries,
+		rateLimited,
+		buildInfo,
+	)
+}
+
+// Handler отдает метрики в формате Prometheus.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// SetBuildInfo публикует версию, коммит и дату сборки.
+func SetBuildInfo(version, commit, date string) {
+	buildInfo.WithLabelValues(version, commit, date).Set(1)
+}
+
+// RegisterPool публикует статистику пула соединений pgx.
+func RegisterPool(pool *pgxpool.Pool) {
+	if pool == nil {
+		return
+	}
+
+	Registry.MustRegister(newPoolCollector(pool))
+}
+
+// Middleware считает HTTP-запросы и время их обработки.
+// Маршрут определяется по шаблону chi, чтобы не плодить метки по каждому идентификатору ссылки.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+
+		code := ww.Status()
+
+		if code == 0 {
+			code = http.StatusOK
+		}
+
+		httpRequests.WithLabelValues(r.Method, route, strconv.Itoa(code)).Inc()
+		httpDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// UnaryServerInterceptor считает gRPC-вызовы и время их обработки.
+func UnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+
+	grpcRequests.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+	grpcDuration.WithLabelValues(info.FullMethod).Observe(time.Since(start).Seconds())
+
+	return resp, err
+}
+
+// Redirect учитывает переход по короткой ссылке.
+func Redirect(result string) {
+	redirects.WithLabelValues(result).Inc()
+}
+
+// ObserveStorage фиксирует длительность операции хранилища, начатой в start.
+//
+//	defer metrics.ObserveStorage("Set", time.Now())
+func ObserveStorage(operation string, start time.Time) {
+	storageDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// AuditEnqueued, AuditDone и AuditFailed отслеживают количество событий аудита в очереди приемника sink.
+func AuditEnqueued(sink string) {
+	auditQueueDepth.WithLabelValues(sink).Inc()
+}
+
+// AuditDone учитывает пачку из n событий, доставленную в приемник sink.
+func AuditDone(sink string, n int) {
+	auditQueueDepth.WithLabelValues(sink).Sub(float64(n))
+	auditBatches.WithLabelValues(sink).Observe(float64(n))
+}
+
+// AuditFailed учитывает n событий, которые не удалось доставить в приемник sink.
+// Они покидают очередь, но в размер доставленных пачек не попадают.
+func AuditFailed(sink string, n int) {
+	auditQueueDepth.WithLabelValues(sink).Sub(float64(n))
+	auditFailures.WithLabelValues(sink).Add(float64(n))
+}
+
+// AuditDropped учитывает событие, отброшенное из-за переполнения очереди приемника sink.
+func AuditDropped(sink string) {
+	auditDropped.WithLabelValues(sink).Inc()
+}
+
+// WebhookDelivery учитывает попытку доставки вебхука с итогом result.
+func WebhookDelivery(result string) {
+	webhookDeliveries.WithLabelValues(result).Inc()
+}
+
+// RateLimited учитывает запрос, отклоненный ограничителем частоты для класса маршрутов class.
+func RateLimited(class string) {
+	rateLimited.WithLabelValues(class).Inc()
+}
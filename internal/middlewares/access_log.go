@@ -0,0 +1,95 @@
+package middlewares
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/clientip"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/logger"
+)
+
+// RequestIDHeader — заголовок, в котором идентификатор запроса принимается от клиента и возвращается в ответе.
+const RequestIDHeader = "X-Request-ID"
+
+type accessLogKey struct{}
+
+// accessLogEntry накапливает сведения, известные только внутренним обработчикам (например, userID после Auth).
+type accessLogEntry struct {
+	userID string
+}
+
+// RequestID кладет идентификатор запроса в контекст, чтобы он попадал во все последующие логи.
+// Идентификатор берется из заголовка X-Request-ID или генерируется.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+
+		if requestID == "" || len(requestID) > 128 {
+			requestID = uuid.NewString()
+		}
+
+		w.Header().Set(RequestIDHeader, requestID)
+
+		next.ServeHTTP(w, r.WithContext(logger.ContextWithRequestID(r.Context(), requestID)))
+	})
+}
+
+// AccessLog пишет структурированную строку лога на каждый HTTP-запрос.
+func AccessLog(log *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			entry := &accessLogEntry{}
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			next.ServeHTTP(ww, r.WithContext(context.WithValue(r.Context(), accessLogKey{}, entry)))
+
+			route := r.URL.Path
+
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+
+			code := ww.Status()
+
+			if code == 0 {
+				code = http.StatusOK
+			}
+
+			// Адрес, определенный clientip.Resolver, учитывает доверенные прокси; без него пишется адрес соединения.
+			remoteIP, _, err := net.SplitHostPort(r.RemoteAddr)
+
+			if err != nil {
+				remoteIP = r.RemoteAddr
+			}
+
+			if ip := clientip.IP(r.Context()); ip != nil {
+				remoteIP = ip.String()
+			}
+
+			logger.WithContext(r.Context(), log).Info("HTTP-запрос",
+				zap.String("method", r.Method),
+				zap.String("route", route),
+				zap.Int("status", code),
+				zap.Int("bytes", ww.BytesWritten()),
+				zap.Duration("latency", time.Since(start)),
+				zap.String("user_id", entry.userID),
+				zap.String("remote_ip", remoteIP),
+			)
+		})
+	}
+}
+
+// setAccessLogUserID передает userID в строку лога доступа, если она ведется для запроса.
+func setAccessLogUserID(ctx context.Context, userID string) {
+	if entry, ok := ctx.Value(accessLogKey{}).(*accessLogEntry); ok {
+		entry.userID = userID
+	}
+}
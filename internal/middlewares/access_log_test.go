@@ -0,0 +1,63 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestAccessLog(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+
+	r := chi.NewRouter()
+	r.Use(RequestID)
+	r.Use(AccessLog(zap.New(core)))
+	r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+		setAccessLogUserID(r.Context(), "user-1")
+		w.WriteHeader(http.StatusTemporaryRedirect)
+	})
+
+	tests := []struct {
+		name      string
+		requestID string
+	}{
+		{name: "идентификатор от клиента", requestID: "req-42"},
+		{name: "идентификатор генерируется", requestID: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+
+			if test.requestID != "" {
+				req.Header.Set(RequestIDHeader, test.requestID)
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			entries := logs.TakeAll()
+			require.Len(t, entries, 1, "Ожидается одна строка лога на запрос")
+
+			fields := entries[0].ContextMap()
+			requestID := w.Header().Get(RequestIDHeader)
+
+			assert.NotEmpty(t, requestID, "Идентификатор запроса не возвращен")
+
+			if test.requestID != "" {
+				assert.Equal(t, test.requestID, requestID, "Идентификатор запроса не совпадает с переданным")
+			}
+
+			assert.Equal(t, requestID, fields["request_id"], "request_id в логе не совпадает с заголовком")
+			assert.Equal(t, "/{id}", fields["route"], "Маршрут не совпадает с ожидаемым")
+			assert.Equal(t, int64(http.StatusTemporaryRedirect), fields["status"], "Код ответа не совпадает с ожидаемым")
+			assert.Equal(t, "user-1", fields["user_id"], "user_id не совпадает с ожидаемым")
+		})
+	}
+}
@@ -0,0 +1,357 @@
+package middlewares
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/problem"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/solution"
+)
+
+const (
+	EncodingGzip   = "gzip"
+	EncodingZstd   = "zstd"
+	EncodingBrotli = "br"
+)
+
+const (
+	// DefaultCompressMinSize — ответы короче этого размера отправляются без сжатия:
+	// накладные расходы кодировщика для них больше выигрыша.
+	DefaultCompressMinSize = 1024
+
+	// MaxDecompressedBodySize ограничивает размер распакованного тела запроса (защита от «zip-бомб»).
+	MaxDecompressedBodySize = 10 << 20
+)
+
+// encodingPreference — порядок выбора кодировки при равных q-значениях в Accept-Encoding.
+var encodingPreference = []string{EncodingZstd, EncodingBrotli, EncodingGzip}
+
+// encodeWriter — общий интерфейс кодировщиков gzip, zstd и brotli.
+type encodeWriter interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// encoder адаптирует кодировщик к solution.Pool: при возврате в пул он отвязывается от ответа.
+type encoder struct {
+	w encodeWriter
+}
+
+func (e *encoder) Reset() {
+	e.w.Reset(io.Discard)
+}
+
+var encoderPools = map[string]*solution.Pool[*encoder]{
+	EncodingGzip: solution.New(func() *encoder {
+		w, _ := gzip.NewWriterLevel(io.Discard, gzip.DefaultCompression)
+
+		return &encoder{w: w}
+	}),
+	EncodingZstd: solution.New(func() *encoder {
+		w, _ := zstd.NewWriter(io.Discard, zstd.WithEncoderConcurrency(1), zstd.WithEncoderLevel(zstd.SpeedDefault))
+
+		return &encoder{w: w}
+	}),
+	EncodingBrotli: solution.New(func() *encoder {
+		return &encoder{w: brotli.NewWriterLevel(io.Discard, 5)}
+	}),
+}
+
+// Decompressor распаковывает тело запроса в кодировке gzip, zstd или br.
+// Размер распакованных данных ограничен MaxDecompressedBodySize.
+func Decompressor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+
+		if encoding == "" || encoding == "identity" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := newDecoder(encoding, r.Body)
+
+		if errors.Is(err, errUnsupportedEncoding) {
+			problem.Error(w, r, http.StatusUnsupportedMediaType, problem.TypeInvalidRequest, "неподдерживаемая кодировка тела запроса")
+			return
+		}
+
+		if err != nil {
+			problem.Error(w, r, http.StatusBadRequest, problem.TypeInvalidRequest, "ошибка при распаковке тела запроса")
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, body, MaxDecompressedBodySize)
+		r.Header.Del("Content-Encoding")
+		r.Header.Del("Content-Length")
+		r.ContentLength = -1
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+var errUnsupportedEncoding = errors.New("неподдерживаемая кодировка")
+
+// decoder закрывает и распаковщик, и исходное тело запроса.
+type decoder struct {
+	io.Reader
+	closeFn func()
+	body    io.Closer
+}
+
+func (d *decoder) Close() error {
+	if d.closeFn != nil {
+		d.closeFn()
+	}
+
+	return d.body.Close()
+}
+
+func newDecoder(encoding string, body io.ReadCloser) (io.ReadCloser, error) {
+	switch encoding {
+	case EncodingGzip:
+		r, err := gzip.NewReader(body)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &decoder{Reader: r, closeFn: func() { r.Close() }, body: body}, nil
+	case EncodingZstd:
+		r, err := zstd.NewReader(body,
+			zstd.WithDecoderConcurrency(1),
+			zstd.WithDecoderLowmem(true),
+			zstd.WithDecoderMaxMemory(MaxDecompressedBodySize),
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &decoder{Reader: r, closeFn: r.Close, body: body}, nil
+	case EncodingBrotli:
+		return &decoder{Reader: brotli.NewReader(body), body: body}, nil
+	default:
+		return nil, errUnsupportedEncoding
+	}
+}
+
+// Compressor сжимает JSON и HTML ответы кодировкой, выбранной по заголовку Accept-Encoding.
+// Ответы короче minSize байт отправляются как есть.
+func Compressor(minSize int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, encoding: encoding, minSize: minSize, status: http.StatusOK}
+			defer cw.Close()
+
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// negotiateEncoding выбирает поддерживаемую кодировку с наибольшим q-значением.
+func negotiateEncoding(header string) string {
+	if header == "" {
+		return ""
+	}
+
+	weights := make(map[string]float64)
+	wildcard := -1.0
+
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+
+			if err != nil {
+				continue
+			}
+
+			q = parsed
+		}
+
+		if name == "*" {
+			wildcard = q
+			continue
+		}
+
+		weights[name] = q
+	}
+
+	best, bestQ := "", 0.0
+
+	for _, name := range encodingPreference {
+		q, ok := weights[name]
+
+		if !ok {
+			q = wildcard
+		}
+
+		if q > bestQ {
+			best, bestQ = name, q
+		}
+	}
+
+	return best
+}
+
+func compressibleType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+
+	if err != nil {
+		return false
+	}
+
+	return mediaType == "application/json" || mediaType == "text/html" || strings.HasSuffix(mediaType, "+json")
+}
+
+// compressWriter копит начало ответа, пока не станет ясно, стоит ли его сжимать.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+	status   int
+	buf      []byte
+	decided  bool
+	enc      *encoder
+}
+
+func (c *compressWriter) WriteHeader(code int) {
+	if c.decided {
+		return
+	}
+
+	c.status = code
+
+	// У этих ответов нет тела, сжимать нечего.
+	if code < http.StatusOK || code == http.StatusNoContent || code == http.StatusNotModified {
+		c.decide(false)
+	}
+}
+
+func (c *compressWriter) Write(p []byte) (int, error) {
+	if !c.decided {
+		c.buf = append(c.buf, p...)
+
+		if len(c.buf) < c.minSize {
+			return len(p), nil
+		}
+
+		if err := c.decide(true); err != nil {
+			return 0, err
+		}
+
+		return len(p), nil
+	}
+
+	if c.enc != nil {
+		return c.enc.w.Write(p)
+	}
+
+	return c.ResponseWriter.Write(p)
+}
+
+// decide отправляет заголовки и накопленные данные, включая сжатие, если ответ подходит по размеру и типу.
+func (c *compressWriter) decide(large bool) error {
+	c.decided = true
+
+	header := c.ResponseWriter.Header()
+
+	if header.Get("Content-Type") == "" && len(c.buf) > 0 {
+		header.Set("Content-Type", http.DetectContentType(c.buf))
+	}
+
+	if large && header.Get("Content-Encoding") == "" && compressibleType(header.Get("Content-Type")) {
+		c.enc = encoderPools[c.encoding].Get()
+		c.enc.w.Reset(c.ResponseWriter)
+
+		header.Set("Content-Encoding", c.encoding)
+		header.Del("Content-Length")
+	}
+
+	c.ResponseWriter.WriteHeader(c.status)
+
+	buf := c.buf
+	c.buf = nil
+
+	if len(buf) == 0 {
+		return nil
+	}
+
+	if c.enc != nil {
+		_, err := c.enc.w.Write(buf)
+
+		return err
+	}
+
+	_, err := c.ResponseWriter.Write(buf)
+
+	return err
+}
+
+// Close дописывает ответ: отправляет короткое тело без сжатия или завершает поток кодировщика.
+func (c *compressWriter) Close() error {
+	if !c.decided {
+		if err := c.decide(false); err != nil {
+			return err
+		}
+	}
+
+	if c.enc == nil {
+		return nil
+	}
+
+	err := c.enc.w.Close()
+	encoderPools[c.encoding].Put(c.enc)
+	c.enc = nil
+
+	return err
+}
+
+func (c *compressWriter) Flush() {
+	if !c.decided {
+		c.decide(len(c.buf) >= c.minSize)
+	}
+
+	if c.enc != nil {
+		c.enc.w.Flush()
+	}
+
+	if f, ok := c.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (c *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := c.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+
+	return nil, nil, http.ErrNotSupported
+}
+
+func (c *compressWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
@@ -0,0 +1,171 @@
+package middlewares
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decode(t *testing.T, encoding string, body []byte) string {
+	t.Helper()
+
+	var (
+		r   io.Reader
+		err error
+	)
+
+	switch encoding {
+	case EncodingGzip:
+		r, err = gzip.NewReader(bytes.NewReader(body))
+	case EncodingZstd:
+		r, err = zstd.NewReader(bytes.NewReader(body))
+	case EncodingBrotli:
+		r = brotli.NewReader(bytes.NewReader(body))
+	default:
+		return string(body)
+	}
+
+	require.NoError(t, err)
+
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+
+	return string(data)
+}
+
+func TestCompressor(t *testing.T) {
+	large := `{"items":"` + strings.Repeat("a", 2*DefaultCompressMinSize) + `"}`
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		contentType    string
+		body           string
+		wantEncoding   string
+	}{
+		{name: "gzip", acceptEncoding: "gzip", contentType: "application/json", body: large, wantEncoding: EncodingGzip},
+		{name: "zstd предпочтительнее при равном q", acceptEncoding: "gzip, br, zstd", contentType: "application/json", body: large, wantEncoding: EncodingZstd},
+		{name: "q-значения", acceptEncoding: "zstd;q=0.1, br;q=0.9, gzip;q=0.5", contentType: "text/html; charset=utf-8", body: large, wantEncoding: EncodingBrotli},
+		{name: "кодировка запрещена", acceptEncoding: "gzip;q=0", contentType: "application/json", body: large, wantEncoding: ""},
+		{name: "без Accept-Encoding", acceptEncoding: "", contentType: "application/json", body: large, wantEncoding: ""},
+		{name: "короткий ответ", acceptEncoding: "gzip", contentType: "application/json", body: `{"result":"ok"}`, wantEncoding: ""},
+		{name: "неподходящий тип", acceptEncoding: "gzip", contentType: "image/png", body: large, wantEncoding: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := Compressor(DefaultCompressMinSize)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", test.contentType)
+				w.WriteHeader(http.StatusCreated)
+
+				// пишем частями, чтобы порог срабатывал посреди ответа
+				for chunk := range chunks(test.body, 100) {
+					w.Write([]byte(chunk))
+				}
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/user/urls", nil)
+
+			if test.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", test.acceptEncoding)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusCreated, w.Code, "Код ответа не совпадает с ожидаемым")
+			assert.Equal(t, test.wantEncoding, w.Header().Get("Content-Encoding"), "Кодировка ответа не совпадает с ожидаемой")
+			assert.Equal(t, test.body, decode(t, test.wantEncoding, w.Body.Bytes()), "Тело ответа не совпадает с ожидаемым")
+		})
+	}
+}
+
+func chunks(s string, size int) func(func(string) bool) {
+	return func(yield func(string) bool) {
+		for len(s) > 0 {
+			n := min(size, len(s))
+
+			if !yield(s[:n]) {
+				return
+			}
+
+			s = s[n:]
+		}
+	}
+}
+
+func encode(t *testing.T, encoding string, data []byte) []byte {
+	t.Helper()
+
+	enc := encoderPools[encoding].Get()
+	defer encoderPools[encoding].Put(enc)
+
+	var buf bytes.Buffer
+
+	enc.w.Reset(&buf)
+	_, err := enc.w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, enc.w.Close())
+
+	return buf.Bytes()
+}
+
+func TestDecompressor(t *testing.T) {
+	payload := []byte(`{"url":"https://practicum.yandex.ru"}`)
+	bomb := bytes.Repeat([]byte{0}, MaxDecompressedBodySize+1)
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		wantCode int
+		wantBody string
+	}{
+		{name: "gzip", encoding: EncodingGzip, body: encode(t, EncodingGzip, payload), wantCode: http.StatusOK, wantBody: string(payload)},
+		{name: "zstd", encoding: EncodingZstd, body: encode(t, EncodingZstd, payload), wantCode: http.StatusOK, wantBody: string(payload)},
+		{name: "brotli", encoding: EncodingBrotli, body: encode(t, EncodingBrotli, payload), wantCode: http.StatusOK, wantBody: string(payload)},
+		{name: "без сжатия", encoding: "", body: payload, wantCode: http.StatusOK, wantBody: string(payload)},
+		{name: "неизвестная кодировка", encoding: "compress", body: payload, wantCode: http.StatusUnsupportedMediaType},
+		{name: "битые данные gzip", encoding: EncodingGzip, body: payload, wantCode: http.StatusBadRequest},
+		{name: "zip-бомба", encoding: EncodingGzip, body: encode(t, EncodingGzip, bomb), wantCode: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := Decompressor(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+					return
+				}
+
+				w.Write(body)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/shorten", bytes.NewReader(test.body))
+
+			if test.encoding != "" {
+				req.Header.Set("Content-Encoding", test.encoding)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, test.wantCode, w.Code, "Код ответа не совпадает с ожидаемым")
+
+			if test.wantBody != "" {
+				assert.Equal(t, test.wantBody, w.Body.String(), "Тело запроса распаковано неверно")
+			}
+		})
+	}
+}
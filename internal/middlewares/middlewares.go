@@ -1,178 +1,30 @@
 package middlewares
 
 import (
-	"bytes"
-	"compress/gzip"
 	"context"
-	"encoding/json"
-	"fmt"
-	"net"
 	"net/http"
-	"os"
-	"sync"
-	"time"
-
-	"github.com/hashicorp/go-retryablehttp"
 
 	"github.com/flash1nho/go-musthave-shortener-tpl/internal/authenticator"
-
-	"go.uber.org/zap"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/clientip"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/problem"
 )
 
-type AuditEvent struct {
-	Timestamp int64  `json:"ts"`
-	Action    string `json:"action"`
-	UserID    string `json:"user_id"`
-	URL       string `json:"url"`
-}
-
 type HTTPProvider struct {
 	w http.ResponseWriter
 	r *http.Request
+	// secure выставляет cookie флаг Secure; включается вместе с HTTPS.
+	secure bool
 }
 
-type Observer interface {
-	Notify(event AuditEvent)
-}
-
-type AuditSubject struct {
-	observers []Observer
-}
-
-type FileObserver struct {
-	mu       sync.RWMutex
-	FilePath string
-	Log      *zap.Logger
-}
-
-type URLObserver struct {
-	URL    string
-	Log    *zap.Logger
-	Client *retryablehttp.Client
-}
-
-func Decompressor(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Header.Get("Content-Encoding") == "gzip" {
-			gzReader, err := gzip.NewReader(r.Body)
-			if err != nil {
-				http.Error(w, "Ошибка при распаковке gzip", http.StatusBadRequest)
-				return
-			}
-
-			defer gzReader.Close()
-
-			r.Body = gzReader
-		}
-
-		next.ServeHTTP(w, r)
-	})
-}
-
-func (s *AuditSubject) Register(o Observer) {
-	s.observers = append(s.observers, o)
-}
-
-func (s *AuditSubject) NotifyAll(e AuditEvent) {
-	for _, o := range s.observers {
-		go o.Notify(e) // Запускаем в горутинах, чтобы не блокировать ответ пользователю
-	}
-}
-
-func (f *FileObserver) Notify(e AuditEvent) {
-	file, err := os.OpenFile(f.FilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-
-	if err != nil {
-		f.Log.Error(fmt.Sprint(err))
-		return
-	}
-
-	defer file.Close()
-
-	data, err := json.Marshal(e)
-
-	if err != nil {
-		f.Log.Error(fmt.Sprint(err))
-		return
-	}
-
-	f.mu.Lock()
-
-	file.Write(append(data, '\n'))
-
-	f.mu.Unlock()
-}
-
-func (u *URLObserver) Notify(e AuditEvent) {
-	data, err := json.Marshal(e)
-
-	if err != nil {
-		u.Log.Error(fmt.Sprint(err))
-		return
-	}
-
-	retryClient := u.Client
-	retryClient.RetryMax = 3
-	retryClient.RetryWaitMin = 1
-	retryClient.RetryWaitMax = 5
-
-	req, err := retryablehttp.NewRequest("POST", u.URL, bytes.NewBuffer(data))
-
-	if err != nil {
-		u.Log.Error(fmt.Sprint(err))
-		return
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := retryClient.Do(req)
-
-	if err != nil {
-		u.Log.Error(fmt.Sprint(err))
-		return
-	}
-
-	resp.Body.Close()
-}
-
-func Audit(subject *AuditSubject) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			userID, _ := r.Context().Value(authenticator.GetUserKey()).(string)
-
-			next.ServeHTTP(w, r)
-
-			event := AuditEvent{
-				Timestamp: time.Now().Unix(),
-				Action:    r.Method,
-				UserID:    userID,
-				URL:       r.URL.Path,
-			}
-
-			subject.NotifyAll(event)
-		})
-	}
-}
-
-func TrustedSubnet(trustedSubnet string) func(http.Handler) http.Handler {
+// TrustedSubnet пропускает только клиентов из доверенных подсетей. Адрес клиента берется из
+// контекста: его сохраняет clientip.Resolver.Middleware, поэтому заголовкам X-Real-IP и
+// X-Forwarded-For верят только от доверенных прокси. Пустой список запрещает доступ всем:
+// служебные маршруты открываются только явно заданным подсетям.
+func TrustedSubnet(subnets clientip.Subnets) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if trustedSubnet == "" {
-				next.ServeHTTP(w, r)
-				return
-			}
-
-			_, subnet, err := net.ParseCIDR(trustedSubnet)
-			if err != nil {
-				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-				return
-			}
-
-			ipStr := r.Header.Get("X-Real-IP")
-			ip := net.ParseIP(ipStr)
-
-			if ip == nil || !subnet.Contains(ip) {
-				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			if !subnets.Contains(clientip.IP(r.Context())) {
+				problem.Error(w, r, http.StatusForbidden, problem.TypeForbidden, "адрес клиента не входит в доверенные подсети")
 				return
 			}
 
@@ -197,7 +49,7 @@ func (p *HTTPProvider) SetCookie(_ context.Context, cookieName, cookieValue stri
 		Value:    cookieValue,
 		Path:     "/",
 		HttpOnly: true,
-		Secure:   false,
+		Secure:   p.secure,
 		SameSite: http.SameSiteLaxMode,
 		MaxAge:   3600 * 24 * 7,
 	}
@@ -207,16 +59,29 @@ func (p *HTTPProvider) SetCookie(_ context.Context, cookieName, cookieValue stri
 	return nil
 }
 
+// Auth — SessionAuth для сервера без TLS: cookie сессии выдается без флага Secure.
 func Auth(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		auth := authenticator.NewAuthenticator()
-		ctx, err := auth.Authenticate(r.Context(), &HTTPProvider{w, r})
+	return SessionAuth(false)(next)
+}
 
-		if err != nil {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
+// SessionAuth аутентифицирует запрос по cookie сессии, выдавая новую при ее отсутствии.
+// secure выставляет cookie флаг Secure и должен совпадать с режимом HTTPS.
+func SessionAuth(secure bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth := authenticator.NewAuthenticator()
+			ctx, err := auth.Authenticate(r.Context(), &HTTPProvider{w: w, r: r, secure: secure})
+
+			if err != nil {
+				problem.Error(w, r, http.StatusUnauthorized, problem.TypeUnauthorized, "cookie сессии повреждена или подделана")
+				return
+			}
+
+			if userID, err := authenticator.FromContext(ctx); err == nil {
+				setAccessLogUserID(ctx, userID)
+			}
 
-		next.ServeHTTP(w, r.Clone(ctx))
-	})
+			next.ServeHTTP(w, r.Clone(ctx))
+		})
+	}
 }
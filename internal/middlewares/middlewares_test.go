@@ -0,0 +1,48 @@
+package middlewares
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/clientip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrustedSubnet(t *testing.T) {
+	subnets, err := clientip.ParseSubnets([]string{"10.0.0.0/8", "2001:db8::/32"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		subnets clientip.Subnets
+		ip      string
+		status  int
+	}{
+		{name: "подсеть IPv4", subnets: subnets, ip: "10.2.3.4", status: http.StatusOK},
+		{name: "подсеть IPv6", subnets: subnets, ip: "2001:db8::1", status: http.StatusOK},
+		{name: "чужой адрес", subnets: subnets, ip: "192.0.2.1", status: http.StatusForbidden},
+		{name: "адрес не определен", subnets: subnets, status: http.StatusForbidden},
+		{name: "подсети не заданы", ip: "10.2.3.4", status: http.StatusForbidden},
+		{name: "пустой список подсетей", subnets: clientip.Subnets{}, ip: "10.2.3.4", status: http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/internal/stats", nil)
+			// заголовок не должен влиять на решение: адрес определяет clientip.Resolver
+			r.Header.Set("X-Real-IP", "10.0.0.1")
+
+			if test.ip != "" {
+				r = r.WithContext(clientip.WithIP(r.Context(), net.ParseIP(test.ip)))
+			}
+
+			w := httptest.NewRecorder()
+			TrustedSubnet(test.subnets)(okHandler).ServeHTTP(w, r)
+
+			assert.Equal(t, test.status, w.Code, "Код ответа не совпадает с ожидаемым")
+		})
+	}
+}
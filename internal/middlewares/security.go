@@ -0,0 +1,180 @@
+package middlewares
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/authenticator"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/problem"
+)
+
+const (
+	// CSRFCookieName — cookie с токеном CSRF. Она доступна скриптам страницы, чтобы те
+	// повторяли токен в заголовке CSRFHeader (схема double-submit cookie).
+	CSRFCookieName = "csrf_token"
+	CSRFHeader     = "X-CSRF-Token"
+
+	// corsMaxAge — сколько секунд браузер может кешировать ответ на preflight-запрос.
+	corsMaxAge = 600
+)
+
+// corsAllowedMethods, corsAllowedHeaders и corsExposedHeaders — методы и заголовки, которые
+// браузерный клиент может использовать и читать при кросс-доменных запросах.
+var (
+	corsAllowedMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	corsAllowedHeaders = []string{"Content-Type", "Content-Encoding", "Accept-Encoding", CSRFHeader, RequestIDHeader}
+	corsExposedHeaders = []string{RequestIDHeader, CSRFHeader, "X-Quota-Remaining", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"}
+)
+
+// SecurityHeaders добавляет стандартные защитные заголовки. При включенном HTTPS
+// добавляется Strict-Transport-Security со сроком hstsMaxAge секунд; значение не больше 0 отключает HSTS.
+func SecurityHeaders(https bool, hstsMaxAge int) func(http.Handler) http.Handler {
+	hsts := ""
+
+	if https && hstsMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(hstsMaxAge) + "; includeSubDomains"
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+
+			h.Set("X-Content-Type-Options", "nosniff")
+			h.Set("X-Frame-Options", "DENY")
+			h.Set("Referrer-Policy", "strict-origin-when-cross-origin")
+			h.Set("Content-Security-Policy", "default-src 'none'; frame-ancestors 'none'")
+			h.Set("Cross-Origin-Opener-Policy", "same-origin")
+
+			if hsts != "" {
+				h.Set("Strict-Transport-Security", hsts)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CORS разрешает кросс-доменные запросы с источников из allowedOrigins (вида https://dash.example).
+// Для перечисленных источников разрешается передача cookie. Источник "*" разрешает любой сайт,
+// но без cookie. Пустой список оставляет политику браузера по умолчанию.
+func CORS(allowedOrigins []string) func(http.Handler) http.Handler {
+	anyOrigin := slices.Contains(allowedOrigins, "*")
+
+	return func(next http.Handler) http.Handler {
+		if len(allowedOrigins) == 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+			w.Header().Add("Vary", "Origin")
+
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			credentials := slices.Contains(allowedOrigins, strings.ToLower(origin))
+
+			if !credentials && !anyOrigin {
+				if preflight {
+					problem.Error(w, r, http.StatusForbidden, problem.TypeForbidden, "источник не входит в список разрешенных")
+					return
+				}
+
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+
+			if credentials {
+				h.Set("Access-Control-Allow-Origin", origin)
+				h.Set("Access-Control-Allow-Credentials", "true")
+			} else {
+				h.Set("Access-Control-Allow-Origin", "*")
+			}
+
+			if !preflight {
+				h.Set("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders, ", "))
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+			h.Set("Access-Control-Allow-Methods", strings.Join(corsAllowedMethods, ", "))
+			h.Set("Access-Control-Allow-Headers", strings.Join(corsAllowedHeaders, ", "))
+			h.Set("Access-Control-Max-Age", strconv.Itoa(corsMaxAge))
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// CSRF защищает изменяющие запросы, аутентифицированные cookie сессии, по схеме double-submit:
+// значение cookie CSRFCookieName должно совпадать с заголовком CSRFHeader. Подделанный запрос
+// с чужого сайта отправит cookie, но прочитать ее и повторить в заголовке не сможет.
+// Запросы без cookie сессии не проверяются: у них нет полномочий, которые можно украсть.
+// Токен выдается в cookie и в заголовке CSRFHeader ответа, если у клиента его еще нет.
+func CSRF(secure bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := ""
+
+			if cookie, err := r.Cookie(CSRFCookieName); err == nil {
+				token = cookie.Value
+			}
+
+			if !isSafeMethod(r.Method) && hasSessionCookie(r) && !validCSRFToken(token, r.Header.Get(CSRFHeader)) {
+				problem.Error(w, r, http.StatusForbidden, problem.TypeCSRF, "CSRF-токен отсутствует или не совпадает")
+				return
+			}
+
+			if token == "" {
+				token = rand.Text()
+
+				http.SetCookie(w, &http.Cookie{
+					Name:     CSRFCookieName,
+					Value:    token,
+					Path:     "/",
+					Secure:   secure,
+					SameSite: http.SameSiteStrictMode,
+					MaxAge:   3600 * 24 * 7,
+				})
+			}
+
+			w.Header().Set(CSRFHeader, token)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+
+	return false
+}
+
+func hasSessionCookie(r *http.Request) bool {
+	_, err := r.Cookie(authenticator.GetCookieName())
+
+	return err == nil
+}
+
+func validCSRFToken(cookieToken, headerToken string) bool {
+	if cookieToken == "" || headerToken == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(cookieToken), []byte(headerToken)) == 1
+}
@@ -0,0 +1,135 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/authenticator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+func TestSecurityHeaders(t *testing.T) {
+	for _, https := range []bool{false, true} {
+		w := httptest.NewRecorder()
+
+		SecurityHeaders(https, 60)(okHandler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+		assert.Equal(t, "DENY", w.Header().Get("X-Frame-Options"))
+
+		if https {
+			assert.Equal(t, "max-age=60; includeSubDomains", w.Header().Get("Strict-Transport-Security"))
+		} else {
+			assert.Empty(t, w.Header().Get("Strict-Transport-Security"), "HSTS без TLS не отправляется")
+		}
+	}
+}
+
+func TestCORS(t *testing.T) {
+	tests := []struct {
+		name        string
+		origins     []string
+		method      string
+		origin      string
+		status      int
+		allowOrigin string
+		credentials bool
+	}{
+		{name: "разрешенный источник", origins: []string{"https://dash.example"}, method: http.MethodGet, origin: "https://dash.example", status: http.StatusOK, allowOrigin: "https://dash.example", credentials: true},
+		{name: "preflight", origins: []string{"https://dash.example"}, method: http.MethodOptions, origin: "https://dash.example", status: http.StatusNoContent, allowOrigin: "https://dash.example", credentials: true},
+		{name: "чужой источник", origins: []string{"https://dash.example"}, method: http.MethodGet, origin: "https://evil.example", status: http.StatusOK},
+		{name: "preflight чужого источника", origins: []string{"https://dash.example"}, method: http.MethodOptions, origin: "https://evil.example", status: http.StatusForbidden},
+		{name: "любой источник без cookie", origins: []string{"*"}, method: http.MethodGet, origin: "https://any.example", status: http.StatusOK, allowOrigin: "*"},
+		{name: "CORS отключен", method: http.MethodOptions, origin: "https://dash.example", status: http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(test.method, "/api/user/urls", nil)
+			r.Header.Set("Origin", test.origin)
+
+			if test.method == http.MethodOptions {
+				r.Header.Set("Access-Control-Request-Method", http.MethodDelete)
+			}
+
+			w := httptest.NewRecorder()
+
+			CORS(test.origins)(okHandler).ServeHTTP(w, r)
+
+			assert.Equal(t, test.status, w.Code, "Код ответа не совпадает с ожидаемым")
+			assert.Equal(t, test.allowOrigin, w.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, test.credentials, w.Header().Get("Access-Control-Allow-Credentials") == "true")
+		})
+	}
+}
+
+func TestCSRF(t *testing.T) {
+	session := &http.Cookie{Name: authenticator.GetCookieName(), Value: "session"}
+	token := &http.Cookie{Name: CSRFCookieName, Value: "token"}
+
+	tests := []struct {
+		name    string
+		method  string
+		cookies []*http.Cookie
+		header  string
+		status  int
+	}{
+		{name: "GET без токена", method: http.MethodGet, cookies: []*http.Cookie{session}, status: http.StatusOK},
+		{name: "POST без сессии", method: http.MethodPost, status: http.StatusOK},
+		{name: "DELETE без токена", method: http.MethodDelete, cookies: []*http.Cookie{session}, status: http.StatusForbidden},
+		{name: "DELETE без заголовка", method: http.MethodDelete, cookies: []*http.Cookie{session, token}, status: http.StatusForbidden},
+		{name: "DELETE с чужим токеном", method: http.MethodDelete, cookies: []*http.Cookie{session, token}, header: "other", status: http.StatusForbidden},
+		{name: "DELETE с токеном", method: http.MethodDelete, cookies: []*http.Cookie{session, token}, header: "token", status: http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(test.method, "/api/user/urls", nil)
+
+			for _, cookie := range test.cookies {
+				r.AddCookie(cookie)
+			}
+
+			if test.header != "" {
+				r.Header.Set(CSRFHeader, test.header)
+			}
+
+			w := httptest.NewRecorder()
+
+			CSRF(true)(okHandler).ServeHTTP(w, r)
+
+			assert.Equal(t, test.status, w.Code, "Код ответа не совпадает с ожидаемым")
+		})
+	}
+
+	// новый клиент получает токен в cookie и в заголовке ответа
+	w := httptest.NewRecorder()
+
+	CSRF(true)(okHandler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, CSRFCookieName, cookies[0].Name)
+	assert.True(t, cookies[0].Secure, "При HTTPS cookie должна быть Secure")
+	assert.False(t, cookies[0].HttpOnly, "Токен должен быть доступен скриптам")
+	assert.Equal(t, cookies[0].Value, w.Header().Get(CSRFHeader))
+}
+
+func TestSessionAuthSecureCookie(t *testing.T) {
+	for _, secure := range []bool{false, true} {
+		w := httptest.NewRecorder()
+
+		SessionAuth(secure)(okHandler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		cookies := w.Result().Cookies()
+		require.Len(t, cookies, 1)
+		assert.Equal(t, secure, cookies[0].Secure, "Флаг Secure должен совпадать с режимом HTTPS")
+		assert.True(t, cookies[0].HttpOnly)
+	}
+}
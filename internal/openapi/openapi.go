@@ -0,0 +1,70 @@
+// Package openapi отдает спецификацию OpenAPI 3 HTTP API сервиса и страницу документации Swagger UI.
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+	"strings"
+
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+const (
+	// SpecPath — адрес, по которому отдается спецификация.
+	SpecPath = "/api/openapi.json"
+	// DocsPath — адрес страницы документации.
+	DocsPath = "/api/docs"
+)
+
+// docsCSP ослабляет политику SecurityHeaders только для страницы документации:
+// Swagger UI загружает свои скрипты и стили, встраивает inline-стили и иконки data:.
+const docsCSP = "default-src 'self'; img-src 'self' data:; style-src 'self' 'unsafe-inline'; frame-ancestors 'none'"
+
+// Spec — спецификация OpenAPI 3, описывающая все маршруты основного HTTP-сервера.
+//
+//go:embed openapi.json
+var Spec []byte
+
+// initializer заменяет swagger-initializer.js из поставки Swagger UI, указывая на спецификацию сервиса.
+var initializer = []byte(`window.onload = function() {
+  window.ui = SwaggerUIBundle({
+    url: "` + SpecPath + `",
+    dom_id: "#swagger-ui",
+    deepLinking: true,
+    withCredentials: true,
+    presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
+    plugins: [SwaggerUIBundle.plugins.DownloadUrl],
+    layout: "StandaloneLayout"
+  });
+};
+`)
+
+// SpecHandler отдает спецификацию в формате JSON.
+func SpecHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(Spec)
+}
+
+// DocsHandler отдает Swagger UI под префиксом DocsPath. Запрос самого DocsPath перенаправляется
+// на DocsPath + "/", чтобы относительные ссылки страницы разрешались внутри префикса.
+func DocsHandler() http.Handler {
+	files := http.StripPrefix(DocsPath+"/", http.FileServerFS(swaggerFiles.FS))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == DocsPath {
+			http.Redirect(w, r, DocsPath+"/", http.StatusMovedPermanently)
+			return
+		}
+
+		w.Header().Set("Content-Security-Policy", docsCSP)
+
+		if strings.TrimPrefix(r.URL.Path, DocsPath+"/") == "swagger-initializer.js" {
+			w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+			w.Write(initializer)
+			return
+		}
+
+		files.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,103 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/audit"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/blocklist"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/facade"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/handler"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/health"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/storage"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/webhook"
+)
+
+// jsonFields возвращает имена полей структуры в JSON.
+func jsonFields(v any) []string {
+	var fields []string
+
+	t := reflect.TypeOf(v)
+
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+
+		if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+
+	slices.Sort(fields)
+
+	return fields
+}
+
+// TestSchemas сверяет свойства схем спецификации с JSON-полями структур, которые пишут обработчики.
+func TestSchemas(t *testing.T) {
+	var spec struct {
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]json.RawMessage `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+
+	require.NoError(t, json.Unmarshal(Spec, &spec), "Спецификация не разбирается")
+
+	tests := []struct {
+		schema string
+		value  any
+	}{
+		{schema: "ShortenRequest", value: handler.ShortenRequest{}},
+		{schema: "ShortenResponse", value: handler.ShortenResponse{}},
+		{schema: "BatchShortenRequest", value: handler.BatchShortenRequest{}},
+		{schema: "BatchShortenResponse", value: handler.BatchShortenResponse{}},
+		{schema: "QuotaCounter", value: handler.QuotaCounter{}},
+		{schema: "QuotaResponse", value: handler.QuotaResponse{}},
+		{schema: "BlocklistRuleRequest", value: handler.BlocklistRuleRequest{}},
+		{schema: "WebhookRequest", value: handler.WebhookRequest{}},
+		{schema: "Webhook", value: webhook.Webhook{}},
+		{schema: "WebhookDelivery", value: webhook.Delivery{}},
+		{schema: "WebhookPayload", value: webhook.Payload{}},
+		{schema: "WebhookLink", value: webhook.Link{}},
+		{schema: "BlocklistRule", value: blocklist.Rule{}},
+		{schema: "AuditEvent", value: audit.Event{}},
+		{schema: "AuditEventsResponse", value: handler.AuditEventsResponse{}},
+		{schema: "AuditFilter", value: audit.Filter{}},
+		{schema: "CheckResult", value: health.CheckResult{}},
+		{schema: "HealthReport", value: health.Report{}},
+		{schema: "AdminLink", value: facade.Link{}},
+		{schema: "AdminUser", value: facade.User{}},
+		{schema: "AuditQueueStats", value: audit.QueueStats{}},
+		{schema: "WebhookQueueStats", value: webhook.QueueStats{}},
+		{schema: "AdminHealthResponse", value: handler.AdminHealthResponse{}},
+		{schema: "UserURL", value: facade.BatchUserShortenResponse{}},
+		{schema: "Stats", value: storage.Stats{}},
+		{schema: "StatsPoint", value: storage.StatsPoint{}},
+		{schema: "StatsTopLink", value: storage.TopLink{}},
+		{schema: "StatsTopDomain", value: storage.TopDomain{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.schema, func(t *testing.T) {
+			schema, ok := spec.Components.Schemas[test.schema]
+			require.True(t, ok, "Схема не найдена в спецификации")
+
+			var properties []string
+
+			for name := range schema.Properties {
+				properties = append(properties, name)
+			}
+
+			slices.Sort(properties)
+
+			assert.Equal(t, jsonFields(test.value), properties, "Свойства схемы не совпадают с полями структуры")
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// Package problem формирует ответы об ошибках в формате RFC 7807 (application/problem+json).
+//
+// Тип проблемы — относительный URI, который клиент может использовать для ветвления логики,
+// не разбирая текст ошибки; detail предназначен человеку и не содержит внутренних подробностей.
+package problem
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/logger"
+)
+
+const ContentType = "application/problem+json"
+
+// Типы проблем.
+const (
+	// TypeBlank означает, что смысл ошибки полностью передается кодом ответа.
+	TypeBlank          = "about:blank"
+	TypeInvalidRequest = "/problems/invalid-request"
+	TypeInvalidURL     = "/problems/invalid-url"
+	TypeBlocked        = "/problems/blocked"
+	TypeNotFound       = "/problems/not-found"
+	TypeConflict       = "/problems/conflict"
+	TypeQuotaExceeded  = "/problems/quota-exceeded"
+	TypeRateLimited    = "/problems/rate-limited"
+	TypeForbidden      = "/problems/forbidden"
+	TypeUnauthorized   = "/problems/unauthorized"
+	TypeCSRF           = "/problems/csrf"
+	TypeInternal       = "/problems/internal"
+)
+
+// titles — краткие неизменные описания типов; для about:blank заголовком служит текст кода ответа.
+var titles = map[string]string{
+	TypeInvalidRequest: "Некорректный запрос",
+	TypeInvalidURL:     "Некорректный адрес",
+	TypeBlocked:        "Адрес заблокирован",
+	TypeNotFound:       "Не найдено",
+	TypeConflict:       "Ссылка уже существует",
+	TypeQuotaExceeded:  "Квота исчерпана",
+	TypeRateLimited:    "Слишком много запросов",
+	TypeForbidden:      "Доступ запрещен",
+	TypeUnauthorized:   "Требуется аутентификация",
+	TypeCSRF:           "Отсутствует CSRF-токен",
+	TypeInternal:       "Внутренняя ошибка сервера",
+}
+
+// generate:reset
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+
+	// Extensions — дополнительные члены объекта проблемы, например остаток квоты.
+	Extensions map[string]any `json:"-"`
+}
+
+// New создает проблему с заголовком, соответствующим типу.
+func New(status int, problemType, detail string) Problem {
+	title, ok := titles[problemType]
+
+	if !ok {
+		problemType = TypeBlank
+		title = http.StatusText(status)
+	}
+
+	return Problem{Type: problemType, Title: title, Status: status, Detail: detail}
+}
+
+// With добавляет член расширения.
+func (p Problem) With(name string, value any) Problem {
+	extensions := make(map[string]any, len(p.Extensions)+1)
+	maps.Copy(extensions, p.Extensions)
+	extensions[name] = value
+	p.Extensions = extensions
+
+	return p
+}
+
+func (p Problem) MarshalJSON() ([]byte, error) {
+	type plain Problem
+
+	data, err := json.Marshal(plain(p))
+
+	if err != nil || len(p.Extensions) == 0 {
+		return data, err
+	}
+
+	members := make(map[string]any, len(p.Extensions)+6)
+	maps.Copy(members, p.Extensions)
+
+	// Стандартные члены имеют приоритет над одноименными расширениями.
+	var standard map[string]any
+
+	if err := json.Unmarshal(data, &standard); err != nil {
+		return nil, err
+	}
+
+	maps.Copy(members, standard)
+
+	return json.Marshal(members)
+}
+
+// Write отправляет проблему, дополнив ее адресом запроса и идентификатором запроса.
+func Write(w http.ResponseWriter, r *http.Request, p Problem) {
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+
+	if p.RequestID == "" {
+		p.RequestID = logger.RequestIDFromContext(r.Context())
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Del("Content-Length")
+	w.WriteHeader(p.Status)
+
+	json.NewEncoder(w).Encode(p)
+}
+
+// Error — сокращение для Write(w, r, New(status, problemType, detail)).
+func Error(w http.ResponseWriter, r *http.Request, status int, problemType, detail string) {
+	Write(w, r, New(status, problemType, detail))
+}
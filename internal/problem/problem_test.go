@@ -0,0 +1,48 @@
+package problem
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name    string
+		problem Problem
+		want    string
+	}{
+		{
+			name:    "тип из каталога",
+			problem: New(http.StatusNotFound, TypeNotFound, "short URL not found"),
+			want:    `{"type":"/problems/not-found","title":"Не найдено","status":404,"detail":"short URL not found","instance":"/abc","request_id":"req-1"}`,
+		},
+		{
+			name:    "неизвестный тип",
+			problem: New(http.StatusTeapot, "/problems/unknown", ""),
+			want:    `{"type":"about:blank","title":"I'm a teapot","status":418,"instance":"/abc","request_id":"req-1"}`,
+		},
+		{
+			name:    "расширение",
+			problem: New(http.StatusTooManyRequests, TypeQuotaExceeded, "квота").With("quota", map[string]int{"used": 2}).With("status", 0),
+			want:    `{"type":"/problems/quota-exceeded","title":"Квота исчерпана","status":429,"detail":"квота","instance":"/abc","request_id":"req-1","quota":{"used":2}}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/abc", nil)
+			r = r.WithContext(logger.ContextWithRequestID(r.Context(), "req-1"))
+			w := httptest.NewRecorder()
+
+			Write(w, r, test.problem)
+
+			assert.Equal(t, test.problem.Status, w.Code, "Код ответа не совпадает с ожидаемым")
+			assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+			assert.JSONEq(t, test.want, w.Body.String(), "Тело ответа не совпадает с ожидаемым")
+		})
+	}
+}
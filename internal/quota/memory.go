@@ -0,0 +1,86 @@
+package quota
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type counter struct {
+	day   time.Time
+	daily int
+	total int
+}
+
+// MemoryStore хранит счетчики в памяти процесса. Индивидуальные квоты в нем не задаются.
+type MemoryStore struct {
+	mu       sync.Mutex
+	counters map[string]*counter
+	now      func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		counters: make(map[string]*counter),
+		now:      time.Now,
+	}
+}
+
+// get возвращает счетчик пользователя со сброшенным суточным значением, если наступили новые сутки.
+func (m *MemoryStore) get(userID string) *counter {
+	today := m.now().UTC().Truncate(24 * time.Hour)
+	c, ok := m.counters[userID]
+
+	if !ok {
+		c = &counter{day: today}
+		m.counters[userID] = c
+	}
+
+	if !c.day.Equal(today) {
+		c.day = today
+		c.daily = 0
+	}
+
+	return c
+}
+
+func (m *MemoryStore) Reserve(_ context.Context, userID string, n int, limits Limits) (Usage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c := m.get(userID)
+	usage := Usage{Daily: c.daily, Total: c.total, Limits: limits}
+
+	if (limits.Daily > 0 && c.daily+n > limits.Daily) || (limits.Total > 0 && c.total+n > limits.Total) {
+		return usage, &ExceededError{Usage: usage, Requested: n}
+	}
+
+	c.daily += n
+	c.total += n
+
+	return Usage{Daily: c.daily, Total: c.total, Limits: limits}, nil
+}
+
+func (m *MemoryStore) Release(_ context.Context, userID string, n int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c := m.get(userID)
+	c.daily = max(c.daily-n, 0)
+	c.total = max(c.total-n, 0)
+
+	return nil
+}
+
+func (m *MemoryStore) Usage(_ context.Context, userID string) (Usage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c := m.get(userID)
+
+	return Usage{Daily: c.daily, Total: c.total}, nil
+}
+
+func (m *MemoryStore) Override(context.Context, string) (Limits, error) {
+	return Limits{Daily: -1, Total: -1}, nil
+}
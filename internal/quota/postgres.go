@@ -0,0 +1,113 @@
+package quota
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresStore хранит счетчики в user_quota_usage, а индивидуальные квоты — в user_quotas.
+// Сутки отсчитываются по времени базы в UTC, чтобы все экземпляры сервиса сбрасывали счетчики одновременно.
+type PostgresStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresStore(pool *pgxpool.Pool) *PostgresStore {
+	return &PostgresStore{pool: pool}
+}
+
+func (p *PostgresStore) Reserve(ctx context.Context, userID string, n int, limits Limits) (Usage, error) {
+	// Ссылки, созданные до появления квот, тоже учитываются в общем счетчике.
+	_, err := p.pool.Exec(ctx, `
+		INSERT INTO user_quota_usage (user_id, day, daily_count, total_count)
+		VALUES ($1, (now() AT TIME ZONE 'UTC')::date, 0,
+			(SELECT COUNT(*) FROM shorten_urls WHERE user_id = $1 AND is_deleted = FALSE))
+		ON CONFLICT (user_id) DO NOTHING`,
+		userID,
+	)
+
+	if err != nil {
+		return Usage{}, err
+	}
+
+	// Проверка и увеличение выполняются одним UPDATE под блокировкой строки,
+	// поэтому параллельные запросы не могут вместе превысить квоту.
+	usage := Usage{Limits: limits}
+
+	err = p.pool.QueryRow(ctx, `
+		UPDATE user_quota_usage AS u SET
+			daily_count = (CASE WHEN u.day = t.today THEN u.daily_count ELSE 0 END) + $2,
+			total_count = u.total_count + $2,
+			day = t.today
+		FROM (SELECT (now() AT TIME ZONE 'UTC')::date AS today) AS t
+		WHERE u.user_id = $1
+			AND ($3 <= 0 OR (CASE WHEN u.day = t.today THEN u.daily_count ELSE 0 END) + $2 <= $3)
+			AND ($4 <= 0 OR u.total_count + $2 <= $4)
+		RETURNING u.daily_count, u.total_count`,
+		userID, n, limits.Daily, limits.Total,
+	).Scan(&usage.Daily, &usage.Total)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		current, err := p.Usage(ctx, userID)
+
+		if err != nil {
+			return Usage{}, err
+		}
+
+		current.Limits = limits
+
+		return current, &ExceededError{Usage: current, Requested: n}
+	}
+
+	if err != nil {
+		return Usage{}, err
+	}
+
+	return usage, nil
+}
+
+func (p *PostgresStore) Release(ctx context.Context, userID string, n int) error {
+	_, err := p.pool.Exec(ctx, `
+		UPDATE user_quota_usage SET
+			daily_count = CASE WHEN day = (now() AT TIME ZONE 'UTC')::date THEN GREATEST(daily_count - $2, 0) ELSE 0 END,
+			total_count = GREATEST(total_count - $2, 0)
+		WHERE user_id = $1`,
+		userID, n,
+	)
+
+	return err
+}
+
+func (p *PostgresStore) Usage(ctx context.Context, userID string) (Usage, error) {
+	var usage Usage
+
+	err := p.pool.QueryRow(ctx, `
+		SELECT CASE WHEN day = (now() AT TIME ZONE 'UTC')::date THEN daily_count ELSE 0 END, total_count
+		FROM user_quota_usage WHERE user_id = $1`,
+		userID,
+	).Scan(&usage.Daily, &usage.Total)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = p.pool.QueryRow(ctx,
+			"SELECT COUNT(*) FROM shorten_urls WHERE user_id = $1 AND is_deleted = FALSE", userID,
+		).Scan(&usage.Total)
+	}
+
+	return usage, err
+}
+
+func (p *PostgresStore) Override(ctx context.Context, userID string) (Limits, error) {
+	limits := Limits{Daily: -1, Total: -1}
+
+	err := p.pool.QueryRow(ctx,
+		"SELECT COALESCE(daily_limit, -1), COALESCE(total_limit, -1) FROM user_quotas WHERE user_id = $1", userID,
+	).Scan(&limits.Daily, &limits.Total)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return limits, nil
+	}
+
+	return limits, err
+}
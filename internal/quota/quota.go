@@ -0,0 +1,161 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Limits — квоты пользователя на создание ссылок. Нулевое значение означает отсутствие ограничения.
+type Limits struct {
+	Daily int
+	Total int
+}
+
+// Usage — использование квоты пользователем. Суточный счетчик сбрасывается в полночь UTC.
+type Usage struct {
+	Daily  int
+	Total  int
+	Limits Limits
+}
+
+// Unlimited — значение остатка для квоты без ограничения.
+const Unlimited = -1
+
+// RemainingDaily возвращает остаток суточной квоты или Unlimited.
+func (u Usage) RemainingDaily() int {
+	return remaining(u.Limits.Daily, u.Daily)
+}
+
+// RemainingTotal возвращает остаток общей квоты или Unlimited.
+func (u Usage) RemainingTotal() int {
+	return remaining(u.Limits.Total, u.Total)
+}
+
+// Remaining возвращает, сколько ссылок пользователь еще может создать с учетом обеих квот, или Unlimited.
+func (u Usage) Remaining() int {
+	daily, total := u.RemainingDaily(), u.RemainingTotal()
+
+	switch {
+	case daily == Unlimited:
+		return total
+	case total == Unlimited:
+		return daily
+	default:
+		return min(daily, total)
+	}
+}
+
+func remaining(limit, used int) int {
+	if limit <= 0 {
+		return Unlimited
+	}
+
+	return max(limit-used, 0)
+}
+
+// ResetsAt возвращает момент сброса суточной квоты, наступающий после now.
+func ResetsAt(now time.Time) time.Time {
+	y, m, d := now.UTC().Date()
+
+	return time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC)
+}
+
+// ExceededError возвращается, если создание ссылок превысило бы квоту пользователя.
+type ExceededError struct {
+	Usage     Usage
+	Requested int
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("превышена квота на создание ссылок: запрошено %d, доступно %d", e.Requested, e.Usage.Remaining())
+}
+
+// IsExceeded сообщает, что ошибка вызвана превышением квоты, и возвращает ее подробности.
+func IsExceeded(err error) (*ExceededError, bool) {
+	var exceeded *ExceededError
+
+	ok := errors.As(err, &exceeded)
+
+	return exceeded, ok
+}
+
+// Store хранит счетчики использования и индивидуальные квоты пользователей.
+type Store interface {
+	// Reserve атомарно увеличивает счетчики на n, если это не превышает limits.
+	// При превышении возвращает *ExceededError с текущим использованием.
+	Reserve(ctx context.Context, userID string, n int, limits Limits) (Usage, error)
+	// Release возвращает n ранее зарезервированных единиц, если ссылки так и не были созданы или удалены.
+	Release(ctx context.Context, userID string, n int) error
+	// Usage возвращает текущее использование без изменения счетчиков.
+	Usage(ctx context.Context, userID string) (Usage, error)
+	// Override возвращает индивидуальные квоты пользователя; незаданные поля равны -1.
+	Override(ctx context.Context, userID string) (Limits, error)
+}
+
+// Manager применяет глобальные квоты с учетом индивидуальных переопределений.
+type Manager struct {
+	store    Store
+	defaults Limits
+}
+
+func NewManager(store Store, defaults Limits) *Manager {
+	return &Manager{store: store, defaults: defaults}
+}
+
+// limits возвращает действующие квоты пользователя.
+func (m *Manager) limits(ctx context.Context, userID string) (Limits, error) {
+	override, err := m.store.Override(ctx, userID)
+
+	if err != nil {
+		return Limits{}, err
+	}
+
+	limits := m.defaults
+
+	if override.Daily >= 0 {
+		limits.Daily = override.Daily
+	}
+
+	if override.Total >= 0 {
+		limits.Total = override.Total
+	}
+
+	return limits, nil
+}
+
+// Reserve резервирует квоту на создание n ссылок.
+func (m *Manager) Reserve(ctx context.Context, userID string, n int) (Usage, error) {
+	limits, err := m.limits(ctx, userID)
+
+	if err != nil {
+		return Usage{}, err
+	}
+
+	return m.store.Reserve(ctx, userID, n, limits)
+}
+
+// Release возвращает квоту, зарезервированную под несозданные или удаленные ссылки.
+func (m *Manager) Release(ctx context.Context, userID string, n int) error {
+	return m.store.Release(ctx, userID, n)
+}
+
+// Usage возвращает использование квоты пользователем вместе с действующими ограничениями.
+func (m *Manager) Usage(ctx context.Context, userID string) (Usage, error) {
+	limits, err := m.limits(ctx, userID)
+
+	if err != nil {
+		return Usage{}, err
+	}
+
+	usage, err := m.store.Usage(ctx, userID)
+
+	if err != nil {
+		return Usage{}, err
+	}
+
+	usage.Limits = limits
+
+	return usage, nil
+}
@@ -0,0 +1,81 @@
+package quota
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// overrideStore подставляет индивидуальные квоты поверх MemoryStore.
+type overrideStore struct {
+	*MemoryStore
+	overrides map[string]Limits
+}
+
+func (o overrideStore) Override(_ context.Context, userID string) (Limits, error) {
+	if limits, ok := o.overrides[userID]; ok {
+		return limits, nil
+	}
+
+	return Limits{Daily: -1, Total: -1}, nil
+}
+
+func TestManagerReserve(t *testing.T) {
+	now := time.Date(2025, 1, 1, 23, 0, 0, 0, time.UTC)
+	memory := NewMemoryStore()
+	memory.now = func() time.Time { return now }
+
+	m := NewManager(overrideStore{
+		MemoryStore: memory,
+		overrides:   map[string]Limits{"vip": {Daily: 0, Total: -1}},
+	}, Limits{Daily: 2, Total: 3})
+
+	ctx := context.Background()
+
+	_, err := m.Reserve(ctx, "user", 2)
+	require.NoError(t, err)
+
+	_, err = m.Reserve(ctx, "user", 1)
+	exceeded, ok := IsExceeded(err)
+	require.True(t, ok, "Ожидается превышение суточной квоты")
+	assert.Equal(t, 0, exceeded.Usage.RemainingDaily())
+	assert.Equal(t, 1, exceeded.Usage.RemainingTotal())
+
+	// на следующие сутки суточный счетчик сбрасывается, общий — нет
+	now = now.Add(2 * time.Hour)
+
+	usage, err := m.Reserve(ctx, "user", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 0, usage.Remaining(), "Общая квота должна быть исчерпана")
+
+	_, err = m.Reserve(ctx, "user", 1)
+	_, ok = IsExceeded(err)
+	assert.True(t, ok, "Ожидается превышение общей квоты")
+
+	require.NoError(t, m.Release(ctx, "user", 1))
+
+	_, err = m.Reserve(ctx, "user", 1)
+	assert.NoError(t, err, "Возвращенная квота должна быть доступна снова")
+
+	// индивидуальная квота снимает суточное ограничение
+	_, err = m.Reserve(ctx, "vip", 3)
+	require.NoError(t, err)
+
+	usage, err = m.Usage(ctx, "vip")
+	require.NoError(t, err)
+	assert.Equal(t, Unlimited, usage.RemainingDaily())
+	assert.Equal(t, 0, usage.RemainingTotal())
+}
+
+func TestResetsAt(t *testing.T) {
+	moscow := time.FixedZone("MSK", 3*60*60)
+
+	assert.Equal(t,
+		time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC),
+		ResetsAt(time.Date(2025, 1, 2, 1, 0, 0, 0, moscow)),
+		"Сутки отсчитываются по UTC",
+	)
+}
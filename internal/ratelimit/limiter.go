@@ -0,0 +1,143 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/authenticator"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/clientip"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/logger"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/metrics"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/problem"
+)
+
+// Limiter применяет лимиты классов маршрутов к HTTP-запросам и gRPC-вызовам.
+type Limiter struct {
+	store    Store
+	limits   map[Class]Limit
+	resolver *clientip.Resolver
+	log      *zap.Logger
+}
+
+func NewLimiter(store Store, limits map[Class]Limit, resolver *clientip.Resolver, log *zap.Logger) *Limiter {
+	return &Limiter{store: store, limits: limits, resolver: resolver, log: log}
+}
+
+// key выбирает, кого ограничивать: пользователя с ранее выданной сессией или адрес клиента.
+// Только что выданной сессии не доверяем, иначе лимит обходится отказом от cookie.
+func (l *Limiter) key(ctx context.Context, class Class, ip func() string) string {
+	if userID, err := authenticator.FromContext(ctx); err == nil && !authenticator.IsNewSession(ctx) {
+		return string(class) + ":user:" + userID
+	}
+
+	return string(class) + ":ip:" + ip()
+}
+
+// allow возвращает решение по запросу. Ошибка хранилища не должна останавливать сервис,
+// поэтому в этом случае запрос пропускается.
+func (l *Limiter) allow(ctx context.Context, class Class, key string) (Result, bool) {
+	limit := l.limits[class]
+
+	if !limit.Enabled() {
+		return Result{}, false
+	}
+
+	result, err := l.store.Allow(ctx, key, limit)
+
+	if err != nil {
+		logger.WithContext(ctx, l.log).Error("Ошибка ограничителя частоты запросов", zap.String("class", string(class)), zap.Error(err))
+		return Result{}, false
+	}
+
+	if !result.Allowed {
+		metrics.RateLimited(string(class))
+	}
+
+	return result, true
+}
+
+// Middleware ограничивает частоту запросов к маршрутам класса class.
+// Должен подключаться после Auth, чтобы учитывать пользователя.
+func (l *Limiter) Middleware(class Class) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := l.key(r.Context(), class, func() string { return l.resolver.FromRequest(r).String() })
+			result, applied := l.allow(r.Context(), class, key)
+
+			if !applied {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			for name, value := range headers(result) {
+				w.Header().Set(name, value)
+			}
+
+			if !result.Allowed {
+				w.Header().Set("Retry-After", ceilSeconds(result.RetryAfter))
+				problem.Error(w, r, http.StatusTooManyRequests, problem.TypeRateLimited, "превышен лимит частоты запросов, повторите через "+ceilSeconds(result.RetryAfter)+" с")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// UnaryServerInterceptor ограничивает частоту gRPC-вызовов. methods сопоставляет полное имя метода с классом;
+// методы вне списка не ограничиваются. Должен стоять в цепочке после Auth.
+func (l *Limiter) UnaryServerInterceptor(methods map[string]Class) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		class, ok := methods[info.FullMethod]
+
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		key := l.key(ctx, class, func() string { return l.resolver.FromContext(ctx).String() })
+		result, applied := l.allow(ctx, class, key)
+
+		if !applied {
+			return handler(ctx, req)
+		}
+
+		md := metadata.New(nil)
+
+		for name, value := range headers(result) {
+			md.Set(name, value)
+		}
+
+		if !result.Allowed {
+			md.Set("retry-after", ceilSeconds(result.RetryAfter))
+			grpc.SetHeader(ctx, md)
+
+			return nil, status.Error(codes.ResourceExhausted, "превышен лимит запросов, повторите через "+ceilSeconds(result.RetryAfter)+" с")
+		}
+
+		grpc.SetHeader(ctx, md)
+
+		return handler(ctx, req)
+	}
+}
+
+// headers формирует заголовки RateLimit-* по черновику IETF «RateLimit header fields for HTTP».
+func headers(result Result) map[string]string {
+	return map[string]string{
+		"RateLimit-Limit":     strconv.Itoa(result.Limit),
+		"RateLimit-Remaining": strconv.Itoa(result.Remaining),
+		"RateLimit-Reset":     ceilSeconds(result.Reset),
+	}
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
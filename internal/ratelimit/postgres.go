@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// cleanupEvery — раз в сколько вызовов из таблицы удаляются давно не использованные корзины.
+const cleanupEvery = 1000
+
+// PostgresStore хранит корзины в таблице rate_limit_buckets, общей для всех экземпляров сервиса.
+// Время берется из базы, поэтому расхождение часов между экземплярами на лимит не влияет.
+// Используется clock_timestamp(), а не now(): now() — время начала транзакции, и после ожидания
+// блокировки строки оно оказалось бы раньше updated_at, записанного конкурентным запросом.
+type PostgresStore struct {
+	pool  *pgxpool.Pool
+	calls atomic.Uint64
+}
+
+func NewPostgresStore(pool *pgxpool.Pool) *PostgresStore {
+	return &PostgresStore{pool: pool}
+}
+
+func (p *PostgresStore) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	if p.calls.Add(1)%cleanupEvery == 0 {
+		p.pool.Exec(ctx, "DELETE FROM rate_limit_buckets WHERE updated_at < now() - interval '1 day'")
+	}
+
+	var result Result
+
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx,
+			"INSERT INTO rate_limit_buckets (key, tokens, updated_at) VALUES ($1, $2, clock_timestamp()) ON CONFLICT (key) DO NOTHING",
+			key, float64(limit.Burst),
+		)
+
+		if err != nil {
+			return err
+		}
+
+		var (
+			tokens    float64
+			updatedAt time.Time
+			now       time.Time
+		)
+
+		err = tx.QueryRow(ctx, "SELECT tokens, updated_at FROM rate_limit_buckets WHERE key = $1 FOR UPDATE", key).Scan(&tokens, &updatedAt)
+
+		if err != nil {
+			return err
+		}
+
+		// Время читается уже после получения блокировки, и им же помечается обновление корзины.
+		err = tx.QueryRow(ctx, "SELECT clock_timestamp()").Scan(&now)
+
+		if err != nil {
+			return err
+		}
+
+		tokens, result = take(tokens, now.Sub(updatedAt), limit)
+
+		_, err = tx.Exec(ctx, "UPDATE rate_limit_buckets SET tokens = $2, updated_at = $3 WHERE key = $1", key, tokens, now)
+
+		return err
+	})
+
+	return result, err
+}
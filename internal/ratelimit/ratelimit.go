@@ -0,0 +1,118 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Class — группа маршрутов с общим лимитом.
+type Class string
+
+const (
+	ClassCreate   Class = "create"
+	ClassRedirect Class = "redirect"
+	ClassAdmin    Class = "admin"
+)
+
+// Limit — параметры корзины токенов: Rate токенов в секунду, не более Burst накопленных.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Enabled сообщает, задан ли лимит. Нулевой Limit запросы не ограничивает.
+func (l Limit) Enabled() bool {
+	return l.Rate > 0 && l.Burst > 0
+}
+
+// ParseLimit разбирает лимит вида «N/unit» или «N/unit:burst», где unit — s, m или h.
+// Например, «60/m» — 60 запросов в минуту с запасом 60, «10/s:50» — 10 в секунду с запасом 50.
+// Пустая строка или «off» означают отсутствие лимита.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+
+	if s == "" || s == "off" {
+		return Limit{}, nil
+	}
+
+	rate, burst, hasBurst := strings.Cut(s, ":")
+	count, unit, ok := strings.Cut(rate, "/")
+
+	if !ok {
+		return Limit{}, fmt.Errorf("некорректный лимит %q: ожидается N/unit[:burst]", s)
+	}
+
+	n, err := strconv.Atoi(count)
+
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("некорректное количество запросов в лимите %q", s)
+	}
+
+	var period time.Duration
+
+	switch unit {
+	case "s":
+		period = time.Second
+	case "m":
+		period = time.Minute
+	case "h":
+		period = time.Hour
+	default:
+		return Limit{}, fmt.Errorf("некорректная единица времени в лимите %q: ожидается s, m или h", s)
+	}
+
+	limit := Limit{Rate: float64(n) / period.Seconds(), Burst: n}
+
+	if hasBurst {
+		b, err := strconv.Atoi(burst)
+
+		if err != nil || b <= 0 {
+			return Limit{}, fmt.Errorf("некорректный запас в лимите %q", s)
+		}
+
+		limit.Burst = b
+	}
+
+	return limit, nil
+}
+
+// Result — решение ограничителя по одному запросу.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	Reset      time.Duration
+}
+
+// Store хранит состояние корзин. Allow атомарно списывает токен из корзины key, если он есть.
+type Store interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// take пересчитывает корзину, в которой было tokens токенов elapsed назад, и пытается списать один токен.
+func take(tokens float64, elapsed time.Duration, limit Limit) (float64, Result) {
+	tokens = math.Min(float64(limit.Burst), tokens+elapsed.Seconds()*limit.Rate)
+
+	result := Result{Limit: limit.Burst}
+
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - tokens) / limit.Rate)
+	}
+
+	result.Remaining = int(tokens)
+	result.Reset = seconds((float64(limit.Burst) - tokens) / limit.Rate)
+
+	return tokens, result
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
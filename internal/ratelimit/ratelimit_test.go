@@ -0,0 +1,120 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/clientip"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Limit
+		wantErr bool
+	}{
+		{value: "60/m", want: Limit{Rate: 1, Burst: 60}},
+		{value: "10/s:50", want: Limit{Rate: 10, Burst: 50}},
+		{value: "3600/h", want: Limit{Rate: 1, Burst: 3600}},
+		{value: "", want: Limit{}},
+		{value: "off", want: Limit{}},
+		{value: "60", wantErr: true},
+		{value: "60/d", wantErr: true},
+		{value: "-1/s", wantErr: true},
+		{value: "10/s:0", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			limit, err := ParseLimit(test.value)
+
+			if test.wantErr {
+				assert.Error(t, err, "Ожидается ошибка разбора")
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.want, limit, "Лимит не совпадает с ожидаемым")
+		})
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+
+	limit := Limit{Rate: 1, Burst: 2}
+	ctx := context.Background()
+
+	for i := range 2 {
+		result, err := store.Allow(ctx, "k", limit)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed, "Запрос %d в пределах запаса должен пройти", i+1)
+	}
+
+	result, _ := store.Allow(ctx, "k", limit)
+	assert.False(t, result.Allowed, "Запрос сверх запаса должен быть отклонен")
+	assert.Equal(t, time.Second, result.RetryAfter, "Время до следующего токена не совпадает с ожидаемым")
+
+	result, _ = store.Allow(ctx, "другой", limit)
+	assert.True(t, result.Allowed, "Корзины разных ключей не должны влиять друг на друга")
+
+	now = now.Add(time.Second)
+
+	result, _ = store.Allow(ctx, "k", limit)
+	assert.True(t, result.Allowed, "За секунду должен накопиться токен")
+	assert.Equal(t, 0, result.Remaining)
+}
+
+func TestMiddleware(t *testing.T) {
+	resolver, err := clientip.NewResolver([]string{"10.0.0.1"})
+	require.NoError(t, err)
+
+	limiter := NewLimiter(NewMemoryStore(), map[Class]Limit{ClassCreate: {Rate: 0.1, Burst: 1}}, resolver, zap.NewNop())
+
+	handler := limiter.Middleware(ClassCreate)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	send := func(remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/shorten", nil)
+		req.RemoteAddr = remoteAddr
+
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		return w
+	}
+
+	w := send("192.0.2.1:1234", "")
+	assert.Equal(t, http.StatusCreated, w.Code, "Код ответа не совпадает с ожидаемым")
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+
+	w = send("192.0.2.1:1234", "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "Код ответа не совпадает с ожидаемым")
+	assert.Equal(t, "10", w.Header().Get("Retry-After"))
+
+	// клиент не может сменить адрес подделкой заголовка: прокси ему не доверен
+	w = send("192.0.2.1:1234", "198.51.100.7")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "Поддельный X-Forwarded-For не должен обходить лимит")
+
+	// за доверенным прокси учитывается адрес из X-Forwarded-For
+	w = send("10.0.0.1:5555", "198.51.100.7")
+	assert.Equal(t, http.StatusCreated, w.Code, "Клиент за доверенным прокси имеет собственный лимит")
+
+	w = send("10.0.0.1:5555", "198.51.100.7")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "Код ответа не совпадает с ожидаемым")
+}
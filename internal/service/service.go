@@ -18,78 +18,348 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/audit"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/blocklist"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/clientip"
 	"github.com/flash1nho/go-musthave-shortener-tpl/internal/config"
 	"github.com/flash1nho/go-musthave-shortener-tpl/internal/handler"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/health"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/metrics"
 	"github.com/flash1nho/go-musthave-shortener-tpl/internal/middlewares"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/openapi"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/ratelimit"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/storage"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/tracing"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
-	"github.com/hashicorp/go-retryablehttp"
+	"github.com/jackc/pgx/v5/pgxpool"
 
 	"go.uber.org/zap"
 )
 
 type Service struct {
-	handler       *handler.Handler
-	gHandler      *pb.GrpcHandler
-	servers       []config.Server
-	log           *zap.Logger
-	auditFile     string
-	auditURL      string
-	enableHTTPS   bool
-	trustedSubnet string
+	handler        *handler.Handler
+	gHandler       *pb.GrpcHandler
+	servers        []config.Server
+	log            *zap.Logger
+	enableHTTPS    bool
+	trustedSubnets clientip.Subnets
+	metricsAddr    string
+	audit          *audit.Dispatcher
+	auditFiles     []*audit.FileSink
+	health         *health.Checker
+	limiter        *ratelimit.Limiter
+	resolver       *clientip.Resolver
+	security       config.Security
 }
 
 func NewService(handler *handler.Handler, gHandler *pb.GrpcHandler, settings config.SettingsObject) *Service {
 	servers := []config.Server{settings.Server1, settings.Server2}
 
-	return &Service{
-		handler:       handler,
-		gHandler:      gHandler,
-		servers:       servers,
-		log:           settings.Log,
-		auditFile:     settings.AuditFile,
-		auditURL:      settings.AuditURL,
-		enableHTTPS:   settings.EnableHTTPS,
-		trustedSubnet: settings.TrustedSubnet,
+	s := &Service{
+		handler:     handler,
+		gHandler:    gHandler,
+		servers:     servers,
+		log:         settings.Log,
+		enableHTTPS: settings.EnableHTTPS,
+		metricsAddr: settings.MetricsAddr,
+		security:    settings.Security,
+		audit: audit.NewDispatcher(audit.Options{
+			QueueSize:     settings.AuditQueue.Size,
+			BatchSize:     settings.AuditQueue.BatchSize,
+			FlushInterval: settings.AuditQueue.FlushInterval,
+			Overflow:      settings.AuditQueue.Overflow,
+		}, settings.Log),
+		health: health.NewChecker(health.DefaultTimeout),
 	}
+
+	// События аудита формирует фасад, поэтому HTTP, REST-мост и gRPC аудируются одинаково.
+	handler.Facade.Audit = s.audit
+	handler.Health = s.health
+	handler.AuditQueues = s.audit.Stats
+
+	store := handler.Facade.Store
+
+	s.health.Register("storage", store.Ping)
+
+	if store.Pool != nil {
+		s.health.Register("migrations", store.CheckMigrations)
+	}
+
+	s.registerAuditSinks(settings, store.Pool)
+
+	resolver, err := clientip.NewResolver(settings.TrustedProxies)
+
+	if err != nil {
+		s.log.Error("Ошибка разбора списка доверенных прокси", zap.Error(err))
+		resolver, _ = clientip.NewResolver(nil)
+	}
+
+	s.trustedSubnets, err = clientip.ParseSubnets(settings.TrustedSubnets)
+
+	// Открывать служебные маршруты всем из-за опечатки в настройке небезопасно, поэтому
+	// при ошибке они закрываются для всех клиентов, как и без заданных подсетей.
+	if err != nil {
+		s.log.Error("Ошибка разбора доверенных подсетей, служебные маршруты закрыты", zap.Error(err))
+		s.trustedSubnets = nil
+	} else if len(s.trustedSubnets) == 0 {
+		s.log.Warn("Доверенные подсети не заданы, служебные маршруты закрыты для всех клиентов")
+	}
+
+	s.resolver = resolver
+	s.limiter = newLimiter(settings, store.Pool, resolver, s.log)
+
+	return s
+}
+
+// auditSinks возвращает приемники аудита из настроек: -audit-file и -audit-url без фильтров,
+// затем список audit_sinks. Пустые имена заменяются типом, повторы получают числовой суффикс.
+func auditSinks(settings config.SettingsObject) []config.AuditSink {
+	var sinks []config.AuditSink
+
+	if settings.AuditFile != "" {
+		sinks = append(sinks, config.AuditSink{Type: "file", Address: settings.AuditFile})
+	}
+
+	if settings.AuditURL != "" {
+		sinks = append(sinks, config.AuditSink{Type: "url", Address: settings.AuditURL})
+	}
+
+	sinks = append(sinks, settings.AuditSinks...)
+	seen := make(map[string]int, len(sinks))
+
+	for i := range sinks {
+		if sinks[i].Name == "" {
+			sinks[i].Name = sinks[i].Type
+		}
+
+		seen[sinks[i].Name]++
+
+		if n := seen[sinks[i].Name]; n > 1 {
+			sinks[i].Name = fmt.Sprintf("%s_%d", sinks[i].Name, n)
+		}
+	}
+
+	return sinks
+}
+
+// registerAuditSinks подключает приемники аудита к диспетчеру и пробам готовности.
+// Приемник, который не удалось создать, отключается, но не мешает запуску сервиса.
+//
+// Журнал /api/internal/audit читается из приемника без фильтра: первого postgres, а без него — первого
+// файла, ведь в базе выборка идет по индексам, а не перебором сегментов. Приемник с фильтром
+// выбирается, только если других нет: тогда журнал неполон, и фильтр сообщается в ответе.
+func (s *Service) registerAuditSinks(settings config.SettingsObject, pool *pgxpool.Pool) {
+	var (
+		auditLog  audit.Querier
+		logFilter audit.Filter
+		logRank   int
+	)
+
+	// useLog запоминает приемник для чтения журнала, если он лучше выбранного.
+	useLog := func(querier audit.Querier, filter audit.Filter, db bool) {
+		rank := 1
+
+		if db {
+			rank++
+		}
+
+		if filter.IsZero() {
+			rank += 2
+		}
+
+		if rank > logRank {
+			auditLog, logFilter, logRank = querier, filter, rank
+		}
+	}
+
+	for _, cfg := range auditSinks(settings) {
+		log := s.log.With(zap.String("sink", cfg.Name), zap.String("type", cfg.Type))
+		filter := audit.Filter{Actions: cfg.Actions, Statuses: cfg.Statuses}
+
+		var (
+			sink  audit.Sink
+			check health.CheckFunc
+		)
+
+		switch cfg.Type {
+		case "file":
+			file, err := audit.NewFileSink(cfg.Address, []byte(settings.AuditHMACKey), audit.Rotation(settings.AuditRotation), s.log)
+
+			if err != nil {
+				log.Error("Ошибка открытия файла аудита, приемник отключен", zap.Error(err))
+				continue
+			}
+
+			s.auditFiles = append(s.auditFiles, file)
+			sink, check = file, file.Check
+			useLog(file, filter, false)
+		case "url":
+			remote := audit.NewHTTPSink(cfg.Address)
+			sink, check = remote, remote.Check
+		case "syslog":
+			remote, err := audit.NewSyslogSink(cfg.Address, "shortener")
+
+			if err != nil {
+				log.Error("Ошибка настройки syslog, приемник отключен", zap.Error(err))
+				continue
+			}
+
+			sink = remote
+		case "postgres":
+			if pool == nil {
+				log.Error("Аудит в Postgres требует базы данных (-d), приемник отключен")
+				continue
+			}
+
+			db := audit.NewPostgresSink(pool)
+			sink, check = db, db.Check
+			useLog(db, filter, true)
+		default:
+			log.Error("Неизвестный тип приемника аудита, приемник отключен")
+			continue
+		}
+
+		s.audit.Register(cfg.Name, sink, filter)
+
+		if check != nil {
+			s.health.Register("audit_"+cfg.Name, check)
+		}
+	}
+
+	if auditLog != nil && !logFilter.IsZero() {
+		s.log.Warn("Журнал аудита читается из приемника с фильтром и содержит не все события",
+			zap.Strings("actions", logFilter.Actions), zap.Strings("statuses", logFilter.Statuses))
+	}
+
+	s.handler.Facade.AuditLog, s.handler.Facade.AuditLogFilter = auditLog, logFilter
+}
+
+// newLimiter собирает ограничитель частоты запросов. Некорректный лимит отключает свой класс,
+// но не мешает запуску сервиса.
+func newLimiter(settings config.SettingsObject, pool *pgxpool.Pool, resolver *clientip.Resolver, log *zap.Logger) *ratelimit.Limiter {
+	limits := make(map[ratelimit.Class]ratelimit.Limit)
+
+	for class, value := range map[ratelimit.Class]string{
+		ratelimit.ClassCreate:   settings.RateLimit.Create,
+		ratelimit.ClassRedirect: settings.RateLimit.Redirect,
+		ratelimit.ClassAdmin:    settings.RateLimit.Admin,
+	} {
+		limit, err := ratelimit.ParseLimit(value)
+
+		if err != nil {
+			log.Error("Ошибка разбора лимита частоты запросов", zap.String("class", string(class)), zap.Error(err))
+			continue
+		}
+
+		limits[class] = limit
+	}
+
+	var store ratelimit.Store = ratelimit.NewMemoryStore()
+
+	if settings.RateLimit.Backend == "postgres" {
+		if pool != nil {
+			store = ratelimit.NewPostgresStore(pool)
+		} else {
+			log.Warn("Хранилище лимитов postgres недоступно без базы данных, лимиты хранятся в памяти")
+		}
+	}
+
+	return ratelimit.NewLimiter(store, limits, resolver, log)
 }
 
 func (s *Service) mainRouter() http.Handler {
 	r := chi.NewRouter()
 
-	r.Use(middleware.Logger)
-	r.Use(middlewares.Decompressor)
-	r.Use(middlewares.Auth)
+	r.Use(middlewares.RequestID)
+	r.Use(s.resolver.Middleware)
+	r.Use(middlewares.AccessLog(s.log))
+	r.Use(metrics.Middleware)
+	r.Use(tracing.Middleware)
+	r.Use(middlewares.SecurityHeaders(s.enableHTTPS, s.security.HSTSMaxAge))
+	r.Use(middlewares.CORS(s.security.AllowedOrigins))
+	r.Use(middlewares.Compressor(middlewares.DefaultCompressMinSize))
 
-	r.Get("/ping", s.handler.Ping)
-	r.Post("/api/shorten/batch", s.handler.APIShortenBatchPostURLHandler)
-	r.Get("/api/user/urls", s.handler.APIUserURLHandler)
-	r.Delete("/api/user/urls", s.handler.APIUserDeleteURLHandler)
+	// Пробы оркестратора не требуют авторизации и не получают cookie сессии.
+	r.Get("/healthz", s.health.Liveness)
+	r.Get("/readyz", s.health.Readiness)
+
+	// Документация API открыта без сессии, чтобы просмотр не создавал пользователей.
+	r.Get(openapi.SpecPath, openapi.SpecHandler)
+	r.Get(openapi.DocsPath, openapi.DocsHandler().ServeHTTP)
+	r.Get(openapi.DocsPath+"/*", openapi.DocsHandler().ServeHTTP)
 
 	r.Group(func(r chi.Router) {
-		subject := &middlewares.AuditSubject{}
+		r.Use(middlewares.Decompressor)
 
-		if s.auditFile != "" {
-			subject.Register(&middlewares.FileObserver{FilePath: s.auditFile, Log: s.log})
+		if s.security.CSRF {
+			r.Use(middlewares.CSRF(s.enableHTTPS))
 		}
 
-		if s.auditURL != "" {
-			subject.Register(&middlewares.URLObserver{URL: s.auditURL, Log: s.log, Client: retryablehttp.NewClient()})
-		}
+		r.Use(middlewares.SessionAuth(s.enableHTTPS))
 
-		r.Use(middlewares.Audit(subject))
-		r.Post("/", s.handler.PostURLHandler)
-		r.Post("/api/shorten", s.handler.APIShortenPostURLHandler)
-		r.Get("/{id}", s.handler.GetURLHandler)
+		s.apiRoutes(r)
 	})
 
+	return r
+}
+
+func (s *Service) apiRoutes(r chi.Router) {
+	limitCreate := s.limiter.Middleware(ratelimit.ClassCreate)
+	limitRedirect := s.limiter.Middleware(ratelimit.ClassRedirect)
+	limitAdmin := s.limiter.Middleware(ratelimit.ClassAdmin)
+
+	r.Get("/ping", s.handler.Ping)
+	r.With(limitCreate).Post("/api/shorten/batch", s.handler.APIShortenBatchPostURLHandler)
+	r.Get("/api/user/urls", s.handler.APIUserURLHandler)
+	r.Get("/api/user/quota", s.handler.APIUserQuotaHandler)
+	r.Delete("/api/user/urls", s.handler.APIUserDeleteURLHandler)
+	r.Get("/api/user/webhooks", s.handler.APIUserWebhooksHandler)
+	r.Post("/api/user/webhooks", s.handler.APIUserWebhookCreateHandler)
+	r.Delete("/api/user/webhooks/{id}", s.handler.APIUserWebhookDeleteHandler)
+	r.Get("/api/user/webhooks/dead-letters", s.handler.APIUserWebhookDeadLettersHandler)
+	r.Post("/api/user/webhooks/deliveries/{id}/redeliver", s.handler.APIUserWebhookRedeliverHandler)
+
+	r.With(limitCreate).Post("/", s.handler.PostURLHandler)
+	r.With(limitCreate).Post("/api/shorten", s.handler.APIShortenPostURLHandler)
+	r.With(limitRedirect).Get("/{id}", s.handler.GetURLHandler)
+
 	r.Group(func(r chi.Router) {
-		r.Use(middlewares.TrustedSubnet(s.trustedSubnet))
+		r.Use(middlewares.TrustedSubnet(s.trustedSubnets))
+		r.Use(limitAdmin)
 		r.Get("/api/internal/stats", s.handler.APIInternalStats)
+		r.Get("/api/internal/health", s.handler.APIInternalHealthHandler)
+		r.Get("/api/internal/links/{code}", s.handler.APIInternalLinkHandler)
+		r.Delete("/api/internal/links/{code}", s.handler.APIInternalLinkDeleteHandler)
+		r.Post("/api/internal/links/{code}/block", s.handler.APIInternalLinkBlockHandler)
+		r.Delete("/api/internal/links/{code}/block", s.handler.APIInternalLinkUnblockHandler)
+		r.Get("/api/internal/users/{id}", s.handler.APIInternalUserHandler)
+		r.Post("/api/internal/users/{id}/disable", s.handler.APIInternalUserDisableHandler)
+		r.Delete("/api/internal/users/{id}/disable", s.handler.APIInternalUserEnableHandler)
+		r.Get("/api/internal/audit", s.handler.APIInternalAuditHandler)
+		r.Get("/api/internal/blocklist", s.handler.APIInternalBlocklistHandler)
+		r.Post("/api/internal/blocklist", s.handler.APIInternalBlocklistAddHandler)
+		r.Delete("/api/internal/blocklist", s.handler.APIInternalBlocklistDeleteHandler)
 	})
 
-	return r
+	gateway, err := pb.NewGateway(context.Background(), s.gHandler)
+
+	if err != nil {
+		s.log.Error("Ошибка инициализации REST-моста gRPC", zap.Error(err))
+	} else {
+		r.Group(func(r chi.Router) {
+			r.Use(middlewares.TrustedSubnet(s.trustedSubnets))
+			r.Use(limitAdmin)
+			r.Handle(pb.GatewayPrefix+"/internal/*", gateway)
+		})
+
+		// REST-мост вызывает сервис в обход gRPC-перехватчиков, поэтому лимиты навешиваются на маршруты.
+		r.With(limitCreate).Post(pb.GatewayPrefix+"/shorten", gateway.ServeHTTP)
+		r.With(limitCreate).Post(pb.GatewayPrefix+"/shorten/batch", gateway.ServeHTTP)
+		r.With(limitRedirect).Get(pb.GatewayPrefix+"/expand/{id}", gateway.ServeHTTP)
+
+		r.Mount(pb.GatewayPrefix, gateway)
+	}
 }
 
 func runServer(ctx context.Context, s *Service, addr string) {
@@ -143,7 +413,20 @@ func runGrpcServer(ctx context.Context, s *Service) {
 	creds := insecure.NewCredentials()
 	grpcServer := grpc.NewServer(
 		grpc.Creds(creds),
-		grpc.UnaryInterceptor(pb.Auth),
+		grpc.ChainUnaryInterceptor(
+			pb.RequestID,
+			s.resolver.UnaryServerInterceptor,
+			metrics.UnaryServerInterceptor,
+			tracing.UnaryServerInterceptor,
+			pb.Auth,
+			s.limiter.UnaryServerInterceptor(map[string]ratelimit.Class{
+				pb.ShortenerService_ShortenURL_FullMethodName:   ratelimit.ClassCreate,
+				pb.ShortenerService_ShortenBatch_FullMethodName: ratelimit.ClassCreate,
+				pb.ShortenerService_ExpandURL_FullMethodName:    ratelimit.ClassRedirect,
+				pb.ShortenerService_GetStats_FullMethodName:     ratelimit.ClassAdmin,
+			}),
+			pb.TrustedSubnet(s.trustedSubnets, pb.ShortenerService_GetStats_FullMethodName),
+		),
 	)
 
 	go func() {
@@ -174,12 +457,74 @@ func runGrpcServer(ctx context.Context, s *Service) {
 	}
 }
 
+// runAdminServer запускает служебный HTTP-сервер с метриками Prometheus на отдельном адресе.
+func runAdminServer(ctx context.Context, s *Service) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.HandleFunc("/healthz", s.health.Liveness)
+	mux.HandleFunc("/readyz", s.health.Readiness)
+
+	server := &http.Server{
+		Addr:         s.metricsAddr,
+		Handler:      mux,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		s.log.Info(fmt.Sprintf("Сервер метрик запущен на http://%s/metrics", server.Addr))
+
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			s.log.Error("Ошибка запуска сервера метрик", zap.String("http://", server.Addr), zap.Error(err))
+		}
+	}()
+
+	<-ctx.Done()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		s.log.Error("Ошибка завершения работы сервера метрик", zap.Error(err))
+	}
+}
+
+// reopenOnHangup заново открывает файлы аудита по SIGHUP, чтобы после внешней ротации (logrotate)
+// запись шла в новые файлы, а не в переименованные.
+func reopenOnHangup(ctx context.Context, sinks []*audit.FileSink, log *zap.Logger) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			for _, sink := range sinks {
+				if err := sink.Reopen(); err != nil {
+					log.Error("Ошибка повторного открытия файла аудита", zap.Error(err))
+				}
+			}
+
+			log.Info("Файлы аудита открыты заново по SIGHUP")
+		}
+	}
+}
+
 func (s *Service) Run() {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
 	defer stop()
 
 	g, ctx := errgroup.WithContext(ctx)
 
+	// Готовность снимается сразу при получении сигнала, до остановки серверов,
+	// чтобы балансировщик перестал направлять новые запросы.
+	context.AfterFunc(ctx, func() {
+		s.health.SetShuttingDown()
+		s.log.Info("Сервис помечен как не готовый к работе")
+	})
+
 	for _, server := range slices.Compact(s.servers) {
 		srv := server
 
@@ -194,10 +539,53 @@ func (s *Service) Run() {
 		return nil
 	})
 
+	if s.metricsAddr != "" {
+		g.Go(func() error {
+			runAdminServer(ctx, s)
+			return nil
+		})
+	}
+
+	if len(s.auditFiles) > 0 {
+		g.Go(func() error {
+			reopenOnHangup(ctx, s.auditFiles, s.log)
+			return nil
+		})
+	}
+
+	if hooks := s.handler.Facade.Webhooks; hooks != nil {
+		g.Go(func() error {
+			hooks.Run(ctx)
+			return nil
+		})
+	}
+
+	if list := s.handler.Facade.Blocklist; list != nil {
+		g.Go(func() error {
+			list.Watch(ctx, blocklist.ReloadInterval)
+			return nil
+		})
+	}
+
+	g.Go(func() error {
+		s.handler.Facade.Store.RunStatsFlush(ctx, storage.StatsFlushInterval, func(err error) {
+			s.log.Error("Ошибка записи статистики", zap.Error(err))
+		})
+		return nil
+	})
+
 	if err := g.Wait(); err != nil && !errors.Is(err, context.Canceled) {
 		s.log.Error("Работа завершена с ошибкой", zap.Error(err))
 	}
 
+	// Серверы остановлены, новых событий аудита не будет: доставляем накопленные.
+	auditCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.audit.Close(auditCtx); err != nil {
+		s.log.Error("Ошибка завершения доставки аудита", zap.Error(err))
+	}
+
 	s.log.Info("Сохранение данных в хранилище...")
 
 	if err := s.handler.Facade.Store.Close(); err != nil {
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/audit"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/config"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/facade"
+	pb "github.com/flash1nho/go-musthave-shortener-tpl/internal/grpc"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/handler"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/openapi"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/storage"
+)
+
+func testService(t *testing.T) *Service {
+	t.Helper()
+
+	store, err := storage.NewStorage("", "")
+	require.NoError(t, err)
+
+	server := config.Server{Addr: config.DefaultHost, BaseURL: config.DefaultURL}
+	settings := config.SettingsObject{Server1: server, Server2: server, Log: zap.NewNop()}
+	f := facade.NewFacade(store, server.BaseURL)
+
+	return NewService(handler.NewHandler(f, settings), pb.NewHandler(f), settings)
+}
+
+// specOperations возвращает множество операций спецификации в виде "МЕТОД путь".
+func specOperations(t *testing.T) map[string]bool {
+	t.Helper()
+
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+
+	require.NoError(t, json.Unmarshal(openapi.Spec, &spec), "Спецификация не разбирается")
+
+	operations := make(map[string]bool)
+
+	for path, item := range spec.Paths {
+		for method := range item {
+			if method == "parameters" || method == "summary" || method == "description" {
+				continue
+			}
+
+			operations[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	return operations
+}
+
+// TestOpenAPIContract сверяет маршруты основного роутера со спецификацией в обе стороны.
+// Маршруты с подстановкой "/*" (REST-мост gRPC, статика документации) обслуживаются вложенным
+// обработчиком, поэтому их операции перечислены в спецификации по отдельности.
+func TestOpenAPIContract(t *testing.T) {
+	s := testService(t)
+	operations := specOperations(t)
+
+	var wildcards []string
+
+	routes, ok := s.mainRouter().(chi.Routes)
+	require.True(t, ok)
+
+	err := chi.Walk(routes, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if prefix, found := strings.CutSuffix(route, "/*"); found {
+			wildcards = append(wildcards, prefix+"/")
+			return nil
+		}
+
+		assert.True(t, operations[method+" "+route], "Маршрут %s %s не описан в спецификации", method, route)
+
+		delete(operations, method+" "+route)
+
+		return nil
+	})
+
+	require.NoError(t, err)
+
+	for operation := range operations {
+		_, path, _ := strings.Cut(operation, " ")
+
+		covered := false
+
+		for _, prefix := range wildcards {
+			if strings.HasPrefix(path, prefix) {
+				covered = true
+				break
+			}
+		}
+
+		assert.True(t, covered, "Операция %s из спецификации не обслуживается роутером", operation)
+	}
+}
+
+func TestOpenAPIRoutes(t *testing.T) {
+	router := testService(t).mainRouter()
+
+	tests := []struct {
+		name        string
+		path        string
+		code        int
+		contentType string
+	}{
+		{name: "спецификация", path: openapi.SpecPath, code: http.StatusOK, contentType: "application/json"},
+		{name: "перенаправление на документацию", path: openapi.DocsPath, code: http.StatusMovedPermanently},
+		{name: "страница документации", path: openapi.DocsPath + "/", code: http.StatusOK, contentType: "text/html; charset=utf-8"},
+		{name: "настройка Swagger UI", path: openapi.DocsPath + "/swagger-initializer.js", code: http.StatusOK, contentType: "text/javascript; charset=utf-8"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, test.path, nil)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, r)
+
+			assert.Equal(t, test.code, w.Code, "Код ответа не совпадает с ожидаемым")
+
+			if test.contentType != "" {
+				assert.Equal(t, test.contentType, w.Header().Get("Content-Type"), "Тип содержимого не совпадает с ожидаемым")
+			}
+
+			assert.Empty(t, w.Result().Cookies(), "Документация не должна выдавать cookie сессии")
+		})
+	}
+}
+
+func TestAuditSinks(t *testing.T) {
+	settings := config.SettingsObject{
+		AuditFile: "audit.log",
+		AuditURL:  "http://audit.local",
+		AuditSinks: []config.AuditSink{
+			{Type: "syslog", Address: "udp://localhost:514", Statuses: []string{"failure"}},
+			{Type: "file", Address: "failures.log"},
+			{Name: "db", Type: "postgres"},
+		},
+	}
+
+	var names []string
+
+	for _, sink := range auditSinks(settings) {
+		names = append(names, sink.Name)
+	}
+
+	assert.Equal(t, []string{"file", "url", "syslog", "file_2", "db"}, names, "Имена приемников не совпадают с ожидаемыми")
+}
+
+func TestAuditLogSink(t *testing.T) {
+	dir := t.TempDir()
+	failures := config.AuditSink{Name: "failures", Type: "file", Address: filepath.Join(dir, "failures.log"), Statuses: []string{"failure"}}
+	all := config.AuditSink{Name: "all", Type: "file", Address: filepath.Join(dir, "all.log")}
+
+	// описываем набор данных: приемники, индекс файла журнала и фильтр журнала
+	testCases := []struct {
+		name   string
+		sinks  []config.AuditSink
+		log    int
+		filter audit.Filter
+	}{
+		{name: "приемник без фильтра важнее первого", sinks: []config.AuditSink{failures, all}, log: 1},
+		{name: "только приемник с фильтром", sinks: []config.AuditSink{failures}, log: 0, filter: audit.Filter{Statuses: []string{"failure"}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := testService(t)
+			s.registerAuditSinks(config.SettingsObject{AuditSinks: tc.sinks}, nil)
+
+			t.Cleanup(func() {
+				assert.NoError(t, s.audit.Close(context.Background()))
+			})
+
+			require.Len(t, s.auditFiles, len(tc.sinks))
+			assert.Same(t, s.auditFiles[tc.log], s.handler.Facade.AuditLog, "Журнал читается не из того приемника")
+			assert.Equal(t, tc.filter, s.handler.Facade.AuditLogFilter, "Фильтр журнала не совпадает с ожидаемым")
+		})
+	}
+}
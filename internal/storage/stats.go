@@ -0,0 +1,483 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/metrics"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/tracing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// StatsRetention — сколько хранятся часовые счетчики переходов и активности; больше окно статистики быть не может.
+	StatsRetention = 30 * 24 * time.Hour
+	// StatsFlushInterval — как часто счетчики из памяти записываются в базу.
+	StatsFlushInterval = 10 * time.Second
+)
+
+// Stats — статистика сервиса: общие счетчики и ряды за окно для панели эксплуатации.
+type Stats struct {
+	URLs  int `json:"urls"`
+	Users int `json:"users"`
+	// From и To — окно статистики [From, To); Bucket — шаг рядов: hour или day.
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+	Bucket string    `json:"bucket"`
+	// ActiveURLs и DeletedURLs — все ссылки, независимо от окна.
+	ActiveURLs  int `json:"active_urls"`
+	DeletedURLs int `json:"deleted_urls"`
+	// ActiveUsers — пользователи, которые в окне создавали или удаляли ссылки либо переходили по ним.
+	ActiveUsers int          `json:"active_users"`
+	Series      []StatsPoint `json:"series"`
+	TopLinks    []TopLink    `json:"top_links"`
+	TopDomains  []TopDomain  `json:"top_domains"`
+}
+
+// StatsPoint — число созданных ссылок и переходов за один шаг окна, начинающийся в Time.
+type StatsPoint struct {
+	Time      time.Time `json:"time"`
+	Created   int       `json:"created"`
+	Redirects int       `json:"redirects"`
+}
+
+// TopLink — ссылка и число переходов по ней в окне.
+type TopLink struct {
+	ShortCode   string `json:"short_code"`
+	OriginalURL string `json:"original_url"`
+	Redirects   int    `json:"redirects"`
+}
+
+// TopDomain — домен назначения и число переходов на него в окне.
+type TopDomain struct {
+	Domain    string `json:"domain"`
+	Redirects int    `json:"redirects"`
+}
+
+// StatsQuery задает окно статистики [From, To), выровненное по Bucket (час или сутки),
+// и длину списков самых популярных ссылок и доменов.
+type StatsQuery struct {
+	From   time.Time
+	To     time.Time
+	Bucket time.Duration
+	Top    int
+}
+
+// activity — часовые счетчики переходов по ссылкам и активные пользователи. Без базы это
+// единственное хранилище статистики, с базой — буфер до записи в нее FlushStats.
+type activity struct {
+	mu     sync.Mutex
+	clicks map[time.Time]map[string]int
+	users  map[time.Time]map[string]struct{}
+}
+
+func newActivity() *activity {
+	return &activity{
+		clicks: make(map[time.Time]map[string]int),
+		users:  make(map[time.Time]map[string]struct{}),
+	}
+}
+
+// user отмечает активность пользователя в часе hour. Вызывается под a.mu.
+func (a *activity) user(hour time.Time, userID string) {
+	if userID == "" {
+		return
+	}
+
+	if a.users[hour] == nil {
+		a.users[hour] = make(map[string]struct{})
+	}
+
+	a.users[hour][userID] = struct{}{}
+}
+
+// prune удаляет часы старше StatsRetention. Вызывается под a.mu.
+func (a *activity) prune(now time.Time) {
+	since := now.Add(-StatsRetention)
+
+	maps.DeleteFunc(a.clicks, func(hour time.Time, _ map[string]int) bool { return hour.Before(since) })
+	maps.DeleteFunc(a.users, func(hour time.Time, _ map[string]struct{}) bool { return hour.Before(since) })
+}
+
+// RecordRedirect учитывает переход по ссылке пользователем userID; пустой userID — переход без сессии.
+func (s *Storage) RecordRedirect(shortURL, userID string) {
+	now := time.Now().UTC()
+	hour := now.Truncate(time.Hour)
+
+	s.activity.mu.Lock()
+	defer s.activity.mu.Unlock()
+
+	if s.activity.clicks[hour] == nil {
+		s.activity.clicks[hour] = make(map[string]int)
+		s.activity.prune(now)
+	}
+
+	s.activity.clicks[hour][shortURL]++
+	s.activity.user(hour, userID)
+}
+
+// RecordActivity отмечает, что пользователь создал или удалил ссылки.
+func (s *Storage) RecordActivity(userID string) {
+	s.activity.mu.Lock()
+	defer s.activity.mu.Unlock()
+
+	s.activity.user(time.Now().UTC().Truncate(time.Hour), userID)
+}
+
+// FlushStats записывает накопленные в памяти счетчики в базу и удаляет из нее счетчики
+// старше StatsRetention. При ошибке счетчики возвращаются в память до следующей попытки.
+func (s *Storage) FlushStats(ctx context.Context) error {
+	if s.Pool == nil {
+		return nil
+	}
+
+	s.activity.mu.Lock()
+	clicks, users := s.activity.clicks, s.activity.users
+	s.activity.clicks, s.activity.users = make(map[time.Time]map[string]int), make(map[time.Time]map[string]struct{})
+	s.activity.mu.Unlock()
+
+	batch := &pgx.Batch{}
+
+	for hour, links := range clicks {
+		for shortURL, n := range links {
+			batch.Queue(`INSERT INTO link_clicks (hour, short_url, clicks) VALUES ($1, $2, $3)
+				ON CONFLICT (hour, short_url) DO UPDATE SET clicks = link_clicks.clicks + EXCLUDED.clicks`, hour, shortURL, n)
+		}
+	}
+
+	for hour, ids := range users {
+		for userID := range ids {
+			batch.Queue(`INSERT INTO user_activity (hour, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, hour, userID)
+		}
+	}
+
+	since := time.Now().UTC().Add(-StatsRetention)
+	batch.Queue(`DELETE FROM link_clicks WHERE hour < $1`, since)
+	batch.Queue(`DELETE FROM user_activity WHERE hour < $1`, since)
+
+	// Пакет выполняется одной транзакцией, поэтому при ошибке счетчики не будут учтены дважды.
+	err := pgx.BeginFunc(ctx, s.Pool, func(tx pgx.Tx) error {
+		return tx.SendBatch(ctx, batch).Close()
+	})
+
+	if err != nil {
+		s.activity.mu.Lock()
+		defer s.activity.mu.Unlock()
+
+		for hour, links := range clicks {
+			if s.activity.clicks[hour] == nil {
+				s.activity.clicks[hour] = make(map[string]int)
+			}
+
+			for shortURL, n := range links {
+				s.activity.clicks[hour][shortURL] += n
+			}
+		}
+
+		for hour, ids := range users {
+			for userID := range ids {
+				s.activity.user(hour, userID)
+			}
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// RunStatsFlush раз в interval записывает счетчики в базу, пока не отменен ctx. Оставшиеся
+// счетчики записывает Close. Без базы сразу возвращается.
+func (s *Storage) RunStatsFlush(ctx context.Context, interval time.Duration, onError func(error)) {
+	if s.Pool == nil {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.FlushStats(ctx); err != nil {
+				onError(err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// GetStats возвращает общие счетчики ссылок и пользователей и статистику за окно q.
+func (s *Storage) GetStats(ctx context.Context, q StatsQuery) (*Stats, error) {
+	defer metrics.ObserveStorage("GetStats", time.Now())
+
+	ctx, span := tracing.Start(ctx, "storage.GetStats")
+	defer span.End()
+
+	stats := &Stats{From: q.From, To: q.To, Bucket: "hour"}
+
+	if q.Bucket >= 24*time.Hour {
+		stats.Bucket = "day"
+	}
+
+	var (
+		created   map[time.Time]int
+		redirects map[time.Time]int
+		err       error
+	)
+
+	if s.Pool != nil {
+		created, redirects, err = s.dbStats(ctx, q, stats)
+	} else {
+		created, redirects = s.memStats(q, stats)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	stats.Series = []StatsPoint{}
+
+	for t := q.From; t.Before(q.To); t = t.Add(q.Bucket) {
+		stats.Series = append(stats.Series, StatsPoint{Time: t, Created: created[t], Redirects: redirects[t]})
+	}
+
+	return stats, nil
+}
+
+// GetTotals возвращает только общее число ссылок и их владельцев. Все ссылки хранятся в памяти
+// и с базой, поэтому в отличие от GetStats запросов к ней нет.
+func (s *Storage) GetTotals(ctx context.Context) (urls, users int) {
+	defer metrics.ObserveStorage("GetTotals", time.Now())
+
+	_, span := tracing.Start(ctx, "storage.GetTotals")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	owners := make(map[string]struct{})
+
+	for _, details := range s.urlMappings {
+		if details.UserID != "" {
+			owners[details.UserID] = struct{}{}
+		}
+	}
+
+	return len(s.urlMappings), len(owners)
+}
+
+// dbStats считает статистику запросами к базе. Счетчики из памяти сюда не записываются:
+// это делает RunStatsFlush, поэтому переходы и активность отстают не больше чем на StatsFlushInterval.
+func (s *Storage) dbStats(ctx context.Context, q StatsQuery, stats *Stats) (created, redirects map[time.Time]int, err error) {
+	// Сутки и часы отсчитываются в UTC независимо от часового пояса сессии.
+	trunc := stats.Bucket
+
+	err = s.Pool.QueryRow(ctx, `
+		SELECT COUNT(*), COUNT(DISTINCT NULLIF(user_id, '')), COUNT(*) FILTER (WHERE NOT is_deleted), COUNT(*) FILTER (WHERE is_deleted)
+		FROM shorten_urls`,
+	).Scan(&stats.URLs, &stats.Users, &stats.ActiveURLs, &stats.DeletedURLs)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = s.Pool.QueryRow(ctx, `SELECT COUNT(DISTINCT user_id) FROM user_activity WHERE hour >= $1 AND hour < $2`, q.From, q.To).
+		Scan(&stats.ActiveUsers)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	created, err = s.dbSeries(ctx, `
+		SELECT date_trunc($3, created_at AT TIME ZONE 'UTC'), COUNT(*)
+		FROM shorten_urls WHERE created_at >= $1 AND created_at < $2 GROUP BY 1`, q.From, q.To, trunc)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	redirects, err = s.dbSeries(ctx, `
+		SELECT date_trunc($3, hour AT TIME ZONE 'UTC'), SUM(clicks)
+		FROM link_clicks WHERE hour >= $1 AND hour < $2 GROUP BY 1`, q.From, q.To, trunc)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := s.Pool.Query(ctx, `
+		SELECT c.short_url, COALESCE(u.original_url, ''), SUM(c.clicks) AS n
+		FROM link_clicks AS c LEFT JOIN shorten_urls AS u ON u.short_url = c.short_url
+		WHERE c.hour >= $1 AND c.hour < $2
+		GROUP BY c.short_url, u.original_url
+		ORDER BY n DESC, c.short_url
+		LIMIT $3`, q.From, q.To, q.Top)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stats.TopLinks, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (TopLink, error) {
+		var link TopLink
+
+		return link, row.Scan(&link.ShortCode, &link.OriginalURL, &link.Redirects)
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err = s.Pool.Query(ctx, `
+		SELECT d.domain, SUM(d.clicks) AS n
+		FROM (
+			SELECT lower(substring(u.original_url FROM '^[A-Za-z][A-Za-z0-9+.-]*://(?:[^@/]*@)?([^/:?#]+)')) AS domain, c.clicks
+			FROM link_clicks AS c JOIN shorten_urls AS u ON u.short_url = c.short_url
+			WHERE c.hour >= $1 AND c.hour < $2
+		) AS d
+		WHERE d.domain IS NOT NULL
+		GROUP BY d.domain
+		ORDER BY n DESC, d.domain
+		LIMIT $3`, q.From, q.To, q.Top)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stats.TopDomains, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (TopDomain, error) {
+		var domain TopDomain
+
+		return domain, row.Scan(&domain.Domain, &domain.Redirects)
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stats.TopLinks = append([]TopLink{}, stats.TopLinks...)
+	stats.TopDomains = append([]TopDomain{}, stats.TopDomains...)
+
+	return created, redirects, nil
+}
+
+// dbSeries читает пары (начало шага, значение) в карту.
+func (s *Storage) dbSeries(ctx context.Context, query string, args ...any) (map[time.Time]int, error) {
+	rows, err := s.Pool.Query(ctx, query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	series := make(map[time.Time]int)
+
+	for rows.Next() {
+		var (
+			t time.Time
+			n int
+		)
+
+		if err := rows.Scan(&t, &n); err != nil {
+			return nil, err
+		}
+
+		series[t.UTC()] = n
+	}
+
+	return series, rows.Err()
+}
+
+// memStats считает статистику по ссылкам и счетчикам в памяти.
+func (s *Storage) memStats(q StatsQuery, stats *Stats) (created, redirects map[time.Time]int) {
+	created = make(map[time.Time]int)
+	redirects = make(map[time.Time]int)
+	clicks := make(map[string]int)
+	users := make(map[string]struct{})
+
+	s.activity.mu.Lock()
+
+	for hour, links := range s.activity.clicks {
+		if hour.Before(q.From) || !hour.Before(q.To) {
+			continue
+		}
+
+		for shortURL, n := range links {
+			redirects[hour.Truncate(q.Bucket)] += n
+			clicks[shortURL] += n
+		}
+	}
+
+	for hour, ids := range s.activity.users {
+		if !hour.Before(q.From) && hour.Before(q.To) {
+			maps.Copy(users, ids)
+		}
+	}
+
+	s.activity.mu.Unlock()
+
+	stats.ActiveUsers = len(users)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Отдельной таблицы пользователей нет: пользователями считаются владельцы ссылок.
+	owners := make(map[string]struct{})
+
+	for _, details := range s.urlMappings {
+		if details.UserID != "" {
+			owners[details.UserID] = struct{}{}
+		}
+
+		if details.IsDeleted {
+			stats.DeletedURLs++
+		} else {
+			stats.ActiveURLs++
+		}
+
+		if !details.CreatedAt.IsZero() && !details.CreatedAt.Before(q.From) && details.CreatedAt.Before(q.To) {
+			created[details.CreatedAt.Truncate(q.Bucket)]++
+		}
+	}
+
+	stats.URLs = len(s.urlMappings)
+	stats.Users = len(owners)
+
+	domains := make(map[string]int)
+	stats.TopLinks = []TopLink{}
+
+	for shortURL, n := range clicks {
+		details := s.urlMappings[shortURL]
+		stats.TopLinks = append(stats.TopLinks, TopLink{ShortCode: shortURL, OriginalURL: details.OriginalURL, Redirects: n})
+
+		if u, err := url.Parse(details.OriginalURL); err == nil && u.Hostname() != "" {
+			domains[strings.ToLower(u.Hostname())] += n
+		}
+	}
+
+	stats.TopDomains = []TopDomain{}
+
+	for domain, n := range domains {
+		stats.TopDomains = append(stats.TopDomains, TopDomain{Domain: domain, Redirects: n})
+	}
+
+	slices.SortFunc(stats.TopLinks, func(a, b TopLink) int {
+		return cmp.Or(cmp.Compare(b.Redirects, a.Redirects), strings.Compare(a.ShortCode, b.ShortCode))
+	})
+
+	slices.SortFunc(stats.TopDomains, func(a, b TopDomain) int {
+		return cmp.Or(cmp.Compare(b.Redirects, a.Redirects), strings.Compare(a.Domain, b.Domain))
+	})
+
+	stats.TopLinks = stats.TopLinks[:min(len(stats.TopLinks), q.Top)]
+	stats.TopDomains = stats.TopDomains[:min(len(stats.TopDomains), q.Top)]
+
+	return created, redirects
+}
@@ -4,11 +4,17 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/flash1nho/go-musthave-shortener-tpl/internal/db"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/metrics"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/tracing"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -22,13 +28,28 @@ type URLMapping struct {
 	UUID        int    `json:"uuid"`
 	ShortURL    string `json:"short_url"`
 	OriginalURL string `json:"original_url"`
+	UserID      string `json:"user_id,omitempty"`
+	IsDeleted   bool   `json:"is_deleted,omitempty"`
+	IsBlocked   bool   `json:"is_blocked,omitempty"`
+	// CreatedAt не записывается для ссылок, время создания которых неизвестно.
+	CreatedAt time.Time `json:"created_at,omitzero"`
+}
+
+// DisabledUser — строка файла хранилища об отключенном пользователе. Ссылки записываются
+// строками URLMapping, а эти строки отличаются от них отсутствием short_url.
+type DisabledUser struct {
+	UserID string `json:"disabled_user_id"`
 }
 
 type URLDetails struct {
 	ShortURL    string `json:"short_url"`
 	OriginalURL string `json:"originalURL"`
 	IsDeleted   bool   `json:"is_deleted"`
-	UserID      string `json:"user_id"`
+	// IsBlocked — ссылку заблокировал оператор: переход по ней запрещен, пока блокировку не снимут.
+	IsBlocked bool   `json:"is_blocked"`
+	UserID    string `json:"user_id"`
+	// CreatedAt — время создания; нулевое для ссылок, созданных до его учета.
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type Storage struct {
@@ -36,8 +57,16 @@ type Storage struct {
 	filePath    string
 	Pool        *pgxpool.Pool
 	urlMappings map[string]URLDetails
+	// disabledUsers — пользователи, отключенные оператором. Сохраняются в базе или в файле
+	// вместе со ссылками; хранилище только в памяти теряет их при перезапуске, как и ссылки.
+	disabledUsers    map[string]bool
+	activity         *activity
+	migrationVersion uint
 }
 
+// ErrExists — короткая ссылка уже сохранена; существующая запись при этом не меняется.
+var ErrExists = errors.New("короткая ссылка уже существует")
+
 type UpdateItem struct {
 	UserID   string
 	ShortURL string
@@ -48,15 +77,11 @@ type UpdateResult struct {
 	Updated  bool
 }
 
-type Stats struct {
-	URLs  int `json:"urls"`
-	Users int `json:"users"`
-}
-
 const numWorkers = 4
 
 func NewStorage(filePath string, databaseDSN string) (*Storage, error) {
 	var pool *pgxpool.Pool = nil
+	var migrationVersion uint
 	var err error
 
 	if databaseDSN != "" {
@@ -75,12 +100,21 @@ func NewStorage(filePath string, databaseDSN string) (*Storage, error) {
 		if err := m.Up(); err != nil && err != migrate.ErrNoChange {
 			return nil, fmt.Errorf("ошибка запуска миграций: %w", err)
 		}
+
+		migrationVersion, _, err = m.Version()
+
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения версии миграций: %w", err)
+		}
 	}
 
 	s := &Storage{
-		filePath:    filePath,
-		Pool:        pool,
-		urlMappings: make(map[string]URLDetails),
+		filePath:         filePath,
+		Pool:             pool,
+		urlMappings:      make(map[string]URLDetails),
+		disabledUsers:    make(map[string]bool),
+		activity:         newActivity(),
+		migrationVersion: migrationVersion,
 	}
 
 	if s.Pool != nil {
@@ -123,7 +157,24 @@ func (s *Storage) fileLoad() error {
 			continue
 		}
 
-		s.urlMappings[m.ShortURL] = URLDetails{OriginalURL: m.OriginalURL, IsDeleted: false}
+		if m.ShortURL == "" {
+			var u DisabledUser
+
+			if err := json.Unmarshal(line, &u); err == nil && u.UserID != "" {
+				s.disabledUsers[u.UserID] = true
+			}
+
+			continue
+		}
+
+		s.urlMappings[m.ShortURL] = URLDetails{
+			ShortURL:    m.ShortURL,
+			OriginalURL: m.OriginalURL,
+			UserID:      m.UserID,
+			IsDeleted:   m.IsDeleted,
+			IsBlocked:   m.IsBlocked,
+			CreatedAt:   m.CreatedAt,
+		}
 	}
 
 	if err := scanner.Err(); err != nil {
@@ -137,7 +188,8 @@ func (s *Storage) dbLoad(ctx context.Context) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	rows, err := s.Pool.Query(ctx, "SELECT original_url, short_url FROM shorten_urls WHERE is_deleted = FALSE")
+	// Удаленные ссылки тоже загружаются: переход по ним отвечает 410, а оператор видит их состояние.
+	rows, err := s.Pool.Query(ctx, "SELECT original_url, short_url, COALESCE(user_id, ''), is_deleted, is_blocked, created_at FROM shorten_urls")
 
 	if err != nil {
 		return err
@@ -147,43 +199,52 @@ func (s *Storage) dbLoad(ctx context.Context) error {
 
 	for rows.Next() {
 		var (
-			originalURL string
-			shortURL    string
+			d         URLDetails
+			createdAt *time.Time
 		)
 
-		err = rows.Scan(&originalURL, &shortURL)
+		err = rows.Scan(&d.OriginalURL, &d.ShortURL, &d.UserID, &d.IsDeleted, &d.IsBlocked, &createdAt)
 
 		if err != nil {
 			return err
 		}
 
-		s.urlMappings[shortURL] = URLDetails{OriginalURL: originalURL, IsDeleted: false}
-	}
-
-	return nil
-}
-
-func (s *Storage) save(ctx context.Context, key string, value string, userID string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+		if createdAt != nil {
+			d.CreatedAt = createdAt.UTC()
+		}
 
-	var err error
+		s.urlMappings[d.ShortURL] = d
+	}
 
-	if s.Pool != nil {
-		err = s.dbSave(ctx, value, key, userID)
-	} else if s.filePath != "" {
-		err = s.fileSave()
+	if err := rows.Err(); err != nil {
+		return err
 	}
 
+	users, err := s.Pool.Query(ctx, "SELECT user_id FROM disabled_users")
+
 	if err != nil {
 		return err
 	}
 
-	return nil
+	defer users.Close()
+
+	for users.Next() {
+		var userID string
+
+		if err := users.Scan(&userID); err != nil {
+			return err
+		}
+
+		s.disabledUsers[userID] = true
+	}
+
+	return users.Err()
 }
 
+// fileSave перезаписывает файл ссылками и отключенными пользователями. Вызывается под s.mu.
 func (s *Storage) fileSave() error {
-	file, err := os.OpenFile(s.filePath, os.O_CREATE|os.O_WRONLY, 0644)
+	// Файл может стать короче: после включения пользователя его строка исчезает.
+	file, err := os.OpenFile(s.filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
 
 	if err != nil {
 		return err
@@ -196,7 +257,15 @@ func (s *Storage) fileSave() error {
 	uuid := 1
 
 	for short, details := range s.urlMappings {
-		m := URLMapping{UUID: uuid, ShortURL: short, OriginalURL: details.OriginalURL}
+		m := URLMapping{
+			UUID:        uuid,
+			ShortURL:    short,
+			OriginalURL: details.OriginalURL,
+			UserID:      details.UserID,
+			IsDeleted:   details.IsDeleted,
+			IsBlocked:   details.IsBlocked,
+			CreatedAt:   details.CreatedAt,
+		}
 
 		if err := encoder.Encode(m); err != nil {
 			return err
@@ -205,21 +274,16 @@ func (s *Storage) fileSave() error {
 		uuid++
 	}
 
-	return nil
-}
-
-func (s *Storage) dbSave(ctx context.Context, originalURL string, shortURL string, userID string) error {
-	insertSQL := `INSERT INTO shorten_urls (original_url, short_url, user_id) VALUES ($1, $2, $3)`
-	_, err := s.Pool.Exec(ctx, insertSQL, originalURL, shortURL, userID)
-
-	if err != nil {
-		return err
+	for userID := range s.disabledUsers {
+		if err := encoder.Encode(DisabledUser{UserID: userID}); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func (s *Storage) dbSaveBatch(ctx context.Context, batch map[string]string) error {
+func (s *Storage) dbSaveBatch(ctx context.Context, batch map[string]string, userID string) error {
 	if s.Pool == nil {
 		return nil
 	}
@@ -227,7 +291,7 @@ func (s *Storage) dbSaveBatch(ctx context.Context, batch map[string]string) erro
 	pb := &pgx.Batch{}
 
 	for shortURL, originalURL := range batch {
-		pb.Queue(`INSERT INTO shorten_urls (original_url, short_url) VALUES ($1, $2)`, originalURL, shortURL)
+		pb.Queue(`INSERT INTO shorten_urls (original_url, short_url, user_id) VALUES ($1, $2, $3)`, originalURL, shortURL, userID)
 	}
 
 	results := s.Pool.SendBatch(ctx, pb)
@@ -244,27 +308,63 @@ func (s *Storage) dbSaveBatch(ctx context.Context, batch map[string]string) erro
 	return nil
 }
 
+// Set сохраняет новую ссылку. Существующая ссылка не меняется: ни владелец, ни удаление
+// или блокировка не сбрасываются, а возвращается ErrExists.
 func (s *Storage) Set(ctx context.Context, key string, value string, userID string) error {
-	s.mu.Lock()
-	s.urlMappings[key] = URLDetails{OriginalURL: value, UserID: userID, IsDeleted: false}
-	s.mu.Unlock()
+	defer metrics.ObserveStorage("Set", time.Now())
+
+	ctx, span := tracing.Start(ctx, "storage.Set")
+	defer span.End()
+
+	return s.insert(ctx, map[string]string{key: value}, userID)
+}
+
+// SetBatch сохраняет пакет новых ссылок целиком или не сохраняет ни одной: если хотя бы одна
+// уже существует, возвращается ErrExists.
+func (s *Storage) SetBatch(ctx context.Context, batch map[string]string, userID string) error {
+	defer metrics.ObserveStorage("SetBatch", time.Now())
+
+	ctx, span := tracing.Start(ctx, "storage.SetBatch")
+	defer span.End()
 
-	return s.save(ctx, key, value, userID)
+	return s.insert(ctx, batch, userID)
 }
 
-func (s *Storage) SetBatch(ctx context.Context, batch map[string]string) error {
+// insert добавляет ссылки, которых еще нет. В памяти они появляются только после записи в базу,
+// чтобы отклоненная базой ссылка не осталась в кеше.
+func (s *Storage) insert(ctx context.Context, batch map[string]string, userID string) error {
 	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for shortURL := range batch {
+		if _, exists := s.urlMappings[shortURL]; exists {
+			return fmt.Errorf("%w: %s", ErrExists, shortURL)
+		}
+	}
+
+	if err := s.dbSaveBatch(ctx, batch, userID); err != nil {
+		return err
+	}
+
+	createdAt := time.Now().UTC()
 
 	for shortURL, originalURL := range batch {
-		s.urlMappings[shortURL] = URLDetails{OriginalURL: originalURL, IsDeleted: false}
+		s.urlMappings[shortURL] = URLDetails{ShortURL: shortURL, OriginalURL: originalURL, UserID: userID, CreatedAt: createdAt}
 	}
 
-	s.mu.Unlock()
+	if s.Pool == nil && s.filePath != "" {
+		return s.fileSave()
+	}
 
-	return s.dbSaveBatch(ctx, batch)
+	return nil
 }
 
-func (s *Storage) Get(key string) (URLDetails, bool) {
+func (s *Storage) Get(ctx context.Context, key string) (URLDetails, bool) {
+	defer metrics.ObserveStorage("Get", time.Now())
+
+	_, span := tracing.Start(ctx, "storage.Get")
+	defer span.End()
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	value, found := s.urlMappings[key]
@@ -273,6 +373,11 @@ func (s *Storage) Get(key string) (URLDetails, bool) {
 }
 
 func (s *Storage) GetURLsByUserID(ctx context.Context, userID string) ([]URLDetails, error) {
+	defer metrics.ObserveStorage("GetURLsByUserID", time.Now())
+
+	ctx, span := tracing.Start(ctx, "storage.GetURLsByUserID")
+	defer span.End()
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -305,7 +410,7 @@ func (s *Storage) GetURLsByUserID(ctx context.Context, userID string) ([]URLDeta
 		}
 	} else {
 		for _, item := range s.urlMappings {
-			if item.UserID == userID {
+			if item.UserID == userID && !item.IsDeleted {
 				batch = append(batch, item)
 			}
 		}
@@ -315,6 +420,11 @@ func (s *Storage) GetURLsByUserID(ctx context.Context, userID string) ([]URLDeta
 }
 
 func (s *Storage) DeleteBatch(ctx context.Context, userID string, ShortURLs []string) error {
+	defer metrics.ObserveStorage("DeleteBatch", time.Now())
+
+	ctx, span := tracing.Start(ctx, "storage.DeleteBatch")
+	defer span.End()
+
 	var items []UpdateItem
 
 	for i := 0; i < len(ShortURLs); i++ {
@@ -326,6 +436,11 @@ func (s *Storage) DeleteBatch(ctx context.Context, userID string, ShortURLs []st
 		items = append(items, item)
 	}
 
+	if s.Pool == nil {
+		s.memDeleteBatch(items)
+		return nil
+	}
+
 	err := batchUpdateWithFanIn(s, ctx, items)
 
 	if err != nil {
@@ -335,13 +450,35 @@ func (s *Storage) DeleteBatch(ctx context.Context, userID string, ShortURLs []st
 	return nil
 }
 
+func (s *Storage) memDeleteBatch(items []UpdateItem) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, item := range items {
+		details, found := s.urlMappings[item.ShortURL]
+
+		if found && details.UserID == item.UserID {
+			details.IsDeleted = true
+			s.urlMappings[item.ShortURL] = details
+		}
+	}
+}
+
 func (s *Storage) Close() error {
+	defer metrics.ObserveStorage("Close", time.Now())
+
 	var err error
 
 	if s.Pool != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err = s.FlushStats(ctx)
+		cancel()
+
 		s.Pool.Close()
 	} else if s.filePath != "" {
+		s.mu.Lock()
 		err = s.fileSave()
+		s.mu.Unlock()
 	}
 
 	if err != nil {
@@ -351,22 +488,172 @@ func (s *Storage) Close() error {
 	return nil
 }
 
-func (s *Storage) GetStats(ctx context.Context) (*Stats, error) {
-	var urlsCount int
-	err := s.Pool.QueryRow(ctx, "SELECT COUNT(*) FROM shorten_urls").Scan(&urlsCount)
+// Ping проверяет доступность хранилища: базы данных или файла, в который сохраняются ссылки.
+// Хранилище в памяти доступно всегда.
+func (s *Storage) Ping(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "storage.Ping")
+	defer span.End()
 
-	if err != nil {
-		return nil, err
+	if s.Pool != nil {
+		return s.Pool.Ping(ctx)
 	}
 
-	var usersCount int
-	err = s.Pool.QueryRow(ctx, "SELECT COUNT(*) FROM users").Scan(&usersCount)
+	if s.filePath != "" {
+		_, err := os.Stat(s.filePath)
+
+		return err
+	}
+
+	return nil
+}
+
+// CheckMigrations проверяет, что в базе применены все миграции, накаченные при запуске, и схема не в «грязном» состоянии.
+func (s *Storage) CheckMigrations(ctx context.Context) error {
+	if s.Pool == nil {
+		return nil
+	}
+
+	var (
+		version int64
+		dirty   bool
+	)
+
+	err := s.Pool.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
 
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("ошибка чтения версии миграций: %w", err)
+	}
+
+	if dirty {
+		return fmt.Errorf("миграция %d применена не полностью", version)
 	}
 
-	return &Stats{URLs: urlsCount, Users: usersCount}, nil
+	if version < int64(s.migrationVersion) {
+		return fmt.Errorf("версия схемы %d ниже ожидаемой %d", version, s.migrationVersion)
+	}
+
+	return nil
+}
+
+// LinksByUserID возвращает все ссылки пользователя, включая удаленные и заблокированные,
+// упорядоченные по коду.
+func (s *Storage) LinksByUserID(ctx context.Context, userID string) []URLDetails {
+	defer metrics.ObserveStorage("LinksByUserID", time.Now())
+
+	_, span := tracing.Start(ctx, "storage.LinksByUserID")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var links []URLDetails
+
+	for _, details := range s.urlMappings {
+		if details.UserID == userID {
+			links = append(links, details)
+		}
+	}
+
+	slices.SortFunc(links, func(a, b URLDetails) int {
+		return strings.Compare(a.ShortURL, b.ShortURL)
+	})
+
+	return links
+}
+
+// ForceDelete удаляет ссылку независимо от владельца. Отсутствующая ссылка не считается ошибкой.
+func (s *Storage) ForceDelete(ctx context.Context, shortURL string) error {
+	defer metrics.ObserveStorage("ForceDelete", time.Now())
+
+	ctx, span := tracing.Start(ctx, "storage.ForceDelete")
+	defer span.End()
+
+	return s.update(ctx, shortURL, "UPDATE shorten_urls SET is_deleted = TRUE WHERE short_url = $1", func(d *URLDetails) {
+		d.IsDeleted = true
+	})
+}
+
+// SetBlocked блокирует ссылку или снимает блокировку. Отсутствующая ссылка не считается ошибкой.
+func (s *Storage) SetBlocked(ctx context.Context, shortURL string, blocked bool) error {
+	defer metrics.ObserveStorage("SetBlocked", time.Now())
+
+	ctx, span := tracing.Start(ctx, "storage.SetBlocked")
+	defer span.End()
+
+	return s.update(ctx, shortURL, "UPDATE shorten_urls SET is_blocked = $2 WHERE short_url = $1", func(d *URLDetails) {
+		d.IsBlocked = blocked
+	}, blocked)
+}
+
+// update меняет ссылку в базе запросом query с аргументами shortURL и args, а затем в памяти функцией fn.
+// Без базы изменение сразу записывается в файл, если он задан.
+func (s *Storage) update(ctx context.Context, shortURL, query string, fn func(d *URLDetails), args ...any) error {
+	if s.Pool != nil {
+		if _, err := s.Pool.Exec(ctx, query, append([]any{shortURL}, args...)...); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	details, found := s.urlMappings[shortURL]
+
+	if !found {
+		return nil
+	}
+
+	fn(&details)
+	s.urlMappings[shortURL] = details
+
+	if s.Pool == nil && s.filePath != "" {
+		return s.fileSave()
+	}
+
+	return nil
+}
+
+// SetUserDisabled отключает пользователя или включает его снова.
+func (s *Storage) SetUserDisabled(ctx context.Context, userID string, disabled bool) error {
+	defer metrics.ObserveStorage("SetUserDisabled", time.Now())
+
+	ctx, span := tracing.Start(ctx, "storage.SetUserDisabled")
+	defer span.End()
+
+	if s.Pool != nil {
+		query := "DELETE FROM disabled_users WHERE user_id = $1"
+
+		if disabled {
+			query = "INSERT INTO disabled_users (user_id) VALUES ($1) ON CONFLICT DO NOTHING"
+		}
+
+		if _, err := s.Pool.Exec(ctx, query, userID); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if disabled {
+		s.disabledUsers[userID] = true
+	} else {
+		delete(s.disabledUsers, userID)
+	}
+
+	if s.Pool == nil && s.filePath != "" {
+		return s.fileSave()
+	}
+
+	return nil
+}
+
+// UserDisabled сообщает, отключен ли пользователь оператором.
+func (s *Storage) UserDisabled(_ context.Context, userID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.disabledUsers[userID]
 }
 
 func batchUpdateWithFanIn(s *Storage, ctx context.Context, items []UpdateItem) error {
@@ -392,9 +679,14 @@ func batchUpdateWithFanIn(s *Storage, ctx context.Context, items []UpdateItem) e
 		close(results)
 	}()
 
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	for result := range results {
 		if result.Updated {
-			s.urlMappings[result.ShortURL] = URLDetails{IsDeleted: true}
+			details := s.urlMappings[result.ShortURL]
+			details.IsDeleted = true
+			s.urlMappings[result.ShortURL] = details
 		}
 	}
 
@@ -414,9 +706,9 @@ func worker(ctx context.Context, pool *pgxpool.Pool, jobs <-chan []UpdateItem, r
 		br := pool.SendBatch(ctx, batch)
 
 		for _, item := range items {
-			_, err := br.Exec()
+			tag, err := br.Exec()
 
-			if err != nil {
+			if err != nil || tag.RowsAffected() == 0 {
 				results <- UpdateResult{ShortURL: item.ShortURL, Updated: false}
 			} else {
 				results <- UpdateResult{ShortURL: item.ShortURL, Updated: true}
@@ -0,0 +1,205 @@
+// Package urlnorm приводит URL к каноническому виду перед сокращением, чтобы эквивалентные
+// адреса получали одну короткую ссылку, и отклоняет адреса, которые нельзя безопасно сохранить.
+package urlnorm
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/idna"
+)
+
+// ErrInvalidURL — общая причина всех ошибок проверки; конкретная причина добавляется в текст.
+var ErrInvalidURL = errors.New("некорректный URL")
+
+const (
+	DefaultScheme    = "https"
+	DefaultMaxLength = 2048
+)
+
+// DefaultAllowedSchemes — схемы, которые можно сокращать по умолчанию.
+var DefaultAllowedSchemes = []string{"http", "https"}
+
+// defaultPorts — порты, которые совпадают с портом схемы и поэтому убираются из адреса.
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// schemePrefix — адрес начинается со схемы (RFC 3986) и "://".
+var schemePrefix = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.-]*://`)
+
+// trackingParams — параметры запроса, которые не влияют на содержимое страницы и служат только для аналитики.
+var trackingParams = []string{"fbclid", "gclid", "yclid", "dclid", "msclkid", "mc_cid", "mc_eid", "_openstat"}
+
+type Options struct {
+	// AllowedSchemes — допустимые схемы в нижнем регистре.
+	AllowedSchemes []string
+	// MaxLength — максимальная длина адреса до и после нормализации.
+	MaxLength int
+	// StripTracking удаляет utm_* и другие параметры отслеживания.
+	StripTracking bool
+}
+
+func DefaultOptions() Options {
+	return Options{
+		AllowedSchemes: DefaultAllowedSchemes,
+		MaxLength:      DefaultMaxLength,
+	}
+}
+
+type Normalizer struct {
+	opts Options
+}
+
+func New(opts Options) *Normalizer {
+	if len(opts.AllowedSchemes) == 0 {
+		opts.AllowedSchemes = DefaultAllowedSchemes
+	}
+
+	if opts.MaxLength <= 0 {
+		opts.MaxLength = DefaultMaxLength
+	}
+
+	return &Normalizer{opts: opts}
+}
+
+func invalid(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidURL, fmt.Sprintf(format, args...))
+}
+
+// Normalize проверяет адрес и возвращает его канонический вид:
+// схема по умолчанию https, хост в нижнем регистре и punycode, без порта по умолчанию
+// и, если включено, без параметров отслеживания. Путь сохраняется как есть.
+func (n *Normalizer) Normalize(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+
+	if raw == "" {
+		return "", invalid("пустой адрес")
+	}
+
+	if len(raw) > n.opts.MaxLength {
+		return "", invalid("длина адреса превышает %d символов", n.opts.MaxLength)
+	}
+
+	if strings.IndexFunc(raw, func(r rune) bool { return unicode.IsControl(r) || unicode.IsSpace(r) }) >= 0 {
+		return "", invalid("адрес содержит управляющие или пробельные символы")
+	}
+
+	// Схема проверяется только в начале: "://" может встретиться и в параметрах запроса.
+	if !schemePrefix.MatchString(raw) {
+		raw = DefaultScheme + "://" + raw
+	}
+
+	u, err := url.Parse(raw)
+
+	if err != nil {
+		return "", invalid("%v", err)
+	}
+
+	if !slices.Contains(n.opts.AllowedSchemes, u.Scheme) {
+		return "", invalid("схема %q не поддерживается", u.Scheme)
+	}
+
+	if u.User != nil {
+		return "", invalid("адрес не должен содержать имя пользователя или пароль")
+	}
+
+	if u.Opaque != "" || u.Host == "" {
+		return "", invalid("не указан хост")
+	}
+
+	host, err := normalizeHost(u.Hostname())
+
+	if err != nil {
+		return "", err
+	}
+
+	port := u.Port()
+
+	if port != "" {
+		p, err := strconv.Atoi(port)
+
+		if err != nil || p < 1 || p > 65535 {
+			return "", invalid("некорректный порт %q", port)
+		}
+
+		if defaultPorts[u.Scheme] == port {
+			port = ""
+		}
+	}
+
+	if port != "" {
+		u.Host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		u.Host = "[" + host + "]"
+	} else {
+		u.Host = host
+	}
+
+	if n.opts.StripTracking && u.RawQuery != "" {
+		u.RawQuery = stripTracking(u.RawQuery)
+
+		if u.RawQuery == "" {
+			u.ForceQuery = false
+		}
+	}
+
+	result := u.String()
+
+	if len(result) > n.opts.MaxLength {
+		return "", invalid("длина адреса превышает %d символов", n.opts.MaxLength)
+	}
+
+	return result, nil
+}
+
+// normalizeHost переводит хост в нижний регистр, а интернационализированное имя — в punycode.
+func normalizeHost(host string) (string, error) {
+	if host == "" {
+		return "", invalid("не указан хост")
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String(), nil
+	}
+
+	ascii, err := idna.Lookup.ToASCII(strings.ToLower(host))
+
+	if err != nil {
+		return "", invalid("некорректное имя хоста %q: %v", host, err)
+	}
+
+	return ascii, nil
+}
+
+// stripTracking удаляет параметры отслеживания, сохраняя порядок и кодирование остальных.
+func stripTracking(rawQuery string) string {
+	parts := strings.Split(rawQuery, "&")
+	kept := parts[:0]
+
+	for _, part := range parts {
+		key, _, _ := strings.Cut(part, "=")
+
+		if decoded, err := url.QueryUnescape(key); err == nil {
+			key = decoded
+		}
+
+		key = strings.ToLower(key)
+
+		if strings.HasPrefix(key, "utm_") || slices.Contains(trackingParams, key) {
+			continue
+		}
+
+		kept = append(kept, part)
+	}
+
+	return strings.Join(kept, "&")
+}
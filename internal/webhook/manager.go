@@ -0,0 +1,448 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/destpolicy"
+	"github.com/flash1nho/go-musthave-shortener-tpl/internal/metrics"
+)
+
+// Значения Options по умолчанию.
+const (
+	DefaultMaxAttempts    = 8
+	DefaultInitialBackoff = 30 * time.Second
+	DefaultMaxBackoff     = time.Hour
+	DefaultPollInterval   = time.Second
+	DefaultTimeout        = 10 * time.Second
+	DefaultWorkers        = 4
+)
+
+// errInternal — адрес вебхука при доставке разрешился во внутреннюю сеть.
+var errInternal = fmt.Errorf("%w: адрес вебхука ведет во внутреннюю сеть", destpolicy.ErrForbidden)
+
+// notifyQueueSize — сколько событий ждут записи в Store; при переполнении событие теряется.
+const notifyQueueSize = 1024
+
+// Options — параметры доставки. Нулевые значения заменяются значениями по умолчанию.
+type Options struct {
+	// MaxAttempts — после стольких неудачных попыток доставка становится StatusDead.
+	MaxAttempts int
+	// InitialBackoff — задержка после первой неудачи; каждая следующая вдвое больше, но не больше MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// PollInterval — как часто проверяются доставки, время попытки которых наступило.
+	PollInterval time.Duration
+	// Timeout ограничивает одну попытку.
+	Timeout time.Duration
+	// Workers — сколько доставок отправляется одновременно.
+	Workers int
+	// Check, если задан, проверяет адрес вебхука при создании и перед каждой попыткой,
+	// например политикой адресов назначения.
+	Check func(ctx context.Context, rawURL string) error
+	// Resolver разрешает хост вебхука при создании; nil — net.DefaultResolver.
+	Resolver destpolicy.Resolver
+	// AllowInternal снимает запрет адресов во внутренней сети. Сервис его не включает:
+	// адреса вебхуков задают пользователи, и без запрета сервис отправлял бы запросы
+	// в собственную сеть и к метаданным облака. Нужен тестам с локальным получателем.
+	AllowInternal bool
+}
+
+func (o Options) withDefaults() Options {
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = DefaultMaxAttempts
+	}
+
+	if o.InitialBackoff <= 0 {
+		o.InitialBackoff = DefaultInitialBackoff
+	}
+
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = DefaultMaxBackoff
+	}
+
+	if o.PollInterval <= 0 {
+		o.PollInterval = DefaultPollInterval
+	}
+
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultTimeout
+	}
+
+	if o.Workers <= 0 {
+		o.Workers = DefaultWorkers
+	}
+
+	return o
+}
+
+// notification — событие, ожидающее записи доставок в Store.
+type notification struct {
+	userID string
+	event  string
+	link   Link
+	at     time.Time
+}
+
+// Manager регистрирует вебхуки и доставляет события. Notify не обращается к Store в запросе
+// пользователя: события передаются фоновой горутине Run.
+type Manager struct {
+	store  Store
+	opts   Options
+	guard  *destpolicy.Policy
+	client *http.Client
+	log    *zap.Logger
+
+	events chan notification
+	wake   chan struct{}
+	now    func() time.Time
+}
+
+// NewManager создает менеджер. Адреса во внутренней сети (loopback, частные, link-local и 0.0.0.0)
+// запрещены независимо от Check, если не задан AllowInternal: при создании вебхука проверяются
+// адреса, в которые разрешается хост, а при доставке — адрес, с которым устанавливается соединение.
+func NewManager(store Store, opts Options, log *zap.Logger) *Manager {
+	opts = opts.withDefaults()
+
+	var guard *destpolicy.Policy
+
+	// Прокси не используется: запрет проверяет адрес соединения, а с прокси это был бы адрес прокси.
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	if !opts.AllowInternal {
+		guard, _ = destpolicy.New(destpolicy.Internal, nil, opts.Resolver)
+		dialer.Control = destpolicy.DenyInternal
+	}
+
+	return &Manager{
+		store: store,
+		opts:  opts,
+		guard: guard,
+		// Перенаправления не выполняются: подпись выдана конкретному адресу.
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   opts.Timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		log:    log,
+		events: make(chan notification, notifyQueueSize),
+		wake:   make(chan struct{}, 1),
+		now:    time.Now,
+	}
+}
+
+// Create регистрирует вебхук пользователя и возвращает его вместе с секретом подписи.
+func (m *Manager) Create(ctx context.Context, userID, rawURL string, events []string) (Webhook, error) {
+	events, err := validate(rawURL, events)
+
+	if err != nil {
+		return Webhook{}, err
+	}
+
+	if err := m.guard.Check(ctx, rawURL); err != nil {
+		return Webhook{}, fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+
+	if m.opts.Check != nil {
+		if err := m.opts.Check(ctx, rawURL); err != nil {
+			return Webhook{}, fmt.Errorf("%w: %w", ErrInvalid, err)
+		}
+	}
+
+	w := Webhook{
+		ID:        newID(8),
+		URL:       rawURL,
+		Events:    events,
+		Secret:    newID(32),
+		CreatedAt: m.now().UTC(),
+	}
+
+	if err := m.store.Create(ctx, userID, w); err != nil {
+		return Webhook{}, err
+	}
+
+	return w, nil
+}
+
+// List возвращает вебхуки пользователя без секретов.
+func (m *Manager) List(ctx context.Context, userID string) ([]Webhook, error) {
+	return m.store.List(ctx, userID)
+}
+
+// Delete удаляет вебхук пользователя; недоставленные события удаляются вместе с ним.
+func (m *Manager) Delete(ctx context.Context, userID, id string) error {
+	return m.store.Delete(ctx, userID, id)
+}
+
+// DeadLetters возвращает доставки пользователя, попытки которых исчерпаны.
+func (m *Manager) DeadLetters(ctx context.Context, userID string) ([]Delivery, error) {
+	return m.store.Deliveries(ctx, userID, StatusDead)
+}
+
+// Redeliver ставит доставку пользователя в очередь заново с полным набором попыток.
+func (m *Manager) Redeliver(ctx context.Context, userID, id string) (Delivery, error) {
+	d, err := m.store.Redeliver(ctx, userID, id, m.now())
+
+	if err != nil {
+		return Delivery{}, err
+	}
+
+	m.signal()
+
+	return d, nil
+}
+
+// QueueStats — состояние очереди доставок всех пользователей.
+type QueueStats struct {
+	// Buffered — события, принятые Notify, но еще не записанные в Store.
+	Buffered int `json:"buffered"`
+	Pending  int `json:"pending"`
+	Dead     int `json:"dead"`
+}
+
+// Stats возвращает состояние очереди доставок.
+func (m *Manager) Stats(ctx context.Context) (QueueStats, error) {
+	counts, err := m.store.Count(ctx)
+
+	if err != nil {
+		return QueueStats{}, err
+	}
+
+	return QueueStats{Buffered: len(m.events), Pending: counts[StatusPending], Dead: counts[StatusDead]}, nil
+}
+
+// Notify передает событие ссылки пользователя userID на доставку его вебхукам.
+// Если очередь событий переполнена, событие теряется: запрос пользователя не ждет вебхуки.
+func (m *Manager) Notify(userID, event string, link Link) {
+	select {
+	case m.events <- notification{userID: userID, event: event, link: link, at: m.now().UTC()}:
+	default:
+		m.log.Warn("Очередь событий вебхуков переполнена, событие отброшено", zap.String("event", event), zap.String("short_code", link.ShortCode))
+	}
+}
+
+func (m *Manager) signal() {
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run записывает события в Store и доставляет их, пока не отменен ctx.
+// Оставшиеся в очереди события записываются перед выходом и будут доставлены после перезапуска.
+func (m *Manager) Run(ctx context.Context) {
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		m.deliverLoop(ctx)
+	}()
+
+	for {
+		select {
+		case n := <-m.events:
+			m.enqueue(ctx, n)
+		case <-ctx.Done():
+			m.drain()
+			<-done
+
+			return
+		}
+	}
+}
+
+// drain записывает события, накопившиеся к остановке.
+func (m *Manager) drain() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for {
+		select {
+		case n := <-m.events:
+			m.enqueue(ctx, n)
+		default:
+			return
+		}
+	}
+}
+
+// enqueue создает по доставке на каждый вебхук пользователя, подписанный на событие.
+func (m *Manager) enqueue(ctx context.Context, n notification) {
+	hooks, err := m.store.Subscribers(ctx, n.userID, n.event)
+
+	if err != nil {
+		m.log.Error("Ошибка поиска вебхуков", zap.String("event", n.event), zap.Error(err))
+		return
+	}
+
+	if len(hooks) == 0 {
+		return
+	}
+
+	deliveries := make([]Delivery, 0, len(hooks))
+
+	for _, w := range hooks {
+		id := newID(16)
+		payload, err := json.Marshal(Payload{ID: id, Event: n.event, CreatedAt: n.at, Link: n.link})
+
+		if err != nil {
+			m.log.Error("Ошибка кодирования события вебхука", zap.Error(err))
+			return
+		}
+
+		deliveries = append(deliveries, Delivery{
+			ID:            id,
+			WebhookID:     w.ID,
+			URL:           w.URL,
+			Event:         n.event,
+			Payload:       payload,
+			Status:        StatusPending,
+			NextAttemptAt: n.at,
+			CreatedAt:     n.at,
+		})
+	}
+
+	if err := m.store.Enqueue(ctx, deliveries); err != nil {
+		m.log.Error("Ошибка сохранения доставок вебхуков", zap.String("event", n.event), zap.Error(err))
+		return
+	}
+
+	m.signal()
+}
+
+// deliverLoop отправляет доставки, время которых наступило, раз в PollInterval или по сигналу.
+func (m *Manager) deliverLoop(ctx context.Context) {
+	ticker := time.NewTicker(m.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-m.wake:
+		}
+
+		m.deliverDue(ctx)
+	}
+}
+
+// deliverDue отправляет наступившие доставки пачками, пока они не закончатся.
+func (m *Manager) deliverDue(ctx context.Context) {
+	// Аренда переживает самую долгую попытку, поэтому доставка не уйдет дважды.
+	lease := 2 * m.opts.Timeout
+
+	for ctx.Err() == nil {
+		due, err := m.store.Claim(ctx, m.now(), lease, m.opts.Workers*4)
+
+		if err != nil {
+			m.log.Error("Ошибка выборки доставок вебхуков", zap.Error(err))
+			return
+		}
+
+		if len(due) == 0 {
+			return
+		}
+
+		var g errgroup.Group
+		g.SetLimit(m.opts.Workers)
+
+		for _, d := range due {
+			g.Go(func() error {
+				m.attempt(ctx, d)
+				return nil
+			})
+		}
+
+		g.Wait()
+	}
+}
+
+// attempt выполняет одну попытку доставки и сохраняет ее итог.
+func (m *Manager) attempt(ctx context.Context, d Delivery) {
+	status, err := m.send(ctx, d)
+
+	d.Attempts++
+	d.LastStatus = status
+
+	switch {
+	case err == nil:
+		d.Status = StatusDelivered
+		d.LastError = ""
+		metrics.WebhookDelivery(StatusDelivered)
+	case d.Attempts >= m.opts.MaxAttempts:
+		d.Status = StatusDead
+		d.LastError = err.Error()
+		metrics.WebhookDelivery(StatusDead)
+		m.log.Warn("Попытки доставки вебхука исчерпаны", zap.String("delivery", d.ID), zap.String("webhook", d.WebhookID), zap.Error(err))
+	default:
+		d.Status = StatusPending
+		d.LastError = err.Error()
+		d.NextAttemptAt = m.now().Add(backoff(d.Attempts, m.opts.InitialBackoff, m.opts.MaxBackoff))
+		metrics.WebhookDelivery("retry")
+	}
+
+	// Итог сохраняется и после остановки сервиса, иначе попытка повторится после перезапуска.
+	if err := m.store.Save(context.WithoutCancel(ctx), d); err != nil {
+		m.log.Error("Ошибка сохранения итога доставки вебхука", zap.String("delivery", d.ID), zap.Error(err))
+	}
+}
+
+// send отправляет подписанное событие и возвращает код ответа получателя.
+// Успешной считается доставка с кодом 2xx.
+func (m *Manager) send(ctx context.Context, d Delivery) (int, error) {
+	if m.opts.Check != nil {
+		if err := m.opts.Check(ctx, d.URL); err != nil {
+			return 0, err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "shortener-webhook/1.0")
+	req.Header.Set(EventHeader, d.Event)
+	req.Header.Set(DeliveryHeader, d.ID)
+	req.Header.Set(SignatureHeader, Sign(d.secret, d.Payload))
+
+	resp, err := m.client.Do(req)
+
+	// Ошибка соединения содержит адрес, в который разрешился хост, а она видна пользователю
+	// в списке недоставленных.
+	if errors.Is(err, destpolicy.ErrForbidden) {
+		return 0, errInternal
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer resp.Body.Close()
+
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("получатель ответил %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,210 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MemoryStore хранит вебхуки и доставки в памяти процесса: после перезапуска они теряются.
+type MemoryStore struct {
+	mu         sync.Mutex
+	webhooks   map[string]Webhook
+	deliveries map[string]Delivery
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		webhooks:   make(map[string]Webhook),
+		deliveries: make(map[string]Delivery),
+	}
+}
+
+func (m *MemoryStore) Create(_ context.Context, userID string, w Webhook) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := 0
+
+	for _, existing := range m.webhooks {
+		if existing.userID == userID {
+			count++
+		}
+	}
+
+	if count >= MaxPerUser {
+		return fmt.Errorf("%w: у пользователя уже %d вебхуков", ErrInvalid, MaxPerUser)
+	}
+
+	w.userID = userID
+	m.webhooks[w.ID] = w
+
+	return nil
+}
+
+func (m *MemoryStore) List(_ context.Context, userID string) ([]Webhook, error) {
+	return m.find(userID, ""), nil
+}
+
+// find возвращает вебхуки пользователя в порядке создания; непустой event оставляет подписанные
+// на него вебхуки вместе с секретами.
+func (m *MemoryStore) find(userID, event string) []Webhook {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var hooks []Webhook
+
+	for _, w := range m.webhooks {
+		if w.userID != userID || (event != "" && !w.Subscribed(event)) {
+			continue
+		}
+
+		if event == "" {
+			w.Secret = ""
+		}
+
+		hooks = append(hooks, w)
+	}
+
+	slices.SortFunc(hooks, func(a, b Webhook) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	return hooks
+}
+
+func (m *MemoryStore) Delete(_ context.Context, userID, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if w, ok := m.webhooks[id]; !ok || w.userID != userID {
+		return ErrNotFound
+	}
+
+	delete(m.webhooks, id)
+
+	for deliveryID, d := range m.deliveries {
+		if d.WebhookID == id {
+			delete(m.deliveries, deliveryID)
+		}
+	}
+
+	return nil
+}
+
+func (m *MemoryStore) Subscribers(_ context.Context, userID, event string) ([]Webhook, error) {
+	return m.find(userID, event), nil
+}
+
+func (m *MemoryStore) Enqueue(_ context.Context, deliveries []Delivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, d := range deliveries {
+		m.deliveries[d.ID] = d
+	}
+
+	return nil
+}
+
+func (m *MemoryStore) Claim(_ context.Context, now time.Time, lease time.Duration, limit int) ([]Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var due []Delivery
+
+	for _, d := range m.deliveries {
+		if d.Status == StatusPending && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+
+	slices.SortFunc(due, func(a, b Delivery) int {
+		return a.NextAttemptAt.Compare(b.NextAttemptAt)
+	})
+
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	for i, d := range due {
+		d.NextAttemptAt = now.Add(lease)
+		m.deliveries[d.ID] = d
+
+		due[i].secret = m.webhooks[d.WebhookID].Secret
+	}
+
+	return due, nil
+}
+
+func (m *MemoryStore) Save(_ context.Context, d Delivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Вебхук могли удалить во время попытки.
+	if _, ok := m.deliveries[d.ID]; !ok {
+		return nil
+	}
+
+	if d.Status == StatusDelivered {
+		delete(m.deliveries, d.ID)
+		return nil
+	}
+
+	d.secret = ""
+	m.deliveries[d.ID] = d
+
+	return nil
+}
+
+func (m *MemoryStore) Deliveries(_ context.Context, userID, status string) ([]Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deliveries []Delivery
+
+	for _, d := range m.deliveries {
+		if d.Status == status && m.webhooks[d.WebhookID].userID == userID {
+			deliveries = append(deliveries, d)
+		}
+	}
+
+	slices.SortFunc(deliveries, func(a, b Delivery) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+
+	return deliveries, nil
+}
+
+func (m *MemoryStore) Count(_ context.Context) (map[string]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	counts := make(map[string]int)
+
+	for _, d := range m.deliveries {
+		counts[d.Status]++
+	}
+
+	return counts, nil
+}
+
+func (m *MemoryStore) Redeliver(_ context.Context, userID, id string, now time.Time) (Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	d, ok := m.deliveries[id]
+
+	if !ok || m.webhooks[d.WebhookID].userID != userID {
+		return Delivery{}, ErrNotFound
+	}
+
+	d.Status = StatusPending
+	d.Attempts = 0
+	d.NextAttemptAt = now
+	m.deliveries[id] = d
+
+	return d, nil
+}
@@ -0,0 +1,233 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// deliveryColumns — поля доставки вместе с адресом вебхука в порядке scanDelivery.
+const deliveryColumns = `d.id, d.webhook_id, w.url, d.event, d.payload, d.status, d.attempts,
+	d.next_attempt_at, d.last_error, d.last_status, d.created_at`
+
+// PostgresStore хранит вебхуки в webhooks, а доставки — в webhook_deliveries. Доставки выбираются
+// с SKIP LOCKED, поэтому несколько экземпляров сервиса не отправляют одно событие дважды.
+type PostgresStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresStore(pool *pgxpool.Pool) *PostgresStore {
+	return &PostgresStore{pool: pool}
+}
+
+func (p *PostgresStore) Create(ctx context.Context, userID string, w Webhook) error {
+	// Проверка числа вебхуков и вставка идут одним запросом, чтобы параллельные запросы не превысили предел.
+	tag, err := p.pool.Exec(ctx, `
+		INSERT INTO webhooks (id, user_id, url, events, secret, created_at)
+		SELECT $1, $2, $3, $4, $5, $6
+		WHERE (SELECT COUNT(*) FROM webhooks WHERE user_id = $2) < $7`,
+		w.ID, userID, w.URL, w.Events, w.Secret, w.CreatedAt, MaxPerUser,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: у пользователя уже %d вебхуков", ErrInvalid, MaxPerUser)
+	}
+
+	return nil
+}
+
+func (p *PostgresStore) List(ctx context.Context, userID string) ([]Webhook, error) {
+	rows, err := p.pool.Query(ctx,
+		"SELECT id, url, events, created_at FROM webhooks WHERE user_id = $1 ORDER BY created_at", userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Webhook, error) {
+		var w Webhook
+		err := row.Scan(&w.ID, &w.URL, &w.Events, &w.CreatedAt)
+
+		return w, err
+	})
+}
+
+func (p *PostgresStore) Delete(ctx context.Context, userID, id string) error {
+	tag, err := p.pool.Exec(ctx, "DELETE FROM webhooks WHERE id = $1 AND user_id = $2", id, userID)
+
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (p *PostgresStore) Subscribers(ctx context.Context, userID, event string) ([]Webhook, error) {
+	rows, err := p.pool.Query(ctx,
+		"SELECT id, url, events, secret, created_at FROM webhooks WHERE user_id = $1 AND $2 = ANY(events)", userID, event)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Webhook, error) {
+		var w Webhook
+		err := row.Scan(&w.ID, &w.URL, &w.Events, &w.Secret, &w.CreatedAt)
+
+		return w, err
+	})
+}
+
+func (p *PostgresStore) Enqueue(ctx context.Context, deliveries []Delivery) error {
+	_, err := p.pool.CopyFrom(ctx, pgx.Identifier{"webhook_deliveries"},
+		[]string{"id", "webhook_id", "event", "payload", "status", "next_attempt_at", "created_at"},
+		pgx.CopyFromSlice(len(deliveries), func(i int) ([]any, error) {
+			d := deliveries[i]
+
+			return []any{d.ID, d.WebhookID, d.Event, d.Payload, d.Status, d.NextAttemptAt, d.CreatedAt}, nil
+		}))
+
+	return err
+}
+
+func (p *PostgresStore) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Delivery, error) {
+	rows, err := p.pool.Query(ctx, `
+		WITH due AS (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		), claimed AS (
+			UPDATE webhook_deliveries SET next_attempt_at = $2
+			FROM due WHERE webhook_deliveries.id = due.id
+			RETURNING webhook_deliveries.*
+		)
+		SELECT `+deliveryColumns+`, w.secret
+		FROM claimed AS d JOIN webhooks AS w ON w.id = d.webhook_id`,
+		now, now.Add(lease), limit,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Delivery, error) {
+		var d Delivery
+		err := row.Scan(append(deliveryFields(&d), &d.secret)...)
+
+		return d, err
+	})
+}
+
+func (p *PostgresStore) Save(ctx context.Context, d Delivery) error {
+	if d.Status == StatusDelivered {
+		_, err := p.pool.Exec(ctx, "DELETE FROM webhook_deliveries WHERE id = $1", d.ID)
+		return err
+	}
+
+	_, err := p.pool.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, next_attempt_at = $4, last_error = $5, last_status = $6
+		WHERE id = $1`,
+		d.ID, d.Status, d.Attempts, d.NextAttemptAt, d.LastError, d.LastStatus,
+	)
+
+	return err
+}
+
+func (p *PostgresStore) Count(ctx context.Context) (map[string]int, error) {
+	rows, err := p.pool.Query(ctx, "SELECT status, COUNT(*) FROM webhook_deliveries GROUP BY status")
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	counts := make(map[string]int)
+
+	for rows.Next() {
+		var (
+			status string
+			n      int
+		)
+
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+
+		counts[status] = n
+	}
+
+	return counts, rows.Err()
+}
+
+func (p *PostgresStore) Deliveries(ctx context.Context, userID, status string) ([]Delivery, error) {
+	rows, err := p.pool.Query(ctx, `
+		SELECT `+deliveryColumns+`
+		FROM webhook_deliveries AS d JOIN webhooks AS w ON w.id = d.webhook_id
+		WHERE w.user_id = $1 AND d.status = $2
+		ORDER BY d.created_at DESC`,
+		userID, status,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Delivery, error) {
+		var d Delivery
+		err := row.Scan(deliveryFields(&d)...)
+
+		return d, err
+	})
+}
+
+func (p *PostgresStore) Redeliver(ctx context.Context, userID, id string, now time.Time) (Delivery, error) {
+	rows, err := p.pool.Query(ctx, `
+		WITH reset AS (
+			UPDATE webhook_deliveries SET status = 'pending', attempts = 0, next_attempt_at = $3
+			WHERE id = $1 AND webhook_id IN (SELECT id FROM webhooks WHERE user_id = $2)
+			RETURNING *
+		)
+		SELECT `+deliveryColumns+`
+		FROM reset AS d JOIN webhooks AS w ON w.id = d.webhook_id`,
+		id, userID, now,
+	)
+
+	if err != nil {
+		return Delivery{}, err
+	}
+
+	d, err := pgx.CollectExactlyOneRow(rows, func(row pgx.CollectableRow) (Delivery, error) {
+		var d Delivery
+		err := row.Scan(deliveryFields(&d)...)
+
+		return d, err
+	})
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Delivery{}, ErrNotFound
+	}
+
+	return d, err
+}
+
+// deliveryFields возвращает адреса полей доставки в порядке deliveryColumns.
+func deliveryFields(d *Delivery) []any {
+	return []any{&d.ID, &d.WebhookID, &d.URL, &d.Event, &d.Payload, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &d.LastError, &d.LastStatus, &d.CreatedAt}
+}
@@ -0,0 +1,195 @@
+// Package webhook доставляет пользователям уведомления о событиях их ссылок.
+//
+// Пользователь регистрирует адрес и список событий, а сервис отправляет на адрес POST с JSON
+// события и подписью HMAC-SHA256 тела в заголовке SignatureHeader. Каждая доставка сохраняется
+// в Store; неудачные попытки повторяются с экспоненциальной задержкой, а после MaxAttempts
+// доставка попадает в список недоставленных, откуда ее можно отправить заново.
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// События ссылки, на которые можно подписаться.
+const (
+	EventCreated = "created"
+	EventClicked = "clicked"
+	EventDeleted = "deleted"
+	// EventExpired зарезервировано для ссылок со сроком действия: подписаться на него можно,
+	// но сейчас сервис таких ссылок не создает.
+	EventExpired = "expired"
+)
+
+// Events — все события, на которые можно подписаться.
+var Events = []string{EventCreated, EventClicked, EventDeleted, EventExpired}
+
+// Состояния доставки.
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	// StatusDead — попытки исчерпаны, доставка ждет повторной отправки пользователем.
+	StatusDead = "dead"
+)
+
+// Заголовки запроса к адресу вебхука.
+const (
+	// SignatureHeader содержит "sha256=" и HMAC-SHA256 тела запроса на секрете вебхука в hex.
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// MaxPerUser ограничивает число вебхуков одного пользователя.
+const MaxPerUser = 10
+
+var (
+	// ErrNotFound — вебхука или доставки нет либо они принадлежат другому пользователю.
+	ErrNotFound = errors.New("вебхук не найден")
+	// ErrInvalid — адрес или список событий вебхука некорректны.
+	ErrInvalid = errors.New("некорректный вебхук")
+)
+
+// Webhook — подписка пользователя на события его ссылок.
+type Webhook struct {
+	ID     string   `json:"id"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// Secret возвращается только при создании: им получатель проверяет подпись.
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+
+	userID string
+}
+
+// Subscribed сообщает, подписан ли вебхук на событие.
+func (w Webhook) Subscribed(event string) bool {
+	return slices.Contains(w.Events, event)
+}
+
+// Link — ссылка, с которой произошло событие.
+type Link struct {
+	ShortCode   string `json:"short_code"`
+	ShortURL    string `json:"short_url"`
+	OriginalURL string `json:"original_url,omitempty"`
+}
+
+// Payload — тело запроса к адресу вебхука.
+type Payload struct {
+	// ID совпадает с идентификатором доставки: при повторных попытках получатель может
+	// отбросить уже обработанное событие.
+	ID        string    `json:"id"`
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"created_at"`
+	Link      Link      `json:"link"`
+}
+
+// Delivery — отправка одного события на один вебхук со всеми попытками.
+type Delivery struct {
+	ID            string    `json:"id"`
+	WebhookID     string    `json:"webhook_id"`
+	URL           string    `json:"url"`
+	Event         string    `json:"event"`
+	Payload       []byte    `json:"-"`
+	Status        string    `json:"status"`
+	Attempts      int       `json:"attempts"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	// LastError и LastStatus описывают последнюю неудачную попытку.
+	LastError  string    `json:"last_error,omitempty"`
+	LastStatus int       `json:"last_status,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+
+	secret string
+}
+
+// Store хранит вебхуки и очередь доставок.
+type Store interface {
+	// Create сохраняет вебхук, если у пользователя их меньше MaxPerUser.
+	Create(ctx context.Context, userID string, w Webhook) error
+	// List возвращает вебхуки пользователя без секретов.
+	List(ctx context.Context, userID string) ([]Webhook, error)
+	// Delete удаляет вебхук пользователя вместе с его доставками.
+	Delete(ctx context.Context, userID, id string) error
+	// Subscribers возвращает вебхуки пользователя, подписанные на событие, вместе с секретами.
+	Subscribers(ctx context.Context, userID, event string) ([]Webhook, error)
+
+	// Enqueue сохраняет новые доставки.
+	Enqueue(ctx context.Context, deliveries []Delivery) error
+	// Claim выбирает до limit доставок, время попытки которых наступило к now, и откладывает
+	// их следующую попытку до now+lease, чтобы другие экземпляры сервиса их не взяли.
+	Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Delivery, error)
+	// Save записывает итог попытки: Status, Attempts, NextAttemptAt, LastError и LastStatus.
+	// Доставленная доставка удаляется: хранятся только ожидающие и недоставленные.
+	Save(ctx context.Context, d Delivery) error
+	// Deliveries возвращает доставки пользователя в состоянии status, новые первыми.
+	Deliveries(ctx context.Context, userID, status string) ([]Delivery, error)
+	// Redeliver сбрасывает счетчик попыток доставки пользователя и назначает попытку на now.
+	Redeliver(ctx context.Context, userID, id string, now time.Time) (Delivery, error)
+	// Count возвращает число доставок всех пользователей по состояниям.
+	Count(ctx context.Context) (map[string]int, error)
+}
+
+// Sign возвращает значение SignatureHeader для тела body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify проверяет подпись тела на стороне получателя.
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// newID возвращает случайный идентификатор или секрет из n байт в hex.
+func newID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// validate проверяет адрес вебхука и приводит список событий к порядку Events без повторов.
+func validate(rawURL string, events []string) ([]string, error) {
+	u, err := url.Parse(rawURL)
+
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: адрес должен быть абсолютным URL http или https", ErrInvalid)
+	}
+
+	if len(events) == 0 {
+		return nil, fmt.Errorf("%w: не указаны события", ErrInvalid)
+	}
+
+	for _, event := range events {
+		if !slices.Contains(Events, event) {
+			return nil, fmt.Errorf("%w: неизвестное событие %q, ожидается одно из: %s", ErrInvalid, event, strings.Join(Events, ", "))
+		}
+	}
+
+	return slices.DeleteFunc(slices.Clone(Events), func(event string) bool {
+		return !slices.Contains(events, event)
+	}), nil
+}
+
+// backoff возвращает задержку перед попыткой после attempts неудачных: initial, 2·initial, 4·initial...
+// но не больше maxDelay.
+func backoff(attempts int, initial, maxDelay time.Duration) time.Duration {
+	delay := initial
+
+	for i := 1; i < attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxDelay)
+}
@@ -0,0 +1,327 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// receiver — получатель вебхуков: проверяет подпись и запоминает события.
+type receiver struct {
+	mu       sync.Mutex
+	secret   string
+	payloads []Payload
+	// failing задает код ответа; 0 — 204.
+	failing atomic.Int32
+	invalid atomic.Int32
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if !Verify(rc.secret, body, r.Header.Get(SignatureHeader)) {
+		rc.invalid.Add(1)
+		w.WriteHeader(http.StatusUnauthorized)
+
+		return
+	}
+
+	if code := rc.failing.Load(); code != 0 {
+		w.WriteHeader(int(code))
+		return
+	}
+
+	var p Payload
+	json.Unmarshal(body, &p)
+
+	if p.ID != r.Header.Get(DeliveryHeader) || p.Event != r.Header.Get(EventHeader) {
+		rc.invalid.Add(1)
+	}
+
+	rc.payloads = append(rc.payloads, p)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (rc *receiver) received() []Payload {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	return append([]Payload(nil), rc.payloads...)
+}
+
+// staticResolver разрешает имена по таблице, без обращения к сети.
+type staticResolver map[string]string
+
+func (s staticResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	ip, ok := s[host]
+
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	return []net.IPAddr{{IP: net.ParseIP(ip)}}, nil
+}
+
+// testOptions — короткие задержки доставки; получатель в тестах слушает на loopback.
+func testOptions() Options {
+	return Options{
+		MaxAttempts:    3,
+		InitialBackoff: 10 * time.Millisecond,
+		MaxBackoff:     20 * time.Millisecond,
+		PollInterval:   5 * time.Millisecond,
+		Timeout:        time.Second,
+		AllowInternal:  true,
+	}
+}
+
+// startManager запускает доставку с короткими задержками и останавливает ее по завершении теста.
+func startManager(t *testing.T, store Store) *Manager {
+	t.Helper()
+
+	return runManager(t, NewManager(store, testOptions(), zap.NewNop()))
+}
+
+// runManager запускает доставку и останавливает ее по завершении теста.
+func runManager(t *testing.T, m *Manager) *Manager {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		m.Run(ctx)
+		close(done)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	return m
+}
+
+func TestDelivery(t *testing.T) {
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	m := startManager(t, NewMemoryStore())
+	ctx := context.Background()
+
+	hook, err := m.Create(ctx, "u1", server.URL, []string{EventClicked, EventCreated, EventClicked})
+	require.NoError(t, err)
+
+	rc.secret = hook.Secret
+	assert.Equal(t, []string{EventCreated, EventClicked}, hook.Events, "События должны быть без повторов и в порядке Events")
+
+	link := Link{ShortCode: "abc", ShortURL: "http://localhost:8080/abc", OriginalURL: "https://example.com"}
+
+	m.Notify("u1", EventCreated, link)
+	m.Notify("u1", EventDeleted, link)
+	m.Notify("u2", EventCreated, link)
+	m.Notify("u1", EventClicked, link)
+
+	require.Eventually(t, func() bool { return len(rc.received()) == 2 }, time.Second, 5*time.Millisecond, "События не доставлены")
+
+	received := rc.received()
+	events := []string{received[0].Event, received[1].Event}
+
+	assert.ElementsMatch(t, []string{EventCreated, EventClicked}, events, "Доставлены события, на которые нет подписки")
+	assert.Equal(t, link, received[0].Link, "Ссылка в событии не совпадает с ожидаемой")
+	assert.Zero(t, rc.invalid.Load(), "Подпись или заголовки запроса неверны")
+
+	hooks, err := m.List(ctx, "u1")
+	require.NoError(t, err)
+	require.Len(t, hooks, 1, "Число вебхуков не совпадает с ожидаемым")
+	assert.Empty(t, hooks[0].Secret, "Секрет не должен возвращаться в списке")
+}
+
+func TestDeadLetterAndRedeliver(t *testing.T) {
+	rc := &receiver{}
+	rc.failing.Store(http.StatusInternalServerError)
+
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	m := startManager(t, NewMemoryStore())
+	ctx := context.Background()
+
+	hook, err := m.Create(ctx, "u1", server.URL, []string{EventDeleted})
+	require.NoError(t, err)
+
+	rc.secret = hook.Secret
+
+	m.Notify("u1", EventDeleted, Link{ShortCode: "abc"})
+
+	var dead []Delivery
+
+	require.Eventually(t, func() bool {
+		dead, err = m.DeadLetters(ctx, "u1")
+		return err == nil && len(dead) == 1
+	}, time.Second, 5*time.Millisecond, "Доставка не попала в список недоставленных")
+
+	assert.Equal(t, 3, dead[0].Attempts, "Число попыток не совпадает с ожидаемым")
+	assert.Equal(t, http.StatusInternalServerError, dead[0].LastStatus, "Код последней попытки не совпадает с ожидаемым")
+	assert.Empty(t, rc.received(), "Событие не должно считаться доставленным")
+
+	_, err = m.Redeliver(ctx, "u2", dead[0].ID)
+	assert.ErrorIs(t, err, ErrNotFound, "Чужую доставку нельзя отправить заново")
+
+	rc.failing.Store(0)
+
+	_, err = m.Redeliver(ctx, "u1", dead[0].ID)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool { return len(rc.received()) == 1 }, time.Second, 5*time.Millisecond, "Повторная доставка не выполнена")
+
+	assert.Equal(t, dead[0].ID, rc.received()[0].ID, "Идентификатор события должен сохраняться при повторе")
+
+	dead, err = m.DeadLetters(ctx, "u1")
+	require.NoError(t, err)
+	assert.Empty(t, dead, "Доставленное событие не должно оставаться в списке недоставленных")
+}
+
+func TestDeliveredPruned(t *testing.T) {
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	store := NewMemoryStore()
+	m := startManager(t, store)
+	ctx := context.Background()
+
+	hook, err := m.Create(ctx, "u1", server.URL, []string{EventClicked})
+	require.NoError(t, err)
+
+	rc.secret = hook.Secret
+
+	const clicks = 200
+
+	for range clicks {
+		m.Notify("u1", EventClicked, Link{ShortCode: "abc"})
+	}
+
+	require.Eventually(t, func() bool { return len(rc.received()) == clicks }, 5*time.Second, 5*time.Millisecond, "События не доставлены")
+
+	// Итог последней попытки сохраняется после ответа получателя.
+	require.Eventually(t, func() bool {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+
+		return len(store.deliveries) == 0
+	}, time.Second, 5*time.Millisecond, "Доставленные события не должны храниться")
+
+	stats, err := m.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, QueueStats{}, stats, "Очередь после доставки должна быть пустой")
+}
+
+func TestCreate(t *testing.T) {
+	resolver := staticResolver{
+		"example.com":      "93.184.216.34",
+		"intranet.example": "10.1.2.3",
+		"rebind.example":   "127.0.0.1",
+	}
+
+	m := NewManager(NewMemoryStore(), Options{Resolver: resolver}, zap.NewNop())
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		url    string
+		events []string
+	}{
+		{name: "относительный адрес", url: "/hook", events: []string{EventCreated}},
+		{name: "неподдерживаемая схема", url: "ftp://example.com/hook", events: []string{EventCreated}},
+		{name: "без событий", url: "https://example.com/hook"},
+		{name: "неизвестное событие", url: "https://example.com/hook", events: []string{"updated"}},
+		{name: "loopback", url: "http://127.0.0.1:6060/debug", events: []string{EventCreated}},
+		{name: "loopback IPv6", url: "http://[::1]/hook", events: []string{EventCreated}},
+		{name: "неуказанный адрес", url: "http://0.0.0.0/hook", events: []string{EventCreated}},
+		{name: "метаданные облака", url: "http://169.254.169.254/latest/meta-data", events: []string{EventCreated}},
+		{name: "частная сеть", url: "http://192.168.0.1/hook", events: []string{EventCreated}},
+		{name: "имя во внутренней сети", url: "https://intranet.example/hook", events: []string{EventCreated}},
+		{name: "имя на loopback", url: "https://rebind.example/hook", events: []string{EventCreated}},
+		{name: "неразрешимое имя", url: "https://missing.example/hook", events: []string{EventCreated}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := m.Create(ctx, "u1", test.url, test.events)
+			assert.ErrorIs(t, err, ErrInvalid, "Ожидается ошибка проверки вебхука")
+		})
+	}
+
+	for range MaxPerUser {
+		_, err := m.Create(ctx, "u1", "https://example.com/hook", []string{EventCreated})
+		require.NoError(t, err)
+	}
+
+	_, err := m.Create(ctx, "u1", "https://example.com/hook", []string{EventCreated})
+	assert.ErrorIs(t, err, ErrInvalid, "Число вебхуков пользователя должно быть ограничено")
+
+	assert.ErrorIs(t, m.Delete(ctx, "u2", "missing"), ErrNotFound, "Ожидается ошибка удаления отсутствующего вебхука")
+}
+
+func TestDeliveryToInternalAddress(t *testing.T) {
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	opts := testOptions()
+	opts.AllowInternal = false
+
+	store := NewMemoryStore()
+	m := runManager(t, NewManager(store, opts, zap.NewNop()))
+	ctx := context.Background()
+
+	// Вебхук прошел проверку при создании, но к доставке его хост разрешается в loopback.
+	require.NoError(t, store.Create(ctx, "u1", Webhook{ID: "w1", URL: server.URL, Events: []string{EventClicked}, Secret: "secret"}))
+
+	m.Notify("u1", EventClicked, Link{ShortCode: "abc"})
+
+	var dead []Delivery
+
+	require.Eventually(t, func() bool {
+		var err error
+
+		dead, err = m.DeadLetters(ctx, "u1")
+
+		return err == nil && len(dead) == 1
+	}, time.Second, 5*time.Millisecond, "Доставка во внутреннюю сеть должна завершиться неудачей")
+
+	assert.Empty(t, rc.received(), "Запрос во внутреннюю сеть не должен отправляться")
+	assert.Zero(t, dead[0].LastStatus, "Код ответа внутреннего адреса не должен сохраняться")
+	assert.Equal(t, errInternal.Error(), dead[0].LastError, "Ошибка не должна раскрывать адрес во внутренней сети")
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: time.Second},
+		{attempts: 2, want: 2 * time.Second},
+		{attempts: 4, want: 8 * time.Second},
+		{attempts: 10, want: 10 * time.Second},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, backoff(test.attempts, time.Second, 10*time.Second), "Задержка после %d попыток не совпадает с ожидаемой", test.attempts)
+	}
+}
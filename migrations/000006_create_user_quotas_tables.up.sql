@@ -0,0 +1,12 @@
+CREATE TABLE user_quotas (
+    user_id VARCHAR(255) PRIMARY KEY,
+    daily_limit INTEGER,
+    total_limit INTEGER
+);
+
+CREATE TABLE user_quota_usage (
+    user_id VARCHAR(255) PRIMARY KEY,
+    day DATE NOT NULL,
+    daily_count INTEGER NOT NULL DEFAULT 0,
+    total_count INTEGER NOT NULL DEFAULT 0
+);
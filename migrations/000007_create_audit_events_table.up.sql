@@ -0,0 +1,16 @@
+CREATE TABLE audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    ts TIMESTAMPTZ NOT NULL,
+    action VARCHAR(32) NOT NULL,
+    status VARCHAR(32) NOT NULL,
+    user_id VARCHAR(255) NOT NULL DEFAULT '',
+    url TEXT NOT NULL DEFAULT '',
+    short_code VARCHAR(255) NOT NULL DEFAULT '',
+    client_ip VARCHAR(64) NOT NULL DEFAULT '',
+    request_id VARCHAR(128) NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX audit_events_ts_idx ON audit_events (ts);
+CREATE INDEX audit_events_user_id_ts_idx ON audit_events (user_id, ts);
+CREATE INDEX audit_events_short_code_ts_idx ON audit_events (short_code, ts);
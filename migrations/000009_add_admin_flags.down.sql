@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS disabled_users;
+ALTER TABLE shorten_urls DROP COLUMN IF EXISTS is_blocked;
@@ -0,0 +1,6 @@
+ALTER TABLE shorten_urls ADD COLUMN is_blocked BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE disabled_users (
+    user_id VARCHAR(255) PRIMARY KEY,
+    disabled_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS user_activity;
+DROP TABLE IF EXISTS link_clicks;
+DROP INDEX IF EXISTS idx_shorten_urls_created_at;
+ALTER TABLE shorten_urls DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,17 @@
+-- У ссылок, созданных до миграции, время создания неизвестно и остается NULL.
+ALTER TABLE shorten_urls ADD COLUMN created_at TIMESTAMPTZ;
+ALTER TABLE shorten_urls ALTER COLUMN created_at SET DEFAULT NOW();
+CREATE INDEX idx_shorten_urls_created_at ON shorten_urls(created_at);
+
+CREATE TABLE link_clicks (
+    hour TIMESTAMPTZ NOT NULL,
+    short_url VARCHAR(255) NOT NULL,
+    clicks BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (hour, short_url)
+);
+
+CREATE TABLE user_activity (
+    hour TIMESTAMPTZ NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    PRIMARY KEY (hour, user_id)
+);